@@ -74,7 +74,7 @@ type App struct {
 
 type userPage struct {
 	config        ui.Config
-	faces         measure.Faces
+	faces         *measure.Faces
 	invalidate    func()
 	user          *user
 	commitsList   *layout.List
@@ -401,7 +401,7 @@ func (a *App) Layout(c ui.Config, q input.Queue, ops *ui.Ops, cs layout.Constrai
 
 func (a *App) newUserPage(user *user) *userPage {
 	up := &userPage{
-		faces:         a.faces,
+		faces:         &a.faces,
 		invalidate:    a.w.Invalidate,
 		user:          user,
 		commitsList:   &layout.List{Axis: layout.Vertical},
@@ -34,11 +34,20 @@ type buildInfo struct {
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Gio is a tool for building gio programs.\n\n")
-		fmt.Fprintf(os.Stderr, "Usage:\n\n\tgio [flags] <pkg>\n\n")
+		fmt.Fprintf(os.Stderr, "Usage:\n\n\tgio [flags] <pkg>\n\tgio ops <file>\n\n")
 		flag.PrintDefaults()
 		os.Exit(2)
 	}
 	flag.Parse()
+	if flag.Arg(0) == "ops" {
+		if flag.NArg() != 2 {
+			flag.Usage()
+		}
+		if err := dumpOps(flag.Arg(1)); err != nil {
+			errorf("gio: %v", err)
+		}
+		return
+	}
 	pkg := flag.Arg(0)
 	if pkg == "" {
 		flag.Usage()
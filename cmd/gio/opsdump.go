@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package main
+
+import (
+	"os"
+
+	"gioui.org/ui/opsfile"
+)
+
+// dumpOps prints the ops of a file written by
+// opsfile.Encode.
+func dumpOps(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	ops, err := opsfile.Decode(f)
+	if err != nil {
+		return err
+	}
+	return opsfile.Dump(os.Stdout, ops)
+}
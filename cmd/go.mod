@@ -2,4 +2,9 @@ module gioui.org/cmd
 
 go 1.12
 
-require golang.org/x/sync v0.0.0-20181221193216-37e7f081c4d4
+require (
+	gioui.org/ui v0.0.0-20190722093309-c080a54038a6
+	golang.org/x/sync v0.0.0-20181221193216-37e7f081c4d4
+)
+
+replace gioui.org/ui => ../ui
@@ -2,6 +2,8 @@
 
 package org.gioui;
 
+import android.content.ClipData;
+import android.content.ClipboardManager;
 import android.content.Context;
 import android.graphics.Rect;
 import android.os.Build;
@@ -12,6 +14,7 @@ import android.view.Choreographer;
 import android.view.KeyCharacterMap;
 import android.view.KeyEvent;
 import android.view.MotionEvent;
+import android.view.PointerIcon;
 import android.view.View;
 import android.view.WindowInsets;
 import android.view.Surface;
@@ -106,7 +109,9 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 						event.getToolType(i),
 						event.getHistoricalX(i, j),
 						event.getHistoricalY(i, j),
-						time);
+						time,
+						event.getButtonState(),
+						event.getMetaState());
 			}
 		}
 		int act = event.getActionMasked();
@@ -123,7 +128,9 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 					event.getToolType(i),
 					event.getX(i),
 					event.getY(i),
-					event.getEventTime());
+					event.getEventTime(),
+					event.getButtonState(),
+					event.getMetaState());
 		}
 		return true;
 	}
@@ -149,6 +156,54 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 		});
 	}
 
+	void setCursor(final int type) {
+		// Pointer icons were introduced in Android N.
+		if (Build.VERSION.SDK_INT < Build.VERSION_CODES.N) {
+			return;
+		}
+		post(new Runnable() {
+			@Override public void run() {
+				setPointerIcon(PointerIcon.getSystemIcon(getContext(), type));
+			}
+		});
+	}
+
+	void readClipboard() {
+		post(new Runnable() {
+			@Override public void run() {
+				ClipboardManager cm = (ClipboardManager)getContext().getSystemService(Context.CLIPBOARD_SERVICE);
+				String text = "";
+				ClipData clip = cm.getPrimaryClip();
+				if (clip != null && clip.getItemCount() > 0) {
+					CharSequence s = clip.getItemAt(0).coerceToText(getContext());
+					if (s != null) {
+						text = s.toString();
+					}
+				}
+				try {
+					onClipboard(nhandle, text.getBytes("UTF-8"));
+				} catch (UnsupportedEncodingException e) {
+					throw new RuntimeException(e);
+				}
+			}
+		});
+	}
+
+	void writeClipboard(byte[] textUTF8) {
+		final String text;
+		try {
+			text = new String(textUTF8, "UTF-8");
+		} catch (UnsupportedEncodingException e) {
+			throw new RuntimeException(e);
+		}
+		post(new Runnable() {
+			@Override public void run() {
+				ClipboardManager cm = (ClipboardManager)getContext().getSystemService(Context.CLIPBOARD_SERVICE);
+				cm.setPrimaryClip(ClipData.newPlainText(null, text));
+			}
+		});
+	}
+
 	void postFrameCallbackOnMainThread() {
 		handler.post(new Runnable() {
 			@Override public void run() {
@@ -214,11 +269,12 @@ public class GioView extends SurfaceView implements Choreographer.FrameCallback
 	static private native void onConfigurationChanged(long handle);
 	static private native void onWindowInsets(long handle, int top, int right, int bottom, int left);
 	static private native void onLowMemory();
-	static private native void onTouchEvent(long handle, int action, int pointerID, int tool, float x, float y, long time);
+	static private native void onTouchEvent(long handle, int action, int pointerID, int tool, float x, float y, long time, int buttons, int meta);
 	static private native void onKeyEvent(long handle, int code, int character, long time);
 	static private native void onFrameCallback(long handle, long nanos);
 	static private native boolean onBack(long handle);
 	static private native void onFocusChange(long handle, boolean focus);
+	static private native void onClipboard(long handle, byte[] text);
 	static private native void runGoMain(byte[] dataDir);
 
 	private static class InputConnection extends BaseInputConnection {
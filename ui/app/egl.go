@@ -7,6 +7,7 @@ package app
 import (
 	"errors"
 	"fmt"
+	"image"
 	"runtime"
 	"strings"
 
@@ -23,6 +24,8 @@ type context struct {
 	width, height int
 	// For sRGB emulation.
 	srgbFBO *gl.SRGBFBO
+	// srgbAge is the BufferAge of srgbFBO.
+	srgbAge int
 }
 
 type eglContext struct {
@@ -31,6 +34,11 @@ type eglContext struct {
 	ctx      _EGLContext
 	visualID int
 	srgb     bool
+	// bufferAge is set if EGL_EXT_buffer_age is supported.
+	bufferAge bool
+	// swapWithDamage is the address of eglSwapBuffersWithDamage,
+	// if supported.
+	swapWithDamage uintptr
 }
 
 var (
@@ -43,6 +51,7 @@ var (
 const (
 	_EGL_ALPHA_SIZE             = 0x3021
 	_EGL_BLUE_SIZE              = 0x3022
+	_EGL_BUFFER_AGE_EXT         = 0x313d
 	_EGL_CONFIG_CAVEAT          = 0x3027
 	_EGL_CONTEXT_CLIENT_VERSION = 0x3098
 	_EGL_DEPTH_SIZE             = 0x3025
@@ -93,11 +102,57 @@ func (c *context) Present() error {
 	}
 	if c.srgbFBO != nil {
 		c.srgbFBO.AfterPresent()
+		c.srgbAge = 1
 	}
 	return nil
 }
 
-func newContext(w *window) (*context, error) {
+func (c *context) BufferAge() int {
+	if c.srgbFBO != nil {
+		// The sRGB framebuffer is copied in full to
+		// the window framebuffer.
+		return c.srgbAge
+	}
+	if !c.eglCtx.bufferAge {
+		return 0
+	}
+	age, ok := eglQuerySurface(c.eglCtx.disp, c.eglSurf, _EGL_BUFFER_AGE_EXT)
+	if !ok {
+		return 0
+	}
+	return int(age)
+}
+
+func (c *context) PresentDamage(damage image.Rectangle) error {
+	swap := c.eglCtx.swapWithDamage
+	if swap == 0 {
+		return c.Present()
+	}
+	if c.eglWin == nil {
+		panic("context is not active")
+	}
+	if c.srgbFBO != nil {
+		c.srgbFBO.Blit()
+	}
+	// EGL rectangles start at the bottom. An empty
+	// list of rectangles means that everything changed,
+	// so an empty damage is passed as an empty rectangle.
+	damage = damage.Canon()
+	rect := [4]_EGLint{
+		_EGLint(damage.Min.X), _EGLint(c.height - damage.Max.Y),
+		_EGLint(damage.Dx()), _EGLint(damage.Dy()),
+	}
+	if !eglSwapBuffersWithDamage(swap, c.eglCtx.disp, c.eglSurf, rect[:]) {
+		return fmt.Errorf("eglSwapBuffersWithDamage failed (%x)", eglGetError())
+	}
+	if c.srgbFBO != nil {
+		c.srgbFBO.AfterPresent()
+		c.srgbAge = 1
+	}
+	return nil
+}
+
+func (w *window) newContext() (*context, error) {
 	eglCtx, err := createContext(_EGLNativeDisplayType(w.display()))
 	if err != nil {
 		return nil, err
@@ -173,11 +228,15 @@ func (c *context) MakeCurrent() error {
 			c.Release()
 			return err
 		}
+		// BufferAge reports the sRGB framebuffer as
+		// reusable for partial redraws.
+		c.srgbFBO.KeepColor = true
 	}
 	if err := c.srgbFBO.Refresh(c.width, c.height); err != nil {
 		c.Release()
 		return err
 	}
+	c.srgbAge = 0
 	return nil
 }
 
@@ -240,12 +299,21 @@ func createContext(disp _EGLNativeDisplayType) (*eglContext, error) {
 	if !ret {
 		return nil, errors.New("newContext: eglGetConfigAttrib for _EGL_NATIVE_VISUAL_ID failed")
 	}
+	var swapWithDamage uintptr
+	switch {
+	case hasExtension(exts, "EGL_KHR_swap_buffers_with_damage"):
+		swapWithDamage = eglGetProcAddress("eglSwapBuffersWithDamageKHR")
+	case hasExtension(exts, "EGL_EXT_swap_buffers_with_damage"):
+		swapWithDamage = eglGetProcAddress("eglSwapBuffersWithDamageEXT")
+	}
 	return &eglContext{
-		disp:     eglDisp,
-		config:   _EGLConfig(eglCfg),
-		ctx:      _EGLContext(eglCtx),
-		visualID: int(visID),
-		srgb:     srgb,
+		disp:           eglDisp,
+		config:         _EGLConfig(eglCfg),
+		ctx:            _EGLContext(eglCtx),
+		visualID:       int(visID),
+		srgb:           srgb,
+		bufferAge:      hasExtension(exts, "EGL_EXT_buffer_age"),
+		swapWithDamage: swapWithDamage,
 	}, nil
 }
 
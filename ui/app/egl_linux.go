@@ -5,13 +5,22 @@ package app
 /*
 #cgo LDFLAGS: -lEGL
 
+#include <stdlib.h>
+#include <stdint.h>
 #include <EGL/egl.h>
 #include <EGL/eglext.h>
 #include <GLES2/gl2.h>
 #include <GLES3/gl3.h>
+
+static EGLBoolean gio_eglSwapBuffersWithDamage(uintptr_t f, EGLDisplay disp, EGLSurface surf, EGLint *rects, EGLint n) {
+	EGLBoolean (*swap)(EGLDisplay, EGLSurface, EGLint *, EGLint) = (void *)f;
+	return swap(disp, surf, rects, n);
+}
 */
 import "C"
 
+import "unsafe"
+
 type (
 	_EGLint     = C.EGLint
 	_EGLDisplay = C.EGLDisplay
@@ -81,3 +90,19 @@ func eglTerminate(disp _EGLDisplay) bool {
 func eglQueryString(disp _EGLDisplay, name _EGLint) string {
 	return C.GoString(C.eglQueryString(disp, name))
 }
+
+func eglQuerySurface(disp _EGLDisplay, surf _EGLSurface, attr _EGLint) (_EGLint, bool) {
+	var val _EGLint
+	ret := C.eglQuerySurface(disp, surf, attr, &val)
+	return val, ret == C.EGL_TRUE
+}
+
+func eglGetProcAddress(name string) uintptr {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return uintptr(unsafe.Pointer(C.eglGetProcAddress(cname)))
+}
+
+func eglSwapBuffersWithDamage(f uintptr, disp _EGLDisplay, surf _EGLSurface, rects []_EGLint) bool {
+	return C.gio_eglSwapBuffersWithDamage(C.uintptr_t(f), disp, surf, &rects[0], C.EGLint(len(rects)/4)) == C.EGL_TRUE
+}
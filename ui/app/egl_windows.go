@@ -5,6 +5,7 @@ package app
 import (
 	"os"
 	"reflect"
+	gosyscall "syscall"
 	"unsafe"
 
 	syscall "golang.org/x/sys/windows"
@@ -39,6 +40,8 @@ var (
 	_eglSwapBuffers         = libEGL.NewProc("eglSwapBuffers")
 	_eglTerminate           = libEGL.NewProc("eglTerminate")
 	_eglQueryString         = libEGL.NewProc("eglQueryString")
+	_eglQuerySurface        = libEGL.NewProc("eglQuerySurface")
+	_eglGetProcAddress      = libEGL.NewProc("eglGetProcAddress")
 )
 
 func init() {
@@ -133,6 +136,23 @@ func eglTerminate(disp _EGLDisplay) bool {
 	return r != 0
 }
 
+func eglQuerySurface(disp _EGLDisplay, surf _EGLSurface, attr _EGLint) (_EGLint, bool) {
+	var val uintptr
+	r, _, _ := _eglQuerySurface.Call(uintptr(disp), uintptr(surf), uintptr(attr), uintptr(unsafe.Pointer(&val)))
+	return _EGLint(val), r != 0
+}
+
+func eglGetProcAddress(name string) uintptr {
+	cname := append([]byte(name), 0)
+	r, _, _ := _eglGetProcAddress.Call(uintptr(unsafe.Pointer(&cname[0])))
+	return r
+}
+
+func eglSwapBuffersWithDamage(f uintptr, disp _EGLDisplay, surf _EGLSurface, rects []_EGLint) bool {
+	r, _, _ := gosyscall.Syscall6(f, 4, uintptr(disp), uintptr(surf), uintptr(unsafe.Pointer(&rects[0])), uintptr(len(rects)/4), 0, 0)
+	return r != 0
+}
+
 func eglQueryString(disp _EGLDisplay, name _EGLint) string {
 	r, _, _ := _eglQueryString.Call(uintptr(disp), uintptr(name))
 	return goString(r)
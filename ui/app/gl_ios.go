@@ -37,7 +37,7 @@ func init() {
 	}
 }
 
-func newContext(w *window) (*context, error) {
+func (w *window) newContext() (*context, error) {
 	ctx := C.gio_createContext()
 	if ctx == 0 {
 		return nil, fmt.Errorf("failed to create EAGLContext")
@@ -14,7 +14,7 @@ type context struct {
 	srgbFBO *gl.SRGBFBO
 }
 
-func newContext(w *window) (*context, error) {
+func (w *window) newContext() (*context, error) {
 	args := map[string]interface{}{
 		// Enable low latency rendering.
 		// See https://developers.google.com/web/updates/2019/05/desynchronized.
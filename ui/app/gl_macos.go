@@ -31,7 +31,7 @@ func init() {
 	}
 }
 
-func newContext(w *window) (*context, error) {
+func (w *window) newContext() (*context, error) {
 	view := w.contextView()
 	ctx := C.gio_contextForView(view)
 	c := &context{
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package app
+
+import (
+	"image"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
+	"gioui.org/ui/input"
+	"gioui.org/ui/pointer"
+)
+
+// Headless is a Window driver without a display, intended for
+// automated tests. Input events and DrawEvents are synthesized
+// by calling Headless methods, and the frames drawn by the
+// program are returned instead of rendered.
+//
+// The program must process the events from the Window returned
+// by Window before any Headless methods are called, because the
+// methods wait for the program to process their events.
+type Headless struct {
+	// Size is the window size in pixels.
+	Size image.Point
+	// PxPerDp is the number of pixels per dp in the
+	// Config of DrawEvents.
+	PxPerDp float32
+	// PxPerSp is the number of pixels per sp in the
+	// Config of DrawEvents.
+	PxPerSp float32
+
+	w         *Window
+	started   bool
+	frame     *ui.Ops
+	animating bool
+	textInput bool
+	// clipboard is the content of the in-memory clipboard.
+	clipboard string
+	// clipboardRead is set when the window requested
+	// the clipboard content.
+	clipboardRead bool
+	// now is the time of the current frame.
+	now time.Time
+	// wakeup is the redraw time requested by
+	// the current frame, if any.
+	wakeup    time.Time
+	hasWakeup bool
+}
+
+// NewHeadless creates a headless driver and its Window.
+func NewHeadless(size image.Point) *Headless {
+	w := newWindow()
+	h := &Headless{
+		Size:    size,
+		PxPerDp: 1,
+		PxPerSp: 1,
+		w:       w,
+	}
+	w.clock = func() time.Time {
+		return h.now
+	}
+	go w.run(func() error {
+		return nil
+	})
+	w.setDriver(h)
+	return h
+}
+
+// Window returns the headless window.
+func (h *Headless) Window() *Window {
+	return h.w
+}
+
+// Frame sends a DrawEvent whose Config returns now from its Now method and
+// waits for the program to draw. Frame returns the frame drawn, or nil if
+// the program didn't draw. The frame is only valid until the program
+// draws again.
+//
+// If the frame reads the clipboard, Frame also sends the clipboard.Event
+// with the content of the clipboard.
+//
+// The headless window has no clock of its own: now is also the time used
+// for scheduling the redraws requested by the frame. Use Wakeup to
+// determine the time of the next frame.
+func (h *Headless) Frame(now time.Time) *ui.Ops {
+	h.start()
+	h.now = now
+	h.frame = nil
+	h.hasWakeup = false
+	h.w.event(DrawEvent{
+		Config: Config{
+			pxPerDp: h.PxPerDp,
+			pxPerSp: h.PxPerSp,
+			now:     now,
+		},
+		Size: h.Size,
+	})
+	frame := h.frame
+	if h.clipboardRead {
+		h.clipboardRead = false
+		h.w.event(clipboard.Event{Text: h.clipboard})
+	}
+	return frame
+}
+
+// Input sends an input event such as a pointer.Event,
+// key.ChordEvent or key.EditEvent and waits for the
+// program to receive it.
+func (h *Headless) Input(e Event) {
+	if _, ok := e.(input.Event); !ok {
+		panic("not an input event")
+	}
+	h.start()
+	h.w.event(e)
+}
+
+// Close sends a DestroyEvent and waits for the
+// program to process it.
+func (h *Headless) Close() {
+	h.start()
+	h.w.event(DestroyEvent{})
+}
+
+// Wakeup returns the earliest redraw time requested by the last
+// frame, for example through an InvalidateOp. Immediate redraws
+// are reported as the time of the last frame.
+func (h *Headless) Wakeup() (time.Time, bool) {
+	if !h.hasWakeup {
+		return time.Time{}, false
+	}
+	if h.wakeup.Before(h.now) {
+		return h.now, true
+	}
+	return h.wakeup, true
+}
+
+// Animating reports whether the window requested continuous
+// redraws after the last frame.
+func (h *Headless) Animating() bool {
+	return h.animating
+}
+
+// TextInput reports whether the window requested the
+// virtual keyboard.
+func (h *Headless) TextInput() bool {
+	return h.textInput
+}
+
+// Clipboard returns the content of the in-memory
+// clipboard of the window.
+func (h *Headless) Clipboard() string {
+	return h.clipboard
+}
+
+// SetClipboard replaces the content of the
+// in-memory clipboard of the window.
+func (h *Headless) SetClipboard(s string) {
+	h.clipboard = s
+}
+
+// start moves the window to StageRunning.
+func (h *Headless) start() {
+	if h.started {
+		return
+	}
+	h.started = true
+	h.w.event(StageEvent{Stage: StageRunning})
+}
+
+func (h *Headless) setAnimating(anim bool) {
+	h.animating = anim
+}
+
+func (h *Headless) showTextInput(show bool) {
+	h.textInput = show
+}
+
+func (h *Headless) readClipboard() {
+	h.clipboardRead = true
+}
+
+func (h *Headless) writeClipboard(s string) {
+	h.clipboard = s
+}
+
+func (h *Headless) setCursor(c pointer.Cursor) {}
+
+// newContext returns nil, because Headless doesn't render.
+func (h *Headless) newContext() (*context, error) {
+	return nil, nil
+}
+
+func (h *Headless) submit(frame *ui.Ops) {
+	h.frame = frame
+	h.hasWakeup, h.wakeup = h.w.hasNextFrame, h.w.nextFrame
+}
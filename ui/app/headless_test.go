@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package app
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/input"
+	"gioui.org/ui/key"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/measure"
+	"gioui.org/ui/pointer"
+	"gioui.org/ui/text"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+// runHeadless runs a program that draws the frames
+// of h with draw.
+func runHeadless(h *Headless, draw func(c ui.Config, q input.Queue, ops *ui.Ops)) {
+	w := h.Window()
+	go func() {
+		ops := new(ui.Ops)
+		for e := range w.Events() {
+			switch e := e.(type) {
+			case DrawEvent:
+				ops.Reset()
+				draw(&e.Config, w.Queue(), ops)
+				w.Draw(ops)
+			case DestroyEvent:
+				return
+			}
+		}
+	}()
+}
+
+func TestHeadlessFrame(t *testing.T) {
+	h := NewHeadless(image.Point{X: 20, Y: 10})
+	h.PxPerDp = 2
+	defer h.Close()
+	skip := false
+	var px int
+	var frameNow time.Time
+	w := h.Window()
+	go func() {
+		ops := new(ui.Ops)
+		for e := range w.Events() {
+			switch e := e.(type) {
+			case DrawEvent:
+				px = e.Config.Px(ui.Dp(10))
+				frameNow = e.Config.Now()
+				if skip {
+					continue
+				}
+				ops.Reset()
+				draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+				draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(ops)
+				w.Draw(ops)
+			case DestroyEvent:
+				return
+			}
+		}
+	}()
+	now := time.Unix(1000, 0)
+	if f := h.Frame(now); f == nil {
+		t.Error("got no frame, expected the frame drawn")
+	}
+	if px != 20 {
+		t.Errorf("got %d px for 10 dp, expected 20", px)
+	}
+	if !frameNow.Equal(now) {
+		t.Errorf("got frame time %v, expected %v", frameNow, now)
+	}
+	skip = true
+	if f := h.Frame(now); f != nil {
+		t.Error("got a frame, expected none")
+	}
+}
+
+func TestHeadlessInput(t *testing.T) {
+	h := NewHeadless(image.Point{X: 20, Y: 20})
+	defer h.Close()
+	handler := new(int)
+	var events []input.Event
+	runHeadless(h, func(c ui.Config, q input.Queue, ops *ui.Ops) {
+		events = append(events, q.Events(handler)...)
+		pointer.RectAreaOp{Size: h.Size}.Add(ops)
+		pointer.HandlerOp{Key: handler}.Add(ops)
+	})
+	now := time.Unix(1000, 0)
+	h.Frame(now)
+	events = nil
+	h.Input(pointer.Event{Type: pointer.Press, Position: f32.Point{X: 5, Y: 5}})
+	h.Frame(now)
+	var press bool
+	for _, e := range events {
+		if e, ok := e.(pointer.Event); ok && e.Type == pointer.Press {
+			press = true
+		}
+	}
+	if !press {
+		t.Errorf("got events %v, expected a press", events)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Input of a non-input event didn't panic")
+		}
+	}()
+	h.Input(DestroyEvent{})
+}
+
+func TestHeadlessWakeup(t *testing.T) {
+	h := NewHeadless(image.Point{X: 20, Y: 20})
+	defer h.Close()
+	var redraw *ui.InvalidateOp
+	runHeadless(h, func(c ui.Config, q input.Queue, ops *ui.Ops) {
+		if redraw != nil {
+			redraw.Add(ops)
+		}
+	})
+	now := time.Unix(1000, 0)
+	later := now.Add(time.Second)
+	redraw = &ui.InvalidateOp{At: later}
+	h.Frame(now)
+	if at, ok := h.Wakeup(); !ok || !at.Equal(later) {
+		t.Errorf("got wakeup %v, %v, expected %v", at, ok, later)
+	}
+	// The virtual clock doesn't reach the redraw by itself.
+	if h.Animating() {
+		t.Error("animating before the redraw time")
+	}
+	redraw = &ui.InvalidateOp{}
+	h.Frame(later)
+	if at, ok := h.Wakeup(); !ok || !at.Equal(later) {
+		t.Errorf("got wakeup %v, %v for an immediate redraw, expected %v", at, ok, later)
+	}
+	if !h.Animating() {
+		t.Error("not animating after an immediate redraw")
+	}
+	redraw = nil
+	h.Frame(later)
+	if at, ok := h.Wakeup(); ok {
+		t.Errorf("got wakeup %v, expected none", at)
+	}
+	if h.Animating() {
+		t.Error("animating without redraws")
+	}
+}
+
+func TestHeadlessTextInput(t *testing.T) {
+	h := NewHeadless(image.Point{X: 20, Y: 20})
+	defer h.Close()
+	handler := new(int)
+	focus := true
+	runHeadless(h, func(c ui.Config, q input.Queue, ops *ui.Ops) {
+		if focus {
+			key.HandlerOp{Key: handler, Focus: true}.Add(ops)
+		}
+	})
+	now := time.Unix(1000, 0)
+	h.Frame(now)
+	if !h.TextInput() {
+		t.Error("text input hidden after focus")
+	}
+	focus = false
+	h.Frame(now)
+	if h.TextInput() {
+		t.Error("text input shown without a focused handler")
+	}
+}
+
+func TestHeadlessEditorClipboard(t *testing.T) {
+	fnt, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHeadless(image.Point{X: 100, Y: 50})
+	defer h.Close()
+	var faces measure.Faces
+	e := &text.Editor{}
+	e.Focus()
+	runHeadless(h, func(c ui.Config, q input.Queue, ops *ui.Ops) {
+		faces.Reset(c)
+		e.Face = faces.For(fnt, ui.Sp(10))
+		e.Layout(c, q, ops, layout.RigidConstraints(h.Size))
+	})
+	now := time.Unix(1000, 0)
+	// Focus the editor.
+	h.Frame(now)
+	h.Frame(now)
+	h.Input(key.EditEvent{Text: "hello"})
+	h.Frame(now)
+
+	h.Input(key.ChordEvent{Name: 'C', Modifiers: key.ModCommand})
+	h.Frame(now)
+	if got := h.Clipboard(); got != "hello" {
+		t.Errorf("copied %q, expected %q", got, "hello")
+	}
+
+	h.SetClipboard(" world")
+	h.Input(key.ChordEvent{Name: 'V', Modifiers: key.ModCommand})
+	// The first frame reads the clipboard, the second
+	// receives the content.
+	h.Frame(now)
+	h.Frame(now)
+	if got, exp := e.Text(), "hello world"; got != exp {
+		t.Errorf("text after paste is %q, expected %q", got, exp)
+	}
+
+	// The editor has no selection, so cut moves all
+	// of the text to the clipboard.
+	h.Input(key.ChordEvent{Name: 'X', Modifiers: key.ModCommand})
+	h.Frame(now)
+	if got, exp := h.Clipboard(), "hello world"; got != exp {
+		t.Errorf("cut %q, expected %q", got, exp)
+	}
+	if got := e.Text(); got != "" {
+		t.Errorf("text after cut is %q, expected none", got)
+	}
+	// Paste the cut text back.
+	h.Input(key.ChordEvent{Name: 'V', Modifiers: key.ModCommand})
+	h.Frame(now)
+	h.Frame(now)
+	if got, exp := e.Text(), "hello world"; got != exp {
+		t.Errorf("text after paste of cut text is %q, expected %q", got, exp)
+	}
+}
@@ -189,6 +189,10 @@ func (f *Functions) CompileShader(s Shader) {
 	C.glCompileShader(C.GLuint(s.V))
 }
 
+func (f *Functions) CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int) {
+	C.glCopyTexSubImage2D(C.GLenum(target), C.GLint(level), C.GLint(xoffset), C.GLint(yoffset), C.GLint(x), C.GLint(y), C.GLsizei(width), C.GLsizei(height))
+}
+
 func (f *Functions) CreateBuffer() Buffer {
 	C.glGenBuffers(1, &f.uints[0])
 	return Buffer{uint(f.uints[0])}
@@ -2,6 +2,8 @@
 
 package gl
 
+import "image"
+
 type (
 	Attrib uint
 	Enum   uint
@@ -16,6 +18,21 @@ type Context interface {
 	Unlock()
 }
 
+// DamageContext is implemented by Contexts that can
+// present frames where only parts of the window changed.
+type DamageContext interface {
+	Context
+	// BufferAge returns the number of frames since the
+	// current contents of the framebuffer were presented,
+	// or 0 if the contents are undefined.
+	BufferAge() int
+	// PresentDamage is like Present, but promises that
+	// the frame differs from the previous frame only
+	// inside damage. The rectangle is in window
+	// coordinates with the origin at the top left.
+	PresentDamage(damage image.Rectangle) error
+}
+
 const (
 	ARRAY_BUFFER                          = 0x8892
 	BLEND                                 = 0xbe2
@@ -47,6 +64,7 @@ const (
 	NEAREST                               = 0x2600
 	ONE                                   = 0x1
 	ONE_MINUS_SRC_ALPHA                   = 0x303
+	ONE_MINUS_SRC_COLOR                   = 0x301
 	QUERY_RESULT                          = 0x8866
 	QUERY_RESULT_AVAILABLE                = 0x8867
 	R16F                                  = 0x822d
@@ -61,6 +79,7 @@ const (
 	RGB                                   = 0x1907
 	RGBA                                  = 0x1908
 	RGBA8                                 = 0x8058
+	SCISSOR_TEST                          = 0xc11
 	SHORT                                 = 0x1402
 	SRGB                                  = 0x8c40
 	SRGB_ALPHA_EXT                        = 0x8c42
@@ -74,6 +93,7 @@ const (
 	TEXTURE_WRAP_T                        = 0x2803
 	TEXTURE0                              = 0x84c0
 	TEXTURE1                              = 0x84c1
+	TEXTURE2                              = 0x84c2
 	TRIANGLE_STRIP                        = 0x5
 	TRIANGLES                             = 0x4
 	UNPACK_ALIGNMENT                      = 0xcf5
@@ -106,6 +126,7 @@ var _ interface {
 	ClearColor(red, green, blue, alpha float32)
 	ClearDepthf(d float32)
 	CompileShader(s Shader)
+	CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int)
 	CreateBuffer() Buffer
 	CreateFramebuffer() Framebuffer
 	CreateProgram() Program
@@ -87,6 +87,9 @@ func (f *Functions) ClearDepthf(d float32) {
 func (f *Functions) CompileShader(s Shader) {
 	f.Ctx.Call("compileShader", js.Value(s))
 }
+func (f *Functions) CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int) {
+	f.Ctx.Call("copyTexSubImage2D", int(target), level, xoffset, yoffset, x, y, width, height)
+}
 func (f *Functions) CreateBuffer() Buffer {
 	return Buffer(f.Ctx.Call("createBuffer"))
 }
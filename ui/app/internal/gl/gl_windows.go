@@ -30,6 +30,7 @@ var (
 	_glClearDepthf                        = LibGLESv2.NewProc("glClearDepthf")
 	_glDeleteQueries                      = LibGLESv2.NewProc("glDeleteQueries")
 	_glCompileShader                      = LibGLESv2.NewProc("glCompileShader")
+	_glCopyTexSubImage2D                  = LibGLESv2.NewProc("glCopyTexSubImage2D")
 	_glGenBuffers                         = LibGLESv2.NewProc("glGenBuffers")
 	_glGenFramebuffers                    = LibGLESv2.NewProc("glGenFramebuffers")
 	_glCreateProgram                      = LibGLESv2.NewProc("glCreateProgram")
@@ -145,6 +146,9 @@ func (c *Functions) ClearDepthf(d float32) {
 func (c *Functions) CompileShader(s Shader) {
 	syscall.Syscall(_glCompileShader.Addr(), 1, uintptr(s.V), 0, 0)
 }
+func (c *Functions) CopyTexSubImage2D(target Enum, level, xoffset, yoffset, x, y, width, height int) {
+	syscall.Syscall9(_glCopyTexSubImage2D.Addr(), 8, uintptr(target), uintptr(level), uintptr(xoffset), uintptr(yoffset), uintptr(x), uintptr(y), uintptr(width), uintptr(height), 0)
+}
 func (c *Functions) CreateBuffer() Buffer {
 	var buf uintptr
 	syscall.Syscall(_glGenBuffers.Addr(), 2, 1, uintptr(unsafe.Pointer(&buf)), 0)
@@ -12,6 +12,13 @@ import (
 // for gamma-correct rendering on platforms without
 // sRGB enabled native framebuffers.
 type SRGBFBO struct {
+	// KeepColor preserves the color contents after Blit,
+	// for callers that redraw only the damaged parts of
+	// the next frame. Otherwise the contents are
+	// invalidated, which saves memory bandwidth on tiled
+	// GPUs.
+	KeepColor bool
+
 	c             *Functions
 	width, height int
 	frameBuffer   Framebuffer
@@ -85,7 +92,9 @@ func (s *SRGBFBO) Blit() {
 	s.c.DisableVertexAttribArray(0)
 	s.c.DisableVertexAttribArray(1)
 	s.c.BindFramebuffer(FRAMEBUFFER, s.frameBuffer)
-	s.c.InvalidateFramebuffer(FRAMEBUFFER, COLOR_ATTACHMENT0)
+	if !s.KeepColor {
+		s.c.InvalidateFramebuffer(FRAMEBUFFER, COLOR_ATTACHMENT0)
+	}
 	s.c.InvalidateFramebuffer(FRAMEBUFFER, DEPTH_ATTACHMENT)
 	// The Android emulator requires framebuffer 0 bound at eglSwapBuffer time.
 	// Bind the sRGB framebuffer again in afterPresent.
@@ -2,8 +2,6 @@ package gpu
 
 import (
 	"fmt"
-
-	"gioui.org/ui"
 )
 
 type resourceCache struct {
@@ -11,11 +9,11 @@ type resourceCache struct {
 	newRes map[interface{}]resource
 }
 
-// opCache is like a resourceCache using the concrete OpKey
+// opCache is like a resourceCache using the concrete pathKey
 // key type to avoid allocations.
 type opCache struct {
-	res    map[ui.OpKey]resource
-	newRes map[ui.OpKey]resource
+	res    map[pathKey]resource
+	newRes map[pathKey]resource
 }
 
 func newResourceCache() *resourceCache {
@@ -35,7 +33,7 @@ func (r *resourceCache) get(key interface{}) (resource, bool) {
 
 func (r *resourceCache) put(key interface{}, val resource) {
 	if _, exists := r.newRes[key]; exists {
-		panic(fmt.Errorf("key exists, %p", key))
+		panic(fmt.Errorf("key exists, %v", key))
 	}
 	r.res[key] = val
 	r.newRes[key] = val
@@ -64,12 +62,12 @@ func (r *resourceCache) release(ctx *context) {
 
 func newOpCache() *opCache {
 	return &opCache{
-		res:    make(map[ui.OpKey]resource),
-		newRes: make(map[ui.OpKey]resource),
+		res:    make(map[pathKey]resource),
+		newRes: make(map[pathKey]resource),
 	}
 }
 
-func (r *opCache) get(key ui.OpKey) (resource, bool) {
+func (r *opCache) get(key pathKey) (resource, bool) {
 	v, exists := r.res[key]
 	if exists {
 		r.newRes[key] = v
@@ -77,9 +75,9 @@ func (r *opCache) get(key ui.OpKey) (resource, bool) {
 	return v, exists
 }
 
-func (r *opCache) put(key ui.OpKey, val resource) {
+func (r *opCache) put(key pathKey, val resource) {
 	if _, exists := r.newRes[key]; exists {
-		panic(fmt.Errorf("key exists, %p", key))
+		panic(fmt.Errorf("key exists, %v", key))
 	}
 	r.res[key] = val
 	r.newRes[key] = val
@@ -105,3 +103,63 @@ func (r *opCache) release(ctx *context) {
 	r.newRes = nil
 	r.res = nil
 }
+
+// gradientCache is like a resourceCache keyed by the
+// encoded stops of gradients. Lookups don't allocate.
+type gradientCache struct {
+	res    map[string]gradientEntry
+	newRes map[string]gradientEntry
+}
+
+type gradientEntry struct {
+	// key is kept to avoid allocating new keys
+	// for newRes.
+	key string
+	tex *texture
+}
+
+func newGradientCache() *gradientCache {
+	return &gradientCache{
+		res:    make(map[string]gradientEntry),
+		newRes: make(map[string]gradientEntry),
+	}
+}
+
+func (r *gradientCache) get(stops []byte) (*texture, bool) {
+	e, exists := r.res[string(stops)]
+	if exists {
+		r.newRes[e.key] = e
+	}
+	return e.tex, exists
+}
+
+func (r *gradientCache) put(stops []byte, tex *texture) {
+	key := string(stops)
+	if _, exists := r.newRes[key]; exists {
+		panic(fmt.Errorf("key exists, %q", key))
+	}
+	e := gradientEntry{key: key, tex: tex}
+	r.res[key] = e
+	r.newRes[key] = e
+}
+
+func (r *gradientCache) frame(ctx *context) {
+	for k, e := range r.res {
+		if _, exists := r.newRes[k]; !exists {
+			delete(r.res, k)
+			e.tex.release(ctx)
+		}
+	}
+	for k, e := range r.newRes {
+		delete(r.newRes, k)
+		r.res[k] = e
+	}
+}
+
+func (r *gradientCache) release(ctx *context) {
+	for _, e := range r.newRes {
+		e.tex.release(ctx)
+	}
+	r.newRes = nil
+	r.res = nil
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+)
+
+type testResource struct{}
+
+func (testResource) release(ctx *context) {}
+
+// TestCachedPaths verifies that the paths of a retained
+// subtree hit the path cache in later frames.
+func TestCachedPaths(t *testing.T) {
+	triangle := func(o *ui.Ops) {
+		var stack ui.StackOp
+		stack.Push(o)
+		var b draw.PathBuilder
+		b.Init(o)
+		b.Line(f32.Point{X: 20})
+		b.Line(f32.Point{X: -20, Y: 20})
+		b.End()
+		draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(o)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(o)
+		stack.Pop()
+	}
+	key := new(int)
+	recordings := 0
+	frame := func(o *ui.Ops) {
+		o.Reset()
+		c := ui.CacheOp{Key: key}
+		if rec := c.Record(o); rec != nil {
+			recordings++
+			triangle(rec)
+		}
+		c.Add(o)
+		// An uncached path is recorded again every frame.
+		ui.TransformOp{Transform: ui.Offset(f32.Point{X: 50})}.Add(o)
+		triangle(o)
+	}
+	viewport := image.Point{X: 100, Y: 100}
+	var d drawOps
+	cache := newResourceCache()
+	pathCache := newOpCache()
+	ops := new(ui.Ops)
+	for i := 0; i < 3; i++ {
+		frame(ops)
+		d.collect(cache, newGradientCache(), ops, viewport)
+		if n := len(d.pathOps); n != 2 {
+			t.Fatalf("frame %d: got %d paths, expected 2", i, n)
+		}
+		var hits []bool
+		for _, p := range d.pathOps {
+			_, hit := pathCache.get(p.pathKey)
+			if !hit {
+				pathCache.put(p.pathKey, testResource{})
+			}
+			hits = append(hits, hit)
+		}
+		pathCache.frame(nil)
+		// The paths of earlier frames are released.
+		if n := len(pathCache.res); n != 2 {
+			t.Errorf("frame %d: %d cached paths, expected 2", i, n)
+		}
+		if cached := hits[0]; cached != (i > 0) {
+			t.Errorf("frame %d: cached path hit: %v", i, cached)
+		}
+		if uncached := hits[1]; uncached {
+			t.Errorf("frame %d: uncached path hit the cache", i)
+		}
+	}
+	if recordings != 1 {
+		t.Errorf("recorded the cached subtree %d times, expected 1", recordings)
+	}
+}
+
+// TestGradientAllocs verifies that drawing gradients with
+// cached stops doesn't allocate.
+func TestGradientAllocs(t *testing.T) {
+	stops := []draw.ColorStop{
+		{Offset: 0, Color: color.RGBA{R: 0xff, A: 0xff}},
+		{Offset: 1, Color: color.RGBA{B: 0xff, A: 0xff}},
+	}
+	ops := new(ui.Ops)
+	draw.LinearGradientOp{End: f32.Point{X: 20}, Stops: stops}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	draw.RadialGradientOp{Radius: 20, Stops: stops}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	viewport := image.Point{X: 100, Y: 100}
+	var d drawOps
+	cache := newResourceCache()
+	gradients := newGradientCache()
+	frame := func() {
+		d.collect(cache, gradients, ops, viewport)
+		gradients.frame(nil)
+	}
+	frame()
+	if n := len(gradients.res); n != 1 {
+		t.Fatalf("got %d cached gradients, expected 1", n)
+	}
+	allocs := testing.AllocsPerRun(10, frame)
+	if allocs != 0 {
+		t.Errorf("got %v allocations per frame, expected 0", allocs)
+	}
+}
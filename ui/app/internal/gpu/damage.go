@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+	"sort"
+
+	"gioui.org/ui/f32"
+)
+
+// damage computes the parts of the window that change
+// between frames.
+type damage struct {
+	valid      bool
+	viewport   image.Point
+	clearColor [3]float32
+	// items and prev are the images of the current
+	// and previous frame in drawing order.
+	items, prev damageItems
+}
+
+// damageItem summarizes an image op for comparing
+// frames.
+type damageItem struct {
+	z        float32
+	clip     image.Rectangle
+	off      f32.Point
+	material material
+	// path is a hash of the clip paths of the image.
+	path uint64
+}
+
+type damageItems []damageItem
+
+// damageHistory records the damage of the most recent
+// frames, for redrawing framebuffers that are older
+// than the previous frame.
+type damageHistory struct {
+	// damage holds the damage of the previous frames,
+	// most recent first.
+	damage [maxBufferAge]image.Rectangle
+	n      int
+}
+
+// maxBufferAge is the age of the oldest framebuffer
+// that is redrawn partially.
+const maxBufferAge = 4
+
+// compute returns the rectangle that contains the
+// changes between the previous frame and d.
+func (dmg *damage) compute(d *drawOps) image.Rectangle {
+	dmg.prev, dmg.items = dmg.items, dmg.prev[:0]
+	for _, l := range d.layers {
+		dmg.add(l.imageOps)
+	}
+	dmg.add(d.zimageOps)
+	dmg.add(d.imageOps)
+	sort.Sort(dmg.items)
+	full := image.Rectangle{Max: d.viewport}
+	if !dmg.valid || dmg.viewport != d.viewport || dmg.clearColor != d.clearColor {
+		dmg.valid = true
+		dmg.viewport = d.viewport
+		dmg.clearColor = d.clearColor
+		return full
+	}
+	prev, items := dmg.prev, dmg.items
+	// Images before the first difference and after
+	// the last difference cover the same pixels in
+	// both frames.
+	for len(prev) > 0 && len(items) > 0 && prev[0].equal(items[0]) {
+		prev, items = prev[1:], items[1:]
+	}
+	for len(prev) > 0 && len(items) > 0 && prev[len(prev)-1].equal(items[len(items)-1]) {
+		prev, items = prev[:len(prev)-1], items[:len(items)-1]
+	}
+	var r image.Rectangle
+	for _, it := range prev {
+		r = r.Union(it.clip)
+	}
+	for _, it := range items {
+		r = r.Union(it.clip)
+	}
+	return r.Intersect(full)
+}
+
+func (dmg *damage) add(ops []imageOp) {
+	for _, img := range ops {
+		dmg.items = append(dmg.items, damageItem{
+			z:        img.z,
+			clip:     img.clip,
+			off:      img.off,
+			material: img.material,
+			path:     hashPath(img.path),
+		})
+	}
+}
+
+// equal reports whether the items draw the same
+// pixels, regardless of their depth.
+func (it damageItem) equal(it2 damageItem) bool {
+	it.z, it2.z = 0, 0
+	return it == it2
+}
+
+func (d damageItems) Len() int           { return len(d) }
+func (d damageItems) Less(i, j int) bool { return d[i].z < d[j].z }
+func (d damageItems) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// hashPath returns a hash of the path clips of p
+// and its parents.
+func hashPath(p *pathOp) uint64 {
+	// FNV-1a.
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for ; p != nil; p = p.parent {
+		if !p.path {
+			continue
+		}
+		if p.hash == 0 {
+			var buf [4 * 11]byte
+			bo := binary.LittleEndian
+			sx, hx, _, hy, sy, _ := p.trans.Elems()
+			for i, v := range []float32{
+				p.off.X, p.off.Y, sx, hx, hy, sy,
+				p.rect.Min.X, p.rect.Min.Y, p.rect.Max.X, p.rect.Max.Y,
+				float32(p.fillRule),
+			} {
+				bo.PutUint32(buf[i*4:], math.Float32bits(v))
+			}
+			ph := uint64(offset)
+			for _, b := range buf {
+				ph = (ph ^ uint64(b)) * prime
+			}
+			for _, b := range p.pathVerts {
+				ph = (ph ^ uint64(b)) * prime
+			}
+			p.hash = ph
+		}
+		h = (h ^ p.hash) * prime
+	}
+	return h
+}
+
+// region returns the part of a framebuffer of the given age to
+// redraw for a frame with damage, and records the damage.
+func (h *damageHistory) region(damage image.Rectangle, age int, viewport image.Point) image.Rectangle {
+	r := damage
+	if age == 0 || age-1 > h.n {
+		r = image.Rectangle{Max: viewport}
+	} else {
+		for _, d := range h.damage[:age-1] {
+			r = r.Union(d)
+		}
+	}
+	copy(h.damage[1:], h.damage[:])
+	h.damage[0] = damage
+	if h.n < len(h.damage) {
+		h.n++
+	}
+	return r
+}
+
+// filterOps returns the images of ops that
+// overlap r.
+func filterOps(ops []imageOp, r image.Rectangle) []imageOp {
+	var n int
+	for _, img := range ops {
+		if img.clip.Overlaps(r) {
+			ops[n] = img
+			n++
+		}
+	}
+	return ops[:n]
+}
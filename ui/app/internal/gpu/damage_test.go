@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"testing"
+)
+
+func colorImage(z float32, clip image.Rectangle, r float32) imageOp {
+	return imageOp{
+		z:    z,
+		clip: clip,
+		material: material{
+			material: materialColor,
+			color:    [4]float32{r, 0, 0, 1},
+		},
+	}
+}
+
+func TestDamage(t *testing.T) {
+	viewport := image.Point{X: 100, Y: 100}
+	full := image.Rectangle{Max: viewport}
+	r1 := image.Rect(10, 10, 20, 20)
+	r2 := image.Rect(50, 50, 60, 70)
+	r3 := image.Rect(80, 0, 90, 10)
+	frame := func(imgs ...imageOp) *drawOps {
+		return &drawOps{viewport: viewport, imageOps: imgs}
+	}
+	var dmg damage
+	if d := dmg.compute(frame(colorImage(0, r1, 1), colorImage(1, r3, 1))); d != full {
+		t.Errorf("first frame: got damage %v, expected %v", d, full)
+	}
+	if d := dmg.compute(frame(colorImage(0, r1, 1), colorImage(1, r3, 1))); !d.Empty() {
+		t.Errorf("identical frame: got damage %v, expected none", d)
+	}
+	// Changes in depth alone don't damage.
+	if d := dmg.compute(frame(colorImage(5, r1, 1), colorImage(6, r3, 1))); !d.Empty() {
+		t.Errorf("frame with new depths: got damage %v, expected none", d)
+	}
+	if d, exp := dmg.compute(frame(colorImage(0, r2, 1), colorImage(1, r3, 1))), r1.Union(r2); d != exp {
+		t.Errorf("moved image: got damage %v, expected %v", d, exp)
+	}
+	if d := dmg.compute(frame(colorImage(0, r2, 0.5), colorImage(1, r3, 1))); d != r2 {
+		t.Errorf("recolored image: got damage %v, expected %v", d, r2)
+	}
+	// The image below r2 changes the order of images.
+	if d, exp := dmg.compute(frame(colorImage(0, r1, 1), colorImage(1, r2, 0.5), colorImage(2, r3, 1))), r1; d != exp {
+		t.Errorf("added image: got damage %v, expected %v", d, exp)
+	}
+	ops := frame(colorImage(0, r1, 1), colorImage(1, r2, 0.5), colorImage(2, r3, 1))
+	ops.clearColor = [3]float32{1, 1, 1}
+	if d := dmg.compute(ops); d != full {
+		t.Errorf("new clear color: got damage %v, expected %v", d, full)
+	}
+	ops = frame(colorImage(0, r1, 1), colorImage(1, r2, 0.5), colorImage(2, r3, 1))
+	ops.clearColor = [3]float32{1, 1, 1}
+	ops.viewport = image.Point{X: 200, Y: 100}
+	if d, exp := dmg.compute(ops), (image.Rectangle{Max: ops.viewport}); d != exp {
+		t.Errorf("new viewport: got damage %v, expected %v", d, exp)
+	}
+}
+
+func TestDamageHistory(t *testing.T) {
+	viewport := image.Point{X: 100, Y: 100}
+	full := image.Rectangle{Max: viewport}
+	var h damageHistory
+	damages := []image.Rectangle{
+		image.Rect(0, 0, 10, 10),
+		image.Rect(20, 20, 30, 30),
+		image.Rect(40, 40, 50, 50),
+		image.Rect(60, 60, 70, 70),
+		image.Rect(80, 80, 90, 90),
+		image.Rect(5, 5, 15, 15),
+	}
+	for _, d := range damages {
+		h.region(d, 1, viewport)
+	}
+	if h.n != maxBufferAge {
+		t.Errorf("got %d frames of history, expected %d", h.n, maxBufferAge)
+	}
+	d := image.Rect(90, 0, 100, 10)
+	for _, tc := range []struct {
+		age int
+		exp image.Rectangle
+	}{
+		{0, full},
+		{1, d},
+		{2, d.Union(damages[5])},
+		{3, d.Union(damages[5]).Union(damages[4])},
+		{maxBufferAge + 1, d.Union(damages[5]).Union(damages[4]).Union(damages[3]).Union(damages[2])},
+		{maxBufferAge + 2, full},
+	} {
+		// Use a copy to leave the history unchanged.
+		h := h
+		if r := h.region(d, tc.age, viewport); r != tc.exp {
+			t.Errorf("buffer age %d: got region %v, expected %v", tc.age, r, tc.exp)
+		}
+	}
+	var empty damageHistory
+	if r := empty.region(d, 2, viewport); r != full {
+		t.Errorf("buffer age 2 without history: got region %v, expected %v", r, full)
+	}
+}
+
+func TestFilterOps(t *testing.T) {
+	ops := []imageOp{
+		colorImage(0, image.Rect(0, 0, 10, 10), 1),
+		colorImage(1, image.Rect(20, 20, 30, 30), 1),
+		colorImage(2, image.Rect(5, 5, 25, 25), 1),
+	}
+	got := filterOps(ops, image.Rect(0, 0, 8, 8))
+	if len(got) != 2 || got[0].z != 0 || got[1].z != 2 {
+		t.Errorf("got images %v, expected the images at depth 0 and 2", got)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"gioui.org/ui/app/internal/gl"
 	gdraw "gioui.org/ui/draw"
 	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/gradient"
 	"gioui.org/ui/internal/ops"
 	"golang.org/x/image/draw"
 )
@@ -27,6 +28,7 @@ type GPU struct {
 
 	pathCache *opCache
 	cache     *resourceCache
+	gradients *gradientCache
 
 	frames     chan frame
 	results    chan frameResult
@@ -36,12 +38,16 @@ type GPU struct {
 	stop       chan struct{}
 	stopped    chan struct{}
 	ops        drawOps
+	damage     damage
 }
 
 type frame struct {
 	collectStats bool
 	viewport     image.Point
 	ops          drawOps
+	// damage is the part of the viewport that
+	// changed since the previous frame.
+	damage image.Rectangle
 }
 
 type frameResult struct {
@@ -55,11 +61,24 @@ type renderer struct {
 	pather        *pather
 	packer        packer
 	intersections packer
+	// layers holds the textures of the OpacityOp layers.
+	layers fboSet
+	// dst holds copies of the framebuffer for
+	// blend modes that read the destination.
+	dst dstTexture
+	// history is the damage of the previous frames.
+	history damageHistory
+}
+
+type dstTexture struct {
+	tex  gl.Texture
+	size image.Point
 }
 
 type drawOps struct {
 	reader     ui.OpsReader
 	cache      *resourceCache
+	gradients  *gradientCache
 	viewport   image.Point
 	clearColor [3]float32
 	imageOps   []imageOp
@@ -69,6 +88,19 @@ type drawOps struct {
 	zimageOps   []imageOp
 	pathOps     []*pathOp
 	pathOpCache []pathOp
+	// layers are in drawing order: layers are
+	// listed before the layers that contain them.
+	layers []*layer
+}
+
+// layer is a group of images drawn into an offscreen
+// texture and composited with an alpha.
+type layer struct {
+	alpha float32
+	// bounds of the layer images in window
+	// coordinates.
+	bounds   image.Rectangle
+	imageOps []imageOp
 }
 
 type drawState struct {
@@ -77,24 +109,57 @@ type drawState struct {
 	cpath *pathOp
 	rect  bool
 	z     int
+	// layer receives the images, if not nil.
+	layer *layer
+	blend gdraw.BlendMode
 
 	// Current ImageOp image and rect, if any.
 	img     image.Image
 	imgRect image.Rectangle
 	// Current ColorOp, if any.
 	color color.RGBA
+	// Current gradient op, if hasGradient is set.
+	gradient    gradientState
+	hasGradient bool
+}
+
+// gradientState is the state of a LinearGradientOp
+// or RadialGradientOp.
+type gradientState struct {
+	radial bool
+	// space maps the gradient to its offsets.
+	space ui.Transform
+	// stops are the encoded stops of the op.
+	stops  []byte
+	spread gdraw.Spread
 }
 
 type pathOp struct {
 	off f32.Point
+	// trans is the transformation of the path
+	// excluding the offset, off.
+	trans ui.Transform
 	// clip is the union of all
 	// later clip rectangles.
 	clip      image.Rectangle
-	pathKey   ui.OpKey
+	pathKey   pathKey
 	path      bool
 	pathVerts []byte
-	parent    *pathOp
-	place     placement
+	// rect is the clip rectangle of a path
+	// without vertices.
+	rect     f32.Rectangle
+	fillRule gdraw.FillRule
+	parent   *pathOp
+	place    placement
+	// hash of the path, or 0 if not computed.
+	hash uint64
+}
+
+// pathKey identifies the vertices of a path op
+// transformed by a linear transformation.
+type pathKey struct {
+	op             ui.OpKey
+	sx, hx, hy, sy float32
 }
 
 type imageOp struct {
@@ -104,6 +169,8 @@ type imageOp struct {
 	clip     image.Rectangle
 	material material
 	clipType clipType
+	// fillRule of the path for clipTypePath.
+	fillRule gdraw.FillRule
 	place    placement
 }
 
@@ -112,15 +179,27 @@ type material struct {
 	opaque   bool
 	// For materialTypeColor.
 	color [4]float32
-	// For materialTypeTexture.
-	texture  *texture
-	uvScale  f32.Point
-	uvOffset f32.Point
+	// For materialTypeTexture and the gradient
+	// materials.
+	texture *texture
+	uvTrans ui.Transform
+	// For the gradient materials.
+	spread gdraw.Spread
+	// For materialLayer, the index of the layer
+	// and its alpha.
+	layer int
+	alpha float32
+	blend gdraw.BlendMode
+	// For blend modes that read the destination, the
+	// transformation from window coordinates to the
+	// coordinates of the destination copy.
+	dstOffset, dstScale f32.Point
 }
 
 // opClip structure must match opClip in package ui/draw.
 type opClip struct {
-	bounds f32.Rectangle
+	bounds   f32.Rectangle
+	fillRule gdraw.FillRule
 }
 
 func (op *opClip) decode(data []byte) {
@@ -139,7 +218,8 @@ func (op *opClip) decode(data []byte) {
 		},
 	}
 	*op = opClip{
-		bounds: r,
+		bounds:   r,
+		fillRule: gdraw.FillRule(data[17]),
 	}
 }
 
@@ -157,12 +237,17 @@ type texture struct {
 type blitter struct {
 	ctx      *context
 	viewport image.Point
-	prog     [2]gl.Program
-	vars     [2]struct {
-		z                   gl.Uniform
-		uScale, uOffset     gl.Uniform
-		uUVScale, uUVOffset gl.Uniform
-		uColor              gl.Uniform
+	prog     [numPrograms]gl.Program
+	vars     [numPrograms]struct {
+		z                    gl.Uniform
+		uScale, uOffset      gl.Uniform
+		uUVTrans1, uUVTrans2 gl.Uniform
+		uColor               gl.Uniform
+		uSpread              gl.Uniform
+		uAlpha               gl.Uniform
+		uDstOffset           gl.Uniform
+		uDstScale            gl.Uniform
+		uBlendMode           gl.Uniform
 	}
 	quadVerts gl.Buffer
 }
@@ -178,8 +263,27 @@ const (
 const (
 	materialTexture materialType = iota
 	materialColor
+	// The gradient materials sample a texture with
+	// the colors of the gradient in the gradient
+	// offset computed from vUV.
+	materialLinearGradient
+	materialRadialGradient
+	// materialLayer samples the texture of a layer
+	// and applies its alpha.
+	materialLayer
+
+	numMaterials = iota
+	// numPrograms is the number of programs for drawing
+	// materials. The programs after the first numMaterials
+	// read the destination for blending.
+	numPrograms = 2 * numMaterials
 )
 
+// gradientTextureSize is the width of the textures
+// that hold the colors of gradients. It must match n
+// in gradientHeader.
+const gradientTextureSize = 256
+
 var (
 	blitAttribs           = []string{"pos", "uv"}
 	attribPos   gl.Attrib = 0
@@ -197,6 +301,7 @@ func NewGPU(ctx gl.Context) (*GPU, error) {
 		stopped:    make(chan struct{}),
 		pathCache:  newOpCache(),
 		cache:      newResourceCache(),
+		gradients:  newGradientCache(),
 	}
 	if err := g.renderLoop(ctx); err != nil {
 		return nil, err
@@ -224,6 +329,7 @@ func (g *GPU) renderLoop(glctx gl.Context) error {
 			return
 		}
 		defer g.cache.release(ctx)
+		defer g.gradients.release(ctx)
 		defer g.pathCache.release(ctx)
 		r := newRenderer(ctx)
 		defer r.release()
@@ -250,7 +356,7 @@ func (g *GPU) renderLoop(glctx gl.Context) error {
 				for _, p := range ops.pathOps {
 					data, exists := g.pathCache.get(p.pathKey)
 					if !exists {
-						data = buildPath(r.ctx, p.pathVerts)
+						data = buildPath(r.ctx, p.vertices())
 						g.pathCache.put(p.pathKey, data)
 					}
 					p.pathVerts = nil
@@ -258,35 +364,62 @@ func (g *GPU) renderLoop(glctx gl.Context) error {
 				g.ack <- struct{}{}
 				r.blitter.viewport = frame.viewport
 				r.pather.viewport = frame.viewport
+				// Redraw only the part of the framebuffer that
+				// differs from the frame.
+				region := image.Rectangle{Max: frame.viewport}
+				dctx, partial := glctx.(gl.DamageContext)
+				if partial {
+					region = r.history.region(frame.damage, dctx.BufferAge(), frame.viewport)
+					ops.zimageOps = filterOps(ops.zimageOps, region)
+					ops.imageOps = filterOps(ops.imageOps, region)
+				}
 				for _, img := range ops.imageOps {
 					expandPathOp(img.path, img.clip)
 				}
+				for _, l := range ops.layers {
+					for _, img := range l.imageOps {
+						expandPathOp(img.path, img.clip)
+					}
+				}
 				if frame.collectStats {
 					zopsTimer.begin()
 				}
 				ctx.DepthFunc(gl.GREATER)
 				ctx.ClearColor(ops.clearColor[0], ops.clearColor[1], ops.clearColor[2], 1.0)
 				ctx.ClearDepthf(0.0)
+				r.scissor(region, frame.viewport)
 				ctx.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 				ctx.Viewport(0, 0, frame.viewport.X, frame.viewport.Y)
 				r.drawZOps(ops.zimageOps)
+				ctx.Disable(gl.SCISSOR_TEST)
 				zopsTimer.end()
 				stencilTimer.begin()
 				ctx.Enable(gl.BLEND)
 				r.packStencils(&ops.pathOps)
 				r.stencilClips(g.pathCache, ops.pathOps)
-				r.packIntersections(ops.imageOps)
-				r.intersect(ops.imageOps)
 				stencilTimer.end()
 				coverTimer.begin()
+				// Draw layers first, because they share the
+				// intersection textures with the frame.
+				r.drawLayers(ops.layers)
+				r.packIntersections(ops.imageOps)
+				r.intersect(ops.imageOps)
 				ctx.Viewport(0, 0, frame.viewport.X, frame.viewport.Y)
-				r.drawOps(ops.imageOps)
+				r.scissor(region, frame.viewport)
+				r.drawOps(ops.imageOps, image.Rectangle{Max: frame.viewport})
+				ctx.Disable(gl.SCISSOR_TEST)
 				ctx.Disable(gl.BLEND)
 				r.pather.stenciler.invalidateFBO()
 				coverTimer.end()
-				err := glctx.Present()
+				var err error
+				if partial {
+					err = dctx.PresentDamage(frame.damage)
+				} else {
+					err = glctx.Present()
+				}
 				cleanupTimer.begin()
 				g.cache.frame(ctx)
+				g.gradients.frame(ctx)
 				g.pathCache.frame(ctx)
 				cleanupTimer.end()
 				var res frameResult
@@ -348,9 +481,10 @@ func (g *GPU) Draw(profile bool, viewport image.Point, root *ui.Ops) {
 		return
 	}
 	g.Flush()
-	g.ops.reset(g.cache, viewport)
-	g.ops.collect(g.cache, root, viewport)
-	g.frames <- frame{profile, viewport, g.ops}
+	g.ops.reset(g.cache, g.gradients, viewport)
+	g.ops.collect(g.cache, g.gradients, root, viewport)
+	damage := g.damage.compute(&g.ops)
+	g.frames <- frame{profile, viewport, g.ops, damage}
 	<-g.ack
 	g.drawing = true
 }
@@ -389,6 +523,10 @@ func newRenderer(ctx *context) *renderer {
 }
 
 func (r *renderer) release() {
+	if r.dst.tex != (gl.Texture{}) {
+		r.ctx.DeleteTexture(r.dst.tex)
+	}
+	r.layers.delete(r.ctx, 0)
 	r.pather.release()
 	r.blitter.release()
 }
@@ -415,14 +553,28 @@ func newBlitter(ctx *context) *blitter {
 	}
 	for i, prog := range prog {
 		ctx.UseProgram(prog)
-		switch materialType(i) {
-		case materialTexture:
-			uTex := gl.GetUniformLocation(ctx.Functions, prog, "tex")
-			ctx.Uniform1i(uTex, 0)
-			b.vars[i].uUVScale = gl.GetUniformLocation(ctx.Functions, prog, "uvScale")
-			b.vars[i].uUVOffset = gl.GetUniformLocation(ctx.Functions, prog, "uvOffset")
+		mat := materialType(i % numMaterials)
+		switch mat {
 		case materialColor:
 			b.vars[i].uColor = gl.GetUniformLocation(ctx.Functions, prog, "color")
+		default:
+			uTex := gl.GetUniformLocation(ctx.Functions, prog, "tex")
+			ctx.Uniform1i(uTex, 0)
+			b.vars[i].uUVTrans1 = gl.GetUniformLocation(ctx.Functions, prog, "uvTransform1")
+			b.vars[i].uUVTrans2 = gl.GetUniformLocation(ctx.Functions, prog, "uvTransform2")
+		}
+		switch mat {
+		case materialLinearGradient, materialRadialGradient:
+			b.vars[i].uSpread = gl.GetUniformLocation(ctx.Functions, prog, "spread")
+		case materialLayer:
+			b.vars[i].uAlpha = gl.GetUniformLocation(ctx.Functions, prog, "alpha")
+		}
+		if i >= numMaterials {
+			uDst := gl.GetUniformLocation(ctx.Functions, prog, "dst")
+			ctx.Uniform1i(uDst, 2)
+			b.vars[i].uDstOffset = gl.GetUniformLocation(ctx.Functions, prog, "dstOffset")
+			b.vars[i].uDstScale = gl.GetUniformLocation(ctx.Functions, prog, "dstScale")
+			b.vars[i].uBlendMode = gl.GetUniformLocation(ctx.Functions, prog, "blendMode")
 		}
 		b.vars[i].z = gl.GetUniformLocation(ctx.Functions, prog, "z")
 		b.vars[i].uScale = gl.GetUniformLocation(ctx.Functions, prog, "scale")
@@ -438,35 +590,85 @@ func (b *blitter) release() {
 	}
 }
 
-func createColorPrograms(ctx *context, vsSrc, fsSrc string) ([2]gl.Program, error) {
-	var prog [2]gl.Program
-	frep := strings.NewReplacer(
-		"HEADER", `
+// createColorPrograms creates a program for each material
+// and for each material blended with the destination.
+func createColorPrograms(ctx *context, vsSrc, fsSrc string) ([numPrograms]gl.Program, error) {
+	var prog [numPrograms]gl.Program
+	headers := [numMaterials]struct{ header, getColor string }{
+		materialTexture: {`
 uniform sampler2D tex;
-`,
-		"GET_COLOR", `texture2D(tex, vUV)`,
-	)
-	fsSrcTex := frep.Replace(fsSrc)
-	var err error
-	prog[materialTexture], err = gl.CreateProgram(ctx.Functions, vsSrc, fsSrcTex, blitAttribs)
-	if err != nil {
-		return prog, err
-	}
-	frep = strings.NewReplacer(
-		"HEADER", `
+`, `texture2D(tex, vUV)`},
+		materialColor: {`
 uniform vec4 color;
-`,
-		"GET_COLOR", `color`,
-	)
-	fsSrcCol := frep.Replace(fsSrc)
-	prog[materialColor], err = gl.CreateProgram(ctx.Functions, vsSrc, fsSrcCol, blitAttribs)
-	if err != nil {
-		ctx.DeleteProgram(prog[materialTexture])
-		return prog, err
+`, `color`},
+		materialLinearGradient: {gradientHeader, `gradient(vUV.x)`},
+		materialRadialGradient: {gradientHeader, `gradient(length(vUV))`},
+		materialLayer: {`
+uniform sampler2D tex;
+uniform float alpha;
+`, `alpha*texture2D(tex, vUV)`},
+	}
+	for i := range prog {
+		h := headers[i%numMaterials]
+		blend := blendHeader
+		if i >= numMaterials {
+			blend = dstBlendHeader
+		}
+		frep := strings.NewReplacer(
+			"BLEND_HEADER", blend,
+			"HEADER", h.header,
+			"GET_COLOR", h.getColor,
+		)
+		var err error
+		prog[i], err = gl.CreateProgram(ctx.Functions, vsSrc, frep.Replace(fsSrc), blitAttribs)
+		if err != nil {
+			for _, p := range prog[:i] {
+				ctx.DeleteProgram(p)
+			}
+			return prog, err
+		}
 	}
 	return prog, nil
 }
 
+// programFor returns the index of the program
+// for drawing m.
+func programFor(m *material) int {
+	idx := int(m.material)
+	if blendReadsDst(m.blend) {
+		idx += numMaterials
+	}
+	return idx
+}
+
+// blendReadsDst reports whether mode is implemented by
+// programs that read the destination color.
+func blendReadsDst(mode gdraw.BlendMode) bool {
+	switch mode {
+	case gdraw.BlendSrcOver, gdraw.BlendScreen, gdraw.BlendAdd, gdraw.BlendClear:
+		return false
+	default:
+		return true
+	}
+}
+
+// blendFunc returns the blend factors for mode.
+func blendFunc(mode gdraw.BlendMode) (gl.Enum, gl.Enum) {
+	switch mode {
+	case gdraw.BlendSrcOver:
+		return gl.ONE, gl.ONE_MINUS_SRC_ALPHA
+	case gdraw.BlendScreen:
+		return gl.ONE, gl.ONE_MINUS_SRC_COLOR
+	case gdraw.BlendAdd:
+		return gl.ONE, gl.ONE
+	case gdraw.BlendClear:
+		return gl.ZERO, gl.ONE_MINUS_SRC_ALPHA
+	default:
+		// The program blends with the destination.
+		return gl.ONE, gl.ZERO
+	}
+}
+
 func (r *renderer) stencilClips(pathCache *opCache, ops []*pathOp) {
 	if len(r.packer.sizes) == 0 {
 		return
@@ -529,6 +731,7 @@ func (r *renderer) intersectPath(p *pathOp, clip image.Rectangle) {
 	}
 	fbo := r.pather.stenciler.cover(p.place.Idx)
 	r.ctx.BindTexture(gl.TEXTURE_2D, fbo.tex)
+	r.ctx.Uniform1f(r.pather.stenciler.uIntersectEvenOdd, evenOdd(p.fillRule))
 	coverScale, coverOff := texSpaceTransform(uv, fbo.size)
 	r.ctx.Uniform2f(r.pather.stenciler.uIntersectUVScale, coverScale.X, coverScale.Y)
 	r.ctx.Uniform2f(r.pather.stenciler.uIntersectUVOffset, coverOff.X, coverOff.Y)
@@ -553,6 +756,7 @@ func (r *renderer) packIntersections(ops []imageOp) {
 			place.Pos = place.Pos.Sub(onePath.clip.Min).Add(img.clip.Min)
 			ops[i].place = place
 			ops[i].clipType = clipTypePath
+			ops[i].fillRule = onePath.fillRule
 		default:
 			sz := image.Point{X: img.clip.Dx(), Y: img.clip.Dy()}
 			place, ok := r.intersections.add(sz)
@@ -627,18 +831,23 @@ func floor(v float32) int {
 	}
 }
 
-func (d *drawOps) reset(cache *resourceCache, viewport image.Point) {
+func (d *drawOps) reset(cache *resourceCache, gradients *gradientCache, viewport image.Point) {
 	d.clearColor = [3]float32{1.0, 1.0, 1.0}
 	d.cache = cache
+	d.gradients = gradients
 	d.viewport = viewport
 	d.imageOps = d.imageOps[:0]
 	d.zimageOps = d.zimageOps[:0]
 	d.pathOps = d.pathOps[:0]
 	d.pathOpCache = d.pathOpCache[:0]
+	for i := range d.layers {
+		d.layers[i] = nil
+	}
+	d.layers = d.layers[:0]
 }
 
-func (d *drawOps) collect(cache *resourceCache, root *ui.Ops, viewport image.Point) {
-	d.reset(cache, viewport)
+func (d *drawOps) collect(cache *resourceCache, gradients *gradientCache, root *ui.Ops, viewport image.Point) {
+	d.reset(cache, gradients, viewport)
 	clip := f32.Rectangle{
 		Max: f32.Point{X: float32(viewport.X), Y: float32(viewport.Y)},
 	}
@@ -671,22 +880,32 @@ loop:
 		case ops.TypeClip:
 			var op opClip
 			op.decode(encOp.Data)
-			off := state.t.Transform(f32.Point{})
-			state.clip = state.clip.Intersect(op.bounds.Add(off))
+			bounds, isRect := state.t.TransformRect(op.bounds)
+			state.clip = state.clip.Intersect(bounds)
 			if state.clip.Empty() {
 				continue
 			}
+			trans, off := splitTransform(state.t)
 			npath := d.newPathOp()
 			*npath = pathOp{
 				parent: state.cpath,
 				off:    off,
 			}
 			state.cpath = npath
-			if len(aux) > 0 {
+			if len(aux) > 0 || !isRect {
+				// Clip rectangles that are no longer rectangles
+				// after transformation are clipped like paths.
+				key := encOp.Key
+				if len(aux) > 0 {
+					key = auxKey
+					state.cpath.fillRule = op.fillRule
+				}
 				state.rect = false
-				state.cpath.pathKey = auxKey
+				state.cpath.trans = trans
+				state.cpath.pathKey = newPathKey(key, trans)
 				state.cpath.path = true
 				state.cpath.pathVerts = aux
+				state.cpath.rect = op.bounds
 				d.pathOps = append(d.pathOps, state.cpath)
 			}
 			aux = nil
@@ -695,23 +914,73 @@ loop:
 			var op gdraw.ColorOp
 			op.Decode(encOp.Data, encOp.Refs)
 			state.img = nil
+			state.hasGradient = false
 			state.color = op.Color
+		case ops.TypeLinearGradient:
+			var op gdraw.LinearGradientOp
+			// Use the encoded stops to avoid allocating.
+			op.Decode(encOp.Data, nil)
+			state.img = nil
+			state.hasGradient = true
+			state.gradient = gradientState{
+				space:  gradient.LinearSpace(op.Start, op.End),
+				stops:  aux,
+				spread: op.Spread,
+			}
+			aux = nil
+			auxKey = ui.OpKey{}
+		case ops.TypeRadialGradient:
+			var op gdraw.RadialGradientOp
+			// Use the encoded stops to avoid allocating.
+			op.Decode(encOp.Data, nil)
+			state.img = nil
+			state.hasGradient = true
+			state.gradient = gradientState{
+				radial: true,
+				space:  gradient.RadialSpace(op.Center, op.Radius),
+				stops:  aux,
+				spread: op.Spread,
+			}
+			aux = nil
+			auxKey = ui.OpKey{}
 		case ops.TypeImage:
 			var op gdraw.ImageOp
 			op.Decode(encOp.Data, encOp.Refs)
 			state.img = op.Src
 			state.imgRect = op.Rect
+			state.hasGradient = false
 		case ops.TypeDraw:
 			var op gdraw.DrawOp
 			op.Decode(encOp.Data, encOp.Refs)
-			off := state.t.Transform(f32.Point{})
-			clip := state.clip.Intersect(op.Rect.Add(off))
+			trect, isRect := state.t.TransformRect(op.Rect)
+			clip := state.clip.Intersect(trect)
 			if clip.Empty() {
 				continue
 			}
 			bounds := boundRectF(clip)
-			mat := state.materialFor(d.cache, op.Rect, off, bounds)
-			if bounds.Min == (image.Point{}) && bounds.Max == d.viewport && state.rect && mat.opaque && mat.material == materialColor {
+			trans, off := splitTransform(state.t)
+			cpath, rect := state.cpath, state.rect
+			if !isRect {
+				// Clip to the transformed rectangle.
+				cpath = d.newPathOp()
+				*cpath = pathOp{
+					parent:  state.cpath,
+					off:     off,
+					trans:   trans,
+					pathKey: newPathKey(encOp.Key, trans),
+					path:    true,
+					rect:    op.Rect,
+				}
+				d.pathOps = append(d.pathOps, cpath)
+				rect = false
+			}
+			mat := state.materialFor(d.cache, d.gradients, op.Rect, trect, isRect, bounds)
+			if state.blend != gdraw.BlendSrcOver {
+				// The result depends on the destination.
+				mat.blend = state.blend
+				mat.opaque = false
+			}
+			if state.layer == nil && bounds.Min == (image.Point{}) && bounds.Max == d.viewport && rect && mat.opaque && mat.material == materialColor {
 				// The image is a uniform opaque color and takes up the whole screen.
 				// Scrap images up to and including this image and set clear color.
 				d.zimageOps = d.zimageOps[:0]
@@ -721,22 +990,48 @@ loop:
 				continue
 			}
 			state.z++
-			// Assume 16-bit depth buffer.
-			const zdepth = 1 << 16
-			// Convert z to window-space, assuming depth range [0;1].
-			zf := float32(state.z)*2/zdepth - 1.0
 			img := imageOp{
-				z:        zf,
-				path:     state.cpath,
+				z:        zf(state.z),
+				path:     cpath,
 				off:      off,
 				clip:     bounds,
 				material: mat,
 			}
-			if state.rect && img.material.opaque {
-				d.zimageOps = append(d.zimageOps, img)
-			} else {
-				d.imageOps = append(d.imageOps, img)
+			d.addImageOp(&state, img, rect)
+		case ops.TypeBlend:
+			var op gdraw.BlendOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.blend = op.Mode
+		case ops.TypeLayer:
+			var op gdraw.OpacityOp
+			op.Decode(encOp.Data, encOp.Refs)
+			if op.Alpha == 1 && state.blend == gdraw.BlendSrcOver {
+				// An opaque layer is the same as no layer.
+				continue
+			}
+			// The layer covers the rest of the stack.
+			l := &layer{alpha: op.Alpha}
+			lstate := state
+			lstate.layer = l
+			lstate.blend = gdraw.BlendSrcOver
+			state.z = d.collectOps(r, lstate)
+			if l.bounds.Empty() {
+				break loop
 			}
+			d.layers = append(d.layers, l)
+			state.z++
+			img := imageOp{
+				z:    zf(state.z),
+				clip: l.bounds,
+				material: material{
+					material: materialLayer,
+					layer:    len(d.layers) - 1,
+					alpha:    l.alpha,
+					blend:    state.blend,
+				},
+			}
+			d.addImageOp(&state, img, false)
+			break loop
 		case ops.TypePush:
 			state.z = d.collectOps(r, state)
 		case ops.TypePop:
@@ -746,6 +1041,30 @@ loop:
 	return state.z
 }
 
+// addImageOp adds an image to the current layer, or to the
+// frame if there is no layer. Opaque images in rect clips
+// are drawn front to back in the frame.
+func (d *drawOps) addImageOp(state *drawState, img imageOp, rect bool) {
+	switch {
+	case state.layer != nil:
+		l := state.layer
+		l.bounds = l.bounds.Union(img.clip)
+		l.imageOps = append(l.imageOps, img)
+	case rect && img.material.opaque:
+		d.zimageOps = append(d.zimageOps, img)
+	default:
+		d.imageOps = append(d.imageOps, img)
+	}
+}
+
+// zf converts z to window-space.
+func zf(z int) float32 {
+	// Assume 16-bit depth buffer.
+	const zdepth = 1 << 16
+	// Convert z to window-space, assuming depth range [0;1].
+	return float32(z)*2/zdepth - 1.0
+}
+
 func expandPathOp(p *pathOp, clip image.Rectangle) {
 	for p != nil {
 		pclip := p.clip
@@ -757,9 +1076,35 @@ func expandPathOp(p *pathOp, clip image.Rectangle) {
 	}
 }
 
-func (d *drawState) materialFor(cache *resourceCache, rect f32.Rectangle, off f32.Point, clip image.Rectangle) material {
+// materialFor returns the material for drawing rect clipped to
+// clip, where trect is the bounds of the transformed rect.
+func (d *drawState) materialFor(cache *resourceCache, gradients *gradientCache, rect, trect f32.Rectangle, isRect bool, clip image.Rectangle) material {
 	var m material
-	if d.img == nil {
+	if d.hasGradient {
+		g := &d.gradient
+		m.material = materialLinearGradient
+		if g.radial {
+			m.material = materialRadialGradient
+		}
+		m.spread = g.spread
+		m.opaque = len(g.stops) > 0
+		for i := 0; i < len(g.stops); i += gradient.StopSize {
+			if _, c := gradient.DecodeStop(g.stops[i:]); c.A != 0xff {
+				m.opaque = false
+			}
+		}
+		tex, exists := gradients.get(g.stops)
+		if !exists {
+			tex = &texture{
+				src: gradientImage(decodeStops(g.stops)),
+			}
+			gradients.put(g.stops, tex)
+		}
+		m.texture = tex
+		// From quad to screen to gradient coordinates.
+		t := ui.Offset(toPointF(clip.Min)).Mul(ui.Scale(toPointF(clip.Size())))
+		m.uvTrans = g.space.Mul(d.t.Invert()).Mul(t)
+	} else if d.img == nil {
 		m.material = materialColor
 		m.color = gamma(d.color.RGBA())
 		m.opaque = m.color[3] == 1.0
@@ -769,7 +1114,20 @@ func (d *drawState) materialFor(cache *resourceCache, rect f32.Rectangle, off f3
 		m.opaque = m.color[3] == 1.0
 	} else {
 		m.material = materialTexture
-		dr := boundRectF(rect.Add(off))
+		tex, exists := cache.get(d.img)
+		if !exists {
+			t := &texture{
+				src: d.img,
+			}
+			cache.put(d.img, t)
+			tex = t
+		}
+		m.texture = tex.(*texture)
+		if !isRect {
+			m.uvTrans = d.uvTransform(rect, clip)
+			return m
+		}
+		dr := boundRectF(trect)
 		sr := d.imgRect
 		if dx := dr.Dx(); dx != 0 {
 			// Don't clip 1 px width sources.
@@ -785,20 +1143,34 @@ func (d *drawState) materialFor(cache *resourceCache, rect f32.Rectangle, off f3
 				sr.Max.Y -= ((dr.Max.Y-clip.Max.Y)*sdy + dy/2) / dy
 			}
 		}
-		tex, exists := cache.get(d.img)
-		if !exists {
-			t := &texture{
-				src: d.img,
-			}
-			cache.put(d.img, t)
-			tex = t
-		}
-		m.texture = tex.(*texture)
-		m.uvScale, m.uvOffset = texSpaceTransform(sr, d.img.Bounds().Size())
+		uvScale, uvOffset := texSpaceTransform(sr, d.img.Bounds().Size())
+		m.uvTrans = ui.Offset(uvOffset).Mul(ui.Scale(uvScale))
 	}
 	return m
 }
 
+// uvTransform returns the transformation from quad texture
+// coordinates of clip to texture coordinates of the current
+// image drawn transformed into rect.
+func (d *drawState) uvTransform(rect f32.Rectangle, clip image.Rectangle) ui.Transform {
+	b := d.img.Bounds()
+	sr := d.imgRect
+	// From quad to screen coordinates.
+	t := ui.Offset(toPointF(clip.Min)).Mul(ui.Scale(toPointF(clip.Size())))
+	// From screen coordinates to the untransformed rect.
+	t = d.t.Invert().Mul(t)
+	// From rect to the source image rectangle.
+	t = ui.Offset(rect.Min.Mul(-1)).Mul(t)
+	t = ui.Scale(f32.Point{
+		X: float32(sr.Dx()) / rect.Dx(),
+		Y: float32(sr.Dy()) / rect.Dy(),
+	}).Mul(t)
+	t = ui.Offset(toPointF(sr.Min.Sub(b.Min))).Mul(t)
+	// From image to texture coordinates.
+	size := toPointF(b.Size())
+	return ui.Scale(f32.Point{X: 1 / size.X, Y: 1 / size.Y}).Mul(t)
+}
+
 func (r *renderer) drawZOps(ops []imageOp) {
 	r.ctx.Enable(gl.DEPTH_TEST)
 	r.ctx.BindBuffer(gl.ARRAY_BUFFER, r.blitter.quadVerts)
@@ -811,22 +1183,58 @@ func (r *renderer) drawZOps(ops []imageOp) {
 		img := ops[i]
 		m := img.material
 		switch m.material {
-		case materialTexture:
+		case materialTexture, materialLinearGradient, materialRadialGradient:
 			r.ctx.BindTexture(gl.TEXTURE_2D, r.texHandle(m.texture))
 		}
 		drc := img.clip
 		scale, off := clipSpaceTransform(drc, r.blitter.viewport)
-		r.blitter.blit(img.z, m.material, m.color, scale, off, m.uvScale, m.uvOffset)
+		r.blitter.blit(img.z, &m, scale, off)
 	}
 	r.ctx.DisableVertexAttribArray(attribPos)
 	r.ctx.DisableVertexAttribArray(attribUV)
 	r.ctx.Disable(gl.DEPTH_TEST)
 }
 
-func (r *renderer) drawOps(ops []imageOp) {
+// scissor restricts drawing to the region of the window
+// framebuffer, if the region doesn't cover the viewport.
+func (r *renderer) scissor(region image.Rectangle, viewport image.Point) {
+	if region == (image.Rectangle{Max: viewport}) {
+		return
+	}
+	r.ctx.Enable(gl.SCISSOR_TEST)
+	// OpenGL framebuffers start at the bottom.
+	r.ctx.Scissor(int32(region.Min.X), int32(viewport.Y-region.Max.Y), int32(region.Dx()), int32(region.Dy()))
+}
+
+// drawLayers draws the layers into their textures.
+func (r *renderer) drawLayers(layers []*layer) {
+	if len(layers) == 0 {
+		return
+	}
+	sizes := make([]image.Point, len(layers))
+	for i, l := range layers {
+		sizes[i] = l.bounds.Size()
+	}
+	r.layers.resize(r.ctx, r.ctx.caps.srgbaTriple, sizes)
+	for i, l := range layers {
+		r.packIntersections(l.imageOps)
+		r.intersect(l.imageOps)
+		bindFramebuffer(r.ctx, r.layers.fbos[i].fbo)
+		r.ctx.Viewport(0, 0, l.bounds.Dx(), l.bounds.Dy())
+		r.ctx.ClearColor(0, 0, 0, 0)
+		r.ctx.Clear(gl.COLOR_BUFFER_BIT)
+		r.drawOps(l.imageOps, l.bounds)
+	}
+	r.ctx.BindFramebuffer(gl.FRAMEBUFFER, r.pather.stenciler.defFBO)
+}
+
+// drawOps draws images to the framebuffer that covers
+// viewport in window coordinates.
+func (r *renderer) drawOps(ops []imageOp, viewport image.Rectangle) {
 	r.ctx.Enable(gl.DEPTH_TEST)
 	r.ctx.DepthMask(false)
-	r.ctx.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	blend := gdraw.BlendSrcOver
+	r.ctx.BlendFunc(blendFunc(blend))
 	r.ctx.BindBuffer(gl.ARRAY_BUFFER, r.blitter.quadVerts)
 	r.ctx.VertexAttribPointer(attribPos, 2, gl.FLOAT, false, 4*4, 0)
 	r.ctx.VertexAttribPointer(attribUV, 2, gl.FLOAT, false, 4*4, 4*2)
@@ -836,18 +1244,31 @@ func (r *renderer) drawOps(ops []imageOp) {
 	for _, img := range ops {
 		m := img.material
 		switch m.material {
-		case materialTexture:
+		case materialTexture, materialLinearGradient, materialRadialGradient:
 			r.ctx.BindTexture(gl.TEXTURE_2D, r.texHandle(m.texture))
+		case materialLayer:
+			f := r.layers.fbos[m.layer]
+			r.ctx.BindTexture(gl.TEXTURE_2D, f.tex)
+			m.uvTrans = layerUVTransform(img.clip.Size(), f.size)
 		}
 		drc := img.clip
-		scale, off := clipSpaceTransform(drc, r.blitter.viewport)
+		scale, off := clipSpaceTransform(drc.Sub(viewport.Min), viewport.Size())
+		if m.blend != blend {
+			blend = m.blend
+			r.ctx.BlendFunc(blendFunc(blend))
+		}
+		if blendReadsDst(m.blend) {
+			m.dstOffset, m.dstScale = r.copyDst(drc.Sub(viewport.Min), viewport.Dy())
+		}
 		var fbo stencilFBO
+		rule := gdraw.NonZero
 		switch img.clipType {
 		case clipTypeNone:
-			r.blitter.blit(img.z, m.material, m.color, scale, off, m.uvScale, m.uvOffset)
+			r.blitter.blit(img.z, &m, scale, off)
 			continue
 		case clipTypePath:
 			fbo = r.pather.stenciler.cover(img.place.Idx)
+			rule = img.fillRule
 		case clipTypeIntersection:
 			fbo = r.pather.stenciler.intersections.fbos[img.place.Idx]
 		}
@@ -862,7 +1283,7 @@ func (r *renderer) drawOps(ops []imageOp) {
 			Max: img.place.Pos.Add(drc.Size()),
 		}
 		coverScale, coverOff := texSpaceTransform(uv, fbo.size)
-		r.pather.cover(img.z, m.material, m.color, scale, off, m.uvScale, m.uvOffset, coverScale, coverOff)
+		r.pather.cover(img.z, &m, scale, off, coverScale, coverOff, rule)
 	}
 	r.ctx.DisableVertexAttribArray(attribPos)
 	r.ctx.DisableVertexAttribArray(attribUV)
@@ -891,6 +1312,49 @@ func (r *renderer) uploadTexture(img image.Image) {
 
 }
 
+// copyDst copies the rectangle r of the framebuffer to the
+// destination texture bound to texture unit 2. The height of
+// the framebuffer is fbHeight. copyDst returns the transformation
+// from window coordinates to destination texture coordinates.
+func (r *renderer) copyDst(rect image.Rectangle, fbHeight int) (f32.Point, f32.Point) {
+	r.ctx.ActiveTexture(gl.TEXTURE2)
+	defer r.ctx.ActiveTexture(gl.TEXTURE0)
+	sz := rect.Size()
+	if r.dst.tex == (gl.Texture{}) {
+		r.dst.tex = createTexture(r.ctx)
+	}
+	r.ctx.BindTexture(gl.TEXTURE_2D, r.dst.tex)
+	if sz.X > r.dst.size.X || sz.Y > r.dst.size.Y {
+		if sz.X < r.dst.size.X {
+			sz.X = r.dst.size.X
+		}
+		if sz.Y < r.dst.size.Y {
+			sz.Y = r.dst.size.Y
+		}
+		r.dst.size = sz
+		tt := r.ctx.caps.srgbaTriple
+		r.ctx.TexImage2D(gl.TEXTURE_2D, 0, tt.internalFormat, sz.X, sz.Y, tt.format, tt.typ, nil)
+	}
+	// OpenGL framebuffers start at the bottom.
+	x, y := rect.Min.X, fbHeight-rect.Max.Y
+	r.ctx.CopyTexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, x, y, rect.Dx(), rect.Dy())
+	offset := f32.Point{X: float32(x), Y: float32(y)}
+	scale := f32.Point{X: 1 / float32(r.dst.size.X), Y: 1 / float32(r.dst.size.Y)}
+	return offset, scale
+}
+
+// layerUVTransform returns the transformation from quad
+// texture coordinates to the texture coordinates of a layer of
+// size drawn into a texture of size texSize. Layers are drawn
+// upside down, because OpenGL framebuffers start at the bottom.
+func layerUVTransform(size, texSize image.Point) ui.Transform {
+	t := ui.Offset(f32.Point{Y: 1}).Mul(ui.Scale(f32.Point{X: 1, Y: -1}))
+	return ui.Scale(f32.Point{
+		X: float32(size.X) / float32(texSize.X),
+		Y: float32(size.Y) / float32(texSize.Y),
+	}).Mul(t)
+}
+
 func gamma(r, g, b, a uint32) [4]float32 {
 	color := [4]float32{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
 	// Assume that image.Uniform colors are in sRGB space. Linearize.
@@ -906,21 +1370,123 @@ func gamma(r, g, b, a uint32) [4]float32 {
 	return color
 }
 
-func (b *blitter) blit(z float32, mat materialType, col [4]float32, scale, off, uvScale, uvOff f32.Point) {
-	b.ctx.UseProgram(b.prog[mat])
+// decodeStops decodes the encoded stops of a gradient op.
+func decodeStops(aux []byte) []gdraw.ColorStop {
+	stops := make([]gdraw.ColorStop, len(aux)/gradient.StopSize)
+	for i := range stops {
+		stops[i].Offset, stops[i].Color = gradient.DecodeStop(aux[i*gradient.StopSize:])
+	}
+	return stops
+}
+
+// gradientImage returns an image with the colors of
+// the stops between offset 0 and 1. The colors are interpolated
+// in linear space and then converted to sRGB for uploading.
+func gradientImage(stops []gdraw.ColorStop) *image.RGBA {
+	const n = gradientTextureSize
+	img := image.NewRGBA(image.Rect(0, 0, n, 1))
+	if len(stops) == 0 {
+		return img
+	}
+	cols := make([][4]float32, len(stops))
+	for i, s := range stops {
+		cols[i] = gamma(s.Color.RGBA())
+	}
+	for x := 0; x < n; x++ {
+		t := float32(x) / (n - 1)
+		c := cols[len(cols)-1]
+		if t <= stops[0].Offset {
+			c = cols[0]
+		} else {
+			for i := 1; i < len(stops); i++ {
+				s0, s1 := stops[i-1], stops[i]
+				if t > s1.Offset {
+					continue
+				}
+				f := (t - s0.Offset) / (s1.Offset - s0.Offset)
+				for j := range c {
+					c[j] = cols[i-1][j]*(1-f) + cols[i][j]*f
+				}
+				break
+			}
+		}
+		pix := img.Pix[x*4 : x*4+4]
+		for j := 0; j < 3; j++ {
+			pix[j] = uint8(toSRGB(c[j])*0xff + .5)
+		}
+		pix[3] = uint8(c[3]*0xff + .5)
+	}
+	return img
+}
+
+// toSRGB is the inverse of the linearization in gamma.
+func toSRGB(c float32) float32 {
+	if c <= 0.0031308 {
+		return c * 12.92
+	}
+	return 1.055*float32(math.Pow(float64(c), 1/2.4)) - 0.055
+}
+
+func (b *blitter) blit(z float32, m *material, scale, off f32.Point) {
+	mat := m.material
+	p := programFor(m)
+	b.ctx.UseProgram(b.prog[p])
 	switch mat {
 	case materialColor:
-		b.ctx.Uniform4f(b.vars[mat].uColor, col[0], col[1], col[2], col[3])
-	case materialTexture:
-		b.ctx.Uniform2f(b.vars[mat].uUVScale, uvScale.X, uvScale.Y)
-		b.ctx.Uniform2f(b.vars[mat].uUVOffset, uvOff.X, uvOff.Y)
-	}
-	b.ctx.Uniform1f(b.vars[mat].z, z)
-	b.ctx.Uniform2f(b.vars[mat].uScale, scale.X, scale.Y)
-	b.ctx.Uniform2f(b.vars[mat].uOffset, off.X, off.Y)
+		col := m.color
+		b.ctx.Uniform4f(b.vars[p].uColor, col[0], col[1], col[2], col[3])
+	default:
+		sx, hx, ox, hy, sy, oy := m.uvTrans.Elems()
+		b.ctx.Uniform3f(b.vars[p].uUVTrans1, sx, hx, ox)
+		b.ctx.Uniform3f(b.vars[p].uUVTrans2, hy, sy, oy)
+	}
+	switch mat {
+	case materialLinearGradient, materialRadialGradient:
+		b.ctx.Uniform1f(b.vars[p].uSpread, float32(m.spread))
+	case materialLayer:
+		b.ctx.Uniform1f(b.vars[p].uAlpha, m.alpha)
+	}
+	if p >= numMaterials {
+		b.ctx.Uniform2f(b.vars[p].uDstOffset, m.dstOffset.X, m.dstOffset.Y)
+		b.ctx.Uniform2f(b.vars[p].uDstScale, m.dstScale.X, m.dstScale.Y)
+		b.ctx.Uniform1f(b.vars[p].uBlendMode, float32(m.blend))
+	}
+	b.ctx.Uniform1f(b.vars[p].z, z)
+	b.ctx.Uniform2f(b.vars[p].uScale, scale.X, scale.Y)
+	b.ctx.Uniform2f(b.vars[p].uOffset, off.X, off.Y)
 	b.ctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
 }
 
+// splitTransform splits t into its linear part
+// and its offset.
+func splitTransform(t ui.Transform) (ui.Transform, f32.Point) {
+	off := t.Transform(f32.Point{})
+	return ui.Offset(off.Mul(-1)).Mul(t), off
+}
+
+func newPathKey(k ui.OpKey, trans ui.Transform) pathKey {
+	sx, hx, _, hy, sy, _ := trans.Elems()
+	return pathKey{op: k, sx: sx, hx: hx, hy: hy, sy: sy}
+}
+
+func toPointF(p image.Point) f32.Point {
+	return f32.Point{X: float32(p.X), Y: float32(p.Y)}
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // texSpaceTransform return the scale and offset that transforms the given subimage
 // into quad texture coordinates.
 func texSpaceTransform(r image.Rectangle, bounds image.Point) (f32.Point, f32.Point) {
@@ -988,8 +1554,8 @@ uniform vec2 offset;
 attribute vec2 pos;
 
 attribute vec2 uv;
-uniform vec2 uvScale;
-uniform vec2 uvOffset;
+uniform vec3 uvTransform1;
+uniform vec3 uvTransform2;
 
 varying vec2 vUV;
 
@@ -998,7 +1564,8 @@ void main() {
 	p *= scale;
 	p += offset;
 	gl_Position = vec4(p, z, 1);
-	vUV = uv*uvScale + uvOffset;
+	vec3 uv3 = vec3(uv, 1.0);
+	vUV = vec2(dot(uvTransform1, uv3), dot(uvTransform2, uv3));
 }
 `
 
@@ -1011,7 +1578,75 @@ varying vec2 vUV;
 
 HEADER
 
+BLEND_HEADER
+
 void main() {
-	gl_FragColor = GET_COLOR;
+	gl_FragColor = blend(GET_COLOR);
+}
+`
+
+// blendHeader declares the blend function for the
+// programs that blend with the fixed function blending
+// of OpenGL.
+const blendHeader = `
+vec4 blend(vec4 src) {
+	return src;
+}
+`
+
+// dstBlendHeader declares the blend function for the
+// programs that blend with a copy of the destination. The
+// blendMode uniform is the gdraw.BlendMode. The blend modes
+// are the separable modes of the W3C compositing specification,
+// for premultiplied colors.
+const dstBlendHeader = `
+uniform sampler2D dst;
+uniform highp vec2 dstOffset;
+uniform highp vec2 dstScale;
+uniform float blendMode;
+
+vec4 blend(vec4 s) {
+	vec4 d = texture2D(dst, (gl_FragCoord.xy - dstOffset)*dstScale);
+	vec3 b;
+	if (blendMode < 1.5) {
+		// Multiply.
+		b = s.rgb*d.rgb;
+	} else if (blendMode < 3.5) {
+		// Overlay.
+		vec3 lo = 2.0*s.rgb*d.rgb;
+		vec3 hi = s.a*d.a - 2.0*(d.a - d.rgb)*(s.a - s.rgb);
+		b = mix(lo, hi, step(0.5*d.a, d.rgb));
+	} else if (blendMode < 4.5) {
+		// Darken.
+		b = min(s.rgb*d.a, d.rgb*s.a);
+	} else {
+		// Lighten.
+		b = max(s.rgb*d.a, d.rgb*s.a);
+	}
+	vec3 rgb = (1.0 - d.a)*s.rgb + (1.0 - s.a)*d.rgb + b;
+	return vec4(rgb, s.a + d.a - s.a*d.a);
+}
+`
+
+// gradientHeader declares the gradient function for the
+// gradient materials. The spread uniform is the gdraw.Spread
+// of the gradient.
+const gradientHeader = `
+uniform sampler2D tex;
+uniform float spread;
+
+vec4 gradient(float t) {
+	if (spread > 1.5) {
+		// Reflect.
+		t = 1.0 - abs(mod(abs(t), 2.0) - 1.0);
+	} else if (spread > 0.5) {
+		// Repeat.
+		t = fract(t);
+	}
+	t = clamp(t, 0.0, 1.0);
+	// Sample the centers of the first and last texels
+	// at offset 0 and 1.
+	const float n = 256.0;
+	return texture2D(tex, vec2((t*(n - 1.0) + 0.5)/n, 0.5));
 }
 `
@@ -9,8 +9,11 @@ import (
 	"image"
 	"unsafe"
 
+	"gioui.org/ui"
 	"gioui.org/ui/app/internal/gl"
+	gdraw "gioui.org/ui/draw"
 	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/ops"
 	"gioui.org/ui/internal/path"
 )
 
@@ -25,13 +28,19 @@ type pather struct {
 
 type coverer struct {
 	ctx  *context
-	prog [2]gl.Program
-	vars [2]struct {
+	prog [numPrograms]gl.Program
+	vars [numPrograms]struct {
 		z                             gl.Uniform
 		uScale, uOffset               gl.Uniform
-		uUVScale, uUVOffset           gl.Uniform
+		uUVTrans1, uUVTrans2          gl.Uniform
 		uCoverUVScale, uCoverUVOffset gl.Uniform
 		uColor                        gl.Uniform
+		uEvenOdd                      gl.Uniform
+		uSpread                       gl.Uniform
+		uAlpha                        gl.Uniform
+		uDstOffset                    gl.Uniform
+		uDstScale                     gl.Uniform
+		uBlendMode                    gl.Uniform
 	}
 }
 
@@ -47,6 +56,7 @@ type stenciler struct {
 	uPathOffset        gl.Uniform
 	uIntersectUVOffset gl.Uniform
 	uIntersectUVScale  gl.Uniform
+	uIntersectEvenOdd  gl.Uniform
 	indexBuf           gl.Buffer
 }
 
@@ -95,14 +105,28 @@ func newCoverer(ctx *context) *coverer {
 	}
 	for i, prog := range prog {
 		ctx.UseProgram(prog)
-		switch materialType(i) {
-		case materialTexture:
-			uTex := gl.GetUniformLocation(ctx.Functions, prog, "tex")
-			ctx.Uniform1i(uTex, 0)
-			c.vars[i].uUVScale = gl.GetUniformLocation(ctx.Functions, prog, "uvScale")
-			c.vars[i].uUVOffset = gl.GetUniformLocation(ctx.Functions, prog, "uvOffset")
+		mat := materialType(i % numMaterials)
+		switch mat {
 		case materialColor:
 			c.vars[i].uColor = gl.GetUniformLocation(ctx.Functions, prog, "color")
+		default:
+			uTex := gl.GetUniformLocation(ctx.Functions, prog, "tex")
+			ctx.Uniform1i(uTex, 0)
+			c.vars[i].uUVTrans1 = gl.GetUniformLocation(ctx.Functions, prog, "uvTransform1")
+			c.vars[i].uUVTrans2 = gl.GetUniformLocation(ctx.Functions, prog, "uvTransform2")
+		}
+		switch mat {
+		case materialLinearGradient, materialRadialGradient:
+			c.vars[i].uSpread = gl.GetUniformLocation(ctx.Functions, prog, "spread")
+		case materialLayer:
+			c.vars[i].uAlpha = gl.GetUniformLocation(ctx.Functions, prog, "alpha")
+		}
+		if i >= numMaterials {
+			uDst := gl.GetUniformLocation(ctx.Functions, prog, "dst")
+			ctx.Uniform1i(uDst, 2)
+			c.vars[i].uDstOffset = gl.GetUniformLocation(ctx.Functions, prog, "dstOffset")
+			c.vars[i].uDstScale = gl.GetUniformLocation(ctx.Functions, prog, "dstScale")
+			c.vars[i].uBlendMode = gl.GetUniformLocation(ctx.Functions, prog, "blendMode")
 		}
 		uCover := gl.GetUniformLocation(ctx.Functions, prog, "cover")
 		ctx.Uniform1i(uCover, 1)
@@ -111,6 +135,7 @@ func newCoverer(ctx *context) *coverer {
 		c.vars[i].uOffset = gl.GetUniformLocation(ctx.Functions, prog, "offset")
 		c.vars[i].uCoverUVScale = gl.GetUniformLocation(ctx.Functions, prog, "uvCoverScale")
 		c.vars[i].uCoverUVOffset = gl.GetUniformLocation(ctx.Functions, prog, "uvCoverOffset")
+		c.vars[i].uEvenOdd = gl.GetUniformLocation(ctx.Functions, prog, "evenOdd")
 	}
 	return c
 }
@@ -139,11 +164,12 @@ func newStenciler(ctx *context) *stenciler {
 		uPathOffset:        gl.GetUniformLocation(ctx.Functions, prog, "pathOffset"),
 		uIntersectUVScale:  gl.GetUniformLocation(ctx.Functions, iprog, "uvScale"),
 		uIntersectUVOffset: gl.GetUniformLocation(ctx.Functions, iprog, "uvOffset"),
+		uIntersectEvenOdd:  gl.GetUniformLocation(ctx.Functions, iprog, "evenOdd"),
 		indexBuf:           ctx.CreateBuffer(),
 	}
 }
 
-func (s *fboSet) resize(ctx *context, sizes []image.Point) {
+func (s *fboSet) resize(ctx *context, tt textureTriple, sizes []image.Point) {
 	// Add fbos.
 	for i := len(s.fbos); i < len(sizes); i++ {
 		tex := ctx.CreateTexture()
@@ -169,7 +195,6 @@ func (s *fboSet) resize(ctx *context, sizes []image.Point) {
 		if resize {
 			f.size = sz
 			ctx.BindTexture(gl.TEXTURE_2D, f.tex)
-			tt := ctx.caps.floatTriple
 			ctx.TexImage2D(gl.TEXTURE_2D, 0, tt.internalFormat, sz.X, sz.Y, tt.format, tt.typ, nil)
 			ctx.BindFramebuffer(gl.FRAMEBUFFER, f.fbo)
 			ctx.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, f.tex, 0)
@@ -222,6 +247,110 @@ func buildPath(ctx *context, p []byte) *pathData {
 	}
 }
 
+// vertices returns the vertices of the path transformed by p.trans.
+func (p *pathOp) vertices() []byte {
+	if p.pathVerts == nil {
+		return rectPath(p.rect, p.trans)
+	}
+	if p.trans == (ui.Transform{}) {
+		return p.pathVerts
+	}
+	return transformPath(p.pathVerts, p.trans)
+}
+
+// transformPath transforms path vertices from draw.PathBuilder.
+// The vertices are encoded anew, because other transformations
+// than offsets don't preserve the x monotonicity of curves. Vertical
+// curves are not encoded, but they contribute after rotation or
+// shearing; they are recovered from the gaps in the path.
+func transformPath(verts []byte, t ui.Transform) []byte {
+	var b transformBuilder
+	b.init(t)
+	var start, pen f32.Point
+	first := true
+	for len(verts) >= 4*path.VertStride {
+		from, ctrl, to := path.DecodeCurve(verts)
+		verts = verts[4*path.VertStride:]
+		switch {
+		case first:
+			b.move(from)
+			start = from
+			first = false
+		case from.X != pen.X:
+			// A new contour.
+			b.line(start)
+			b.move(from)
+			start = from
+		case from.Y != pen.Y:
+			// A discarded vertical curve.
+			b.line(from)
+		}
+		b.quad(ctrl, to)
+		pen = to
+	}
+	if !first {
+		b.line(start)
+	}
+	return b.end()
+}
+
+// rectPath returns the path vertices of r transformed by t.
+func rectPath(r f32.Rectangle, t ui.Transform) []byte {
+	var b transformBuilder
+	b.init(t)
+	b.move(r.Min)
+	b.line(f32.Point{X: r.Max.X, Y: r.Min.Y})
+	b.line(r.Max)
+	b.line(f32.Point{X: r.Min.X, Y: r.Max.Y})
+	b.line(r.Min)
+	return b.end()
+}
+
+// transformBuilder is a draw.PathBuilder that transforms
+// absolute coordinates.
+type transformBuilder struct {
+	ops     ui.Ops
+	builder gdraw.PathBuilder
+	t       ui.Transform
+	pen     f32.Point
+}
+
+func (b *transformBuilder) init(t ui.Transform) {
+	b.t = t
+	b.builder.Init(&b.ops)
+}
+
+func (b *transformBuilder) move(to f32.Point) {
+	to = b.t.Transform(to)
+	b.builder.Move(to.Sub(b.pen))
+	b.pen = to
+}
+
+func (b *transformBuilder) line(to f32.Point) {
+	to = b.t.Transform(to)
+	b.builder.Line(to.Sub(b.pen))
+	b.pen = to
+}
+
+func (b *transformBuilder) quad(ctrl, to f32.Point) {
+	ctrl, to = b.t.Transform(ctrl), b.t.Transform(to)
+	b.builder.Quad(ctrl.Sub(b.pen), to.Sub(b.pen))
+	b.pen = to
+}
+
+// end completes the path and returns its vertices.
+func (b *transformBuilder) end() []byte {
+	b.builder.End()
+	var r ui.OpsReader
+	r.Reset(&b.ops)
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		if ops.OpType(encOp.Data[0]) == ops.TypeAux {
+			return encOp.Data[ops.TypeAuxLen:]
+		}
+	}
+	return nil
+}
+
 func (p *pathData) release(ctx *context) {
 	ctx.DeleteBuffer(p.data)
 }
@@ -246,7 +375,7 @@ func (s *stenciler) beginIntersect(sizes []image.Point) {
 	// 8 bit coverage is enough, but OpenGL ES only supports single channel
 	// floating point formats. Replace with GL_RGB+GL_UNSIGNED_BYTE if
 	// no floating point support is available.
-	s.intersections.resize(s.ctx, sizes)
+	s.intersections.resize(s.ctx, s.ctx.caps.floatTriple, sizes)
 	s.ctx.ClearColor(1.0, 0.0, 0.0, 0.0)
 	s.ctx.UseProgram(s.iprog)
 }
@@ -270,7 +399,7 @@ func (s *stenciler) begin(sizes []image.Point) {
 	s.ctx.BindTexture(gl.TEXTURE_2D, gl.Texture{})
 	s.ctx.ActiveTexture(gl.TEXTURE0)
 	s.ctx.BlendFunc(gl.ONE, gl.ONE)
-	s.fbos.resize(s.ctx, sizes)
+	s.fbos.resize(s.ctx, s.ctx.caps.floatTriple, sizes)
 	s.ctx.ClearColor(0.0, 0.0, 0.0, 0.0)
 	s.ctx.UseProgram(s.prog)
 	s.ctx.EnableVertexAttribArray(attribPathCorner)
@@ -334,27 +463,52 @@ func (s *stenciler) end() {
 	s.ctx.BindFramebuffer(gl.FRAMEBUFFER, s.defFBO)
 }
 
-func (p *pather) cover(z float32, mat materialType, col [4]float32, scale, off, uvScale, uvOff, coverScale, coverOff f32.Point) {
-	p.coverer.cover(z, mat, col, scale, off, uvScale, uvOff, coverScale, coverOff)
+func (p *pather) cover(z float32, m *material, scale, off, coverScale, coverOff f32.Point, rule gdraw.FillRule) {
+	p.coverer.cover(z, m, scale, off, coverScale, coverOff, rule)
 }
 
-func (c *coverer) cover(z float32, mat materialType, col [4]float32, scale, off, uvScale, uvOff, coverScale, coverOff f32.Point) {
-	c.ctx.UseProgram(c.prog[mat])
+func (c *coverer) cover(z float32, m *material, scale, off, coverScale, coverOff f32.Point, rule gdraw.FillRule) {
+	mat := m.material
+	p := programFor(m)
+	c.ctx.UseProgram(c.prog[p])
 	switch mat {
 	case materialColor:
-		c.ctx.Uniform4f(c.vars[mat].uColor, col[0], col[1], col[2], col[3])
-	case materialTexture:
-		c.ctx.Uniform2f(c.vars[mat].uUVScale, uvScale.X, uvScale.Y)
-		c.ctx.Uniform2f(c.vars[mat].uUVOffset, uvOff.X, uvOff.Y)
+		col := m.color
+		c.ctx.Uniform4f(c.vars[p].uColor, col[0], col[1], col[2], col[3])
+	default:
+		sx, hx, ox, hy, sy, oy := m.uvTrans.Elems()
+		c.ctx.Uniform3f(c.vars[p].uUVTrans1, sx, hx, ox)
+		c.ctx.Uniform3f(c.vars[p].uUVTrans2, hy, sy, oy)
+	}
+	switch mat {
+	case materialLinearGradient, materialRadialGradient:
+		c.ctx.Uniform1f(c.vars[p].uSpread, float32(m.spread))
+	case materialLayer:
+		c.ctx.Uniform1f(c.vars[p].uAlpha, m.alpha)
 	}
-	c.ctx.Uniform1f(c.vars[mat].z, z)
-	c.ctx.Uniform2f(c.vars[mat].uScale, scale.X, scale.Y)
-	c.ctx.Uniform2f(c.vars[mat].uOffset, off.X, off.Y)
-	c.ctx.Uniform2f(c.vars[mat].uCoverUVScale, coverScale.X, coverScale.Y)
-	c.ctx.Uniform2f(c.vars[mat].uCoverUVOffset, coverOff.X, coverOff.Y)
+	if p >= numMaterials {
+		c.ctx.Uniform2f(c.vars[p].uDstOffset, m.dstOffset.X, m.dstOffset.Y)
+		c.ctx.Uniform2f(c.vars[p].uDstScale, m.dstScale.X, m.dstScale.Y)
+		c.ctx.Uniform1f(c.vars[p].uBlendMode, float32(m.blend))
+	}
+	c.ctx.Uniform1f(c.vars[p].z, z)
+	c.ctx.Uniform2f(c.vars[p].uScale, scale.X, scale.Y)
+	c.ctx.Uniform2f(c.vars[p].uOffset, off.X, off.Y)
+	c.ctx.Uniform2f(c.vars[p].uCoverUVScale, coverScale.X, coverScale.Y)
+	c.ctx.Uniform2f(c.vars[p].uCoverUVOffset, coverOff.X, coverOff.Y)
+	c.ctx.Uniform1f(c.vars[p].uEvenOdd, evenOdd(rule))
 	c.ctx.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
 }
 
+// evenOdd returns the value of the evenOdd shader
+// uniform for a fill rule.
+func evenOdd(rule gdraw.FillRule) float32 {
+	if rule == gdraw.EvenOdd {
+		return 1
+	}
+	return 0
+}
+
 const stencilVSrc = `
 #version 100
 
@@ -492,8 +646,8 @@ precision highp float;
 uniform float z;
 uniform vec2 scale;
 uniform vec2 offset;
-uniform vec2 uvScale;
-uniform vec2 uvOffset;
+uniform vec3 uvTransform1;
+uniform vec3 uvTransform2;
 uniform vec2 uvCoverScale;
 uniform vec2 uvCoverOffset;
 
@@ -506,7 +660,8 @@ varying vec2 vUV;
 
 void main() {
     gl_Position = vec4(pos*scale + offset, z, 1);
-	vUV = uv*uvScale + uvOffset;
+	vec3 uv3 = vec3(uv, 1.0);
+	vUV = vec2(dot(uvTransform1, uv3), dot(uvTransform2, uv3));
 	vCoverUV = uv*uvCoverScale+uvCoverOffset;
 }
 `
@@ -520,14 +675,23 @@ precision mediump float;
 // large cover atlases.
 varying highp vec2 vCoverUV;
 uniform sampler2D cover;
+uniform float evenOdd;
 varying vec2 vUV;
 
 HEADER
 
+BLEND_HEADER
+
 void main() {
     gl_FragColor = GET_COLOR;
 	float cover = abs(texture2D(cover, vCoverUV).r);
-	gl_FragColor *= cover;
+	if (evenOdd > 0.5) {
+		cover = 1.0 - abs(1.0 - mod(cover, 2.0));
+	}
+	// Overlapping parts of a path, such as the outlines
+	// of a stroke crossing itself, cover pixels more than once.
+	cover = min(cover, 1.0);
+	gl_FragColor = blend(gl_FragColor*cover);
 }
 `
 
@@ -561,9 +725,16 @@ precision mediump float;
 // large cover atlases.
 varying highp vec2 vUV;
 uniform sampler2D cover;
+uniform float evenOdd;
 
 void main() {
 	float cover = abs(texture2D(cover, vUV).r);
+	if (evenOdd > 0.5) {
+		cover = 1.0 - abs(1.0 - mod(cover, 2.0));
+	}
+	// Overlapping parts of a path, such as the outlines
+	// of a stroke crossing itself, cover pixels more than once.
+	cover = min(cover, 1.0);
     gl_FragColor.r = cover;
 }
 `
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"gioui.org/ui/clipboard"
+	"gioui.org/ui/input"
+)
+
+type clipboardQueue struct {
+	// receivers are the keys waiting for
+	// the clipboard content.
+	receivers map[input.Key]struct{}
+	// requested is set when receivers are added.
+	requested bool
+	text      *string
+}
+
+// WriteClipboard returns the text written by the
+// most recent frame, if any.
+func (q *clipboardQueue) WriteClipboard() (string, bool) {
+	if q.text == nil {
+		return "", false
+	}
+	text := *q.text
+	q.text = nil
+	return text, true
+}
+
+// ReadClipboard reports whether the clipboard content
+// must be read for receivers added since the last call.
+func (q *clipboardQueue) ReadClipboard() bool {
+	r := q.requested
+	q.requested = false
+	return r
+}
+
+// Push delivers the clipboard content to the
+// waiting receivers.
+func (q *clipboardQueue) Push(e clipboard.Event, events *handlerEvents) {
+	for k := range q.receivers {
+		events.Add(k, e)
+		delete(q.receivers, k)
+	}
+}
+
+func (q *clipboardQueue) ProcessWriteClipboard(d []byte, refs []interface{}) {
+	var op clipboard.WriteOp
+	op.Decode(d, refs)
+	q.text = &op.Text
+}
+
+func (q *clipboardQueue) ProcessReadClipboard(d []byte, refs []interface{}) {
+	var op clipboard.ReadOp
+	op.Decode(d, refs)
+	if q.receivers == nil {
+		q.receivers = make(map[input.Key]struct{})
+	}
+	if _, ok := q.receivers[op.Key]; !ok {
+		q.receivers[op.Key] = struct{}{}
+		q.requested = true
+	}
+}
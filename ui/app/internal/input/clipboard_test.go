@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
+)
+
+func TestClipboard(t *testing.T) {
+	var r Router
+	ops := new(ui.Ops)
+	clipboard.WriteOp{Text: "copied"}.Add(ops)
+	r.Frame(ops)
+	if text, ok := r.WriteClipboard(); !ok || text != "copied" {
+		t.Errorf("WriteClipboard = %q, %v, want %q, true", text, ok, "copied")
+	}
+	if _, ok := r.WriteClipboard(); ok {
+		t.Error("clipboard written twice")
+	}
+	h1, h2 := new(int), new(int)
+	ops.Reset()
+	clipboard.ReadOp{Key: h1}.Add(ops)
+	clipboard.ReadOp{Key: h2}.Add(ops)
+	r.Frame(ops)
+	if !r.ReadClipboard() {
+		t.Fatal("clipboard not read")
+	}
+	// Waiting receivers don't read again.
+	ops.Reset()
+	clipboard.ReadOp{Key: h1}.Add(ops)
+	r.Frame(ops)
+	if r.ReadClipboard() {
+		t.Error("clipboard read twice")
+	}
+	r.Add(clipboard.Event{Text: "pasted"})
+	for _, h := range []*int{h1, h2} {
+		evts := r.Events(h)
+		if len(evts) != 1 || evts[0] != (clipboard.Event{Text: "pasted"}) {
+			t.Errorf("got events %v, want one clipboard event", evts)
+		}
+	}
+}
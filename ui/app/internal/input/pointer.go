@@ -5,11 +5,14 @@ package input
 import (
 	"encoding/binary"
 	"image"
+	"math"
 
 	"gioui.org/ui"
+	"gioui.org/ui/draw"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/input"
 	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/internal/path"
 	"gioui.org/ui/pointer"
 )
 
@@ -20,6 +23,16 @@ type pointerQueue struct {
 	pointers []pointerInfo
 	reader   ui.OpsReader
 	scratch  []input.Key
+	hits     []input.Key
+	clips    []clipPath
+	// pathData holds copies of the clip paths, for
+	// hit testing after the frame ops are reused.
+	pathData []byte
+	// mouse is the position of the most recent
+	// mouse event, if any.
+	mouse    f32.Point
+	hasMouse bool
+	cursor   pointer.Cursor
 }
 
 type hitNode struct {
@@ -30,12 +43,18 @@ type hitNode struct {
 
 	// For handler nodes.
 	key input.Key
+	// For cursor nodes.
+	cursor    pointer.Cursor
+	hasCursor bool
 }
 
 type pointerInfo struct {
 	id       pointer.ID
 	pressed  bool
 	handlers []input.Key
+	// entered tracks the handlers whose area
+	// contains the pointer.
+	entered []input.Key
 }
 
 type pointerHandler struct {
@@ -54,6 +73,21 @@ type areaNode struct {
 	trans ui.Transform
 	next  int
 	area  areaOp
+	// clip is the path of path areas.
+	clip clipPath
+}
+
+// clipPath is the path of a ClipOp.
+type clipPath struct {
+	trans  ui.Transform
+	bounds f32.Rectangle
+	rule   draw.FillRule
+	// verts are the path vertices, or nil
+	// for rectangular clips.
+	verts []byte
+	// copied is set when verts is a copy in
+	// pathData instead of the frame ops.
+	copied bool
 }
 
 type areaKind uint8
@@ -61,15 +95,34 @@ type areaKind uint8
 const (
 	areaRect areaKind = iota
 	areaEllipse
+	areaPath
 )
 
-func (q *pointerQueue) collectHandlers(r *ui.OpsReader, events *handlerEvents, t ui.Transform, area, node int, pass bool) {
+func (q *pointerQueue) collectHandlers(r *ui.OpsReader, events *handlerEvents, t ui.Transform, area, node, clip int, pass bool) {
+	var aux []byte
 	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
 		switch ops.OpType(encOp.Data[0]) {
 		case ops.TypePush:
-			q.collectHandlers(r, events, t, area, node, pass)
+			q.collectHandlers(r, events, t, area, node, clip, pass)
 		case ops.TypePop:
 			return
+		case ops.TypeAux:
+			aux = encOp.Data[ops.TypeAuxLen:]
+		case ops.TypeClip:
+			var op clipOp
+			op.Decode(encOp.Data)
+			c := clipPath{trans: t, bounds: op.bounds, rule: op.fillRule}
+			if len(aux) > 0 {
+				// The vertices are copied only if a
+				// path area refers to the clip.
+				c.verts = aux
+			}
+			q.clips = append(q.clips, c)
+			clip = len(q.clips) - 1
+			aux = nil
+		case ops.TypeLinearGradient, ops.TypeRadialGradient:
+			// Drop the aux data of the gradient stops.
+			aux = nil
 		case ops.TypePass:
 			var op pointer.PassOp
 			op.Decode(encOp.Data)
@@ -77,7 +130,24 @@ func (q *pointerQueue) collectHandlers(r *ui.OpsReader, events *handlerEvents, t
 		case ops.TypeArea:
 			var op areaOp
 			op.Decode(encOp.Data)
-			q.areas = append(q.areas, areaNode{trans: t, next: area, area: op})
+			n := areaNode{trans: t, next: area, area: op}
+			if op.kind == areaPath {
+				if clip != -1 {
+					c := &q.clips[clip]
+					if c.verts != nil && !c.copied {
+						start := len(q.pathData)
+						q.pathData = append(q.pathData, c.verts...)
+						c.verts = q.pathData[start:len(q.pathData):len(q.pathData)]
+						c.copied = true
+					}
+					n.clip = *c
+					n.trans = n.clip.trans
+				} else {
+					// Without a clip, the area is empty.
+					n.clip.trans = t
+				}
+			}
+			q.areas = append(q.areas, n)
 			area = len(q.areas) - 1
 			q.hitTree = append(q.hitTree, hitNode{
 				next: node,
@@ -89,6 +159,17 @@ func (q *pointerQueue) collectHandlers(r *ui.OpsReader, events *handlerEvents, t
 			var op ui.TransformOp
 			op.Decode(encOp.Data)
 			t = t.Mul(op.Transform)
+		case ops.TypeCursor:
+			var op pointer.CursorOp
+			op.Decode(encOp.Data)
+			q.hitTree = append(q.hitTree, hitNode{
+				next:      node,
+				area:      area,
+				pass:      pass,
+				cursor:    op.Cursor,
+				hasCursor: true,
+			})
+			node = len(q.hitTree) - 1
 		case ops.TypePointerHandler:
 			var op pointer.HandlerOp
 			op.Decode(encOp.Data, encOp.Refs)
@@ -138,6 +219,35 @@ func (q *pointerQueue) opHit(handlers *[]input.Key, pos f32.Point) {
 	}
 }
 
+// cursorAt returns the cursor of the topmost cursor
+// node at pos.
+func (q *pointerQueue) cursorAt(pos f32.Point) pointer.Cursor {
+	pass := true
+	idx := len(q.hitTree) - 1
+	for idx >= 0 {
+		n := &q.hitTree[idx]
+		if !q.hit(n.area, pos) {
+			idx--
+			continue
+		}
+		if n.hasCursor {
+			return n.cursor
+		}
+		pass = pass && n.pass
+		if pass {
+			idx--
+		} else {
+			idx = n.next
+		}
+	}
+	return pointer.CursorDefault
+}
+
+// Cursor returns the cursor under the mouse pointer.
+func (q *pointerQueue) Cursor() pointer.Cursor {
+	return q.cursor
+}
+
 func (q *pointerQueue) hit(areaIdx int, p f32.Point) bool {
 	for areaIdx != -1 {
 		a := &q.areas[areaIdx]
@@ -151,6 +261,9 @@ func (q *pointerQueue) hit(areaIdx int, p f32.Point) bool {
 
 func (a *areaNode) hit(p f32.Point) bool {
 	p = a.trans.InvTransform(p)
+	if a.area.kind == areaPath {
+		return a.clip.hit(p)
+	}
 	return a.area.Hit(p)
 }
 
@@ -168,14 +281,20 @@ func (q *pointerQueue) Frame(root *ui.Ops, events *handlerEvents) {
 	}
 	q.hitTree = q.hitTree[:0]
 	q.areas = q.areas[:0]
+	q.clips = q.clips[:0]
+	q.pathData = q.pathData[:0]
 	q.reader.Reset(root)
-	q.collectHandlers(&q.reader, events, ui.Transform{}, -1, -1, false)
+	q.collectHandlers(&q.reader, events, ui.Transform{}, -1, -1, -1, false)
 	for k, h := range q.handlers {
 		if !h.active {
 			q.dropHandler(k)
+			q.forgetHandler(k)
 			delete(q.handlers, k)
 		}
 	}
+	if q.hasMouse {
+		q.cursor = q.cursorAt(q.mouse)
+	}
 }
 
 func (q *pointerQueue) dropHandler(k input.Key) {
@@ -189,9 +308,25 @@ func (q *pointerQueue) dropHandler(k input.Key) {
 	}
 }
 
+// forgetHandler removes k from the entered handlers
+// of every pointer.
+func (q *pointerQueue) forgetHandler(k input.Key) {
+	for i := range q.pointers {
+		p := &q.pointers[i]
+		for i := len(p.entered) - 1; i >= 0; i-- {
+			if p.entered[i] == k {
+				p.entered = append(p.entered[:i], p.entered[i+1:]...)
+			}
+		}
+	}
+}
+
 func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 	q.init()
 	if e.Type == pointer.Cancel {
+		for i := range q.pointers {
+			q.deliverEnterLeave(&q.pointers[i], nil, e, events)
+		}
 		q.pointers = q.pointers[:0]
 		for k := range q.handlers {
 			q.dropHandler(k)
@@ -205,14 +340,35 @@ func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 			break
 		}
 	}
+	if e.Type == pointer.Leave {
+		// The pointer left the window.
+		if e.Source == pointer.Mouse {
+			q.hasMouse = false
+		}
+		if pidx == -1 {
+			return
+		}
+		p := &q.pointers[pidx]
+		q.deliverEnterLeave(p, nil, e, events)
+		if !p.pressed {
+			q.pointers = append(q.pointers[:pidx], q.pointers[pidx+1:]...)
+		}
+		return
+	}
 	if pidx == -1 {
 		q.pointers = append(q.pointers, pointerInfo{id: e.PointerID})
 		pidx = len(q.pointers) - 1
 	}
+	if e.Source == pointer.Mouse {
+		q.mouse, q.hasMouse = e.Position, true
+		q.cursor = q.cursorAt(e.Position)
+	}
 	p := &q.pointers[pidx]
+	q.hits = q.hits[:0]
+	q.opHit(&q.hits, e.Position)
+	q.deliverEnterLeave(p, q.hits, e, events)
 	if !p.pressed && (e.Type == pointer.Move || e.Type == pointer.Press) {
-		p.handlers, q.scratch = q.scratch[:0], p.handlers
-		q.opHit(&p.handlers, e.Position)
+		p.handlers = append(p.handlers[:0], q.hits...)
 		if e.Type == pointer.Press {
 			p.pressed = true
 		}
@@ -233,9 +389,6 @@ func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 			q.dropHandler(k)
 		}
 	}
-	if e.Type == pointer.Release {
-		q.pointers = append(q.pointers[:pidx], q.pointers[pidx+1:]...)
-	}
 	for i, k := range p.handlers {
 		h := q.handlers[k]
 		e := e
@@ -248,19 +401,66 @@ func (q *pointerQueue) Push(e pointer.Event, events *handlerEvents) {
 		e.Hit = q.hit(h.area, e.Position)
 		e.Position = h.transform.InvTransform(e.Position)
 		events.Add(k, e)
-		if e.Type == pointer.Release {
-			// Release grab when the number of grabs reaches zero.
-			grabs := 0
-			for _, p := range q.pointers {
-				if p.pressed && len(p.handlers) == 1 && p.handlers[0] == k {
-					grabs++
-				}
-			}
-			if grabs == 0 {
-				h.wantsGrab = false
+	}
+	// A mouse pointer stays pressed until all its
+	// buttons are released.
+	if e.Type != pointer.Release || e.Buttons != 0 {
+		return
+	}
+	p.pressed = false
+	for _, k := range p.handlers {
+		// Release grab when the number of grabs reaches zero.
+		grabs := 0
+		for _, p := range q.pointers {
+			if p.pressed && len(p.handlers) == 1 && p.handlers[0] == k {
+				grabs++
 			}
 		}
+		if grabs == 0 {
+			q.handlers[k].wantsGrab = false
+		}
+	}
+	if e.Source == pointer.Touch {
+		// A released touch no longer hovers.
+		q.deliverEnterLeave(p, nil, e, events)
+		q.pointers = append(q.pointers[:pidx], q.pointers[pidx+1:]...)
+	}
+}
+
+// deliverEnterLeave delivers Leave events to the handlers
+// p no longer hits and Enter events to the handlers in
+// hits not yet entered by p.
+func (q *pointerQueue) deliverEnterLeave(p *pointerInfo, hits []input.Key, e pointer.Event, events *handlerEvents) {
+	for _, k := range p.entered {
+		if !containsKey(hits, k) {
+			q.deliverEvent(k, pointer.Leave, e, events)
+		}
+	}
+	for _, k := range hits {
+		if !containsKey(p.entered, k) {
+			q.deliverEvent(k, pointer.Enter, e, events)
+		}
+	}
+	p.entered = append(p.entered[:0], hits...)
+}
+
+func (q *pointerQueue) deliverEvent(k input.Key, typ pointer.Type, e pointer.Event, events *handlerEvents) {
+	h := q.handlers[k]
+	e.Type = typ
+	e.Priority = pointer.Shared
+	e.Scroll = f32.Point{}
+	e.Hit = q.hit(h.area, e.Position)
+	e.Position = h.transform.InvTransform(e.Position)
+	events.Add(k, e)
+}
+
+func containsKey(keys []input.Key, k input.Key) bool {
+	for _, k2 := range keys {
+		if k2 == k {
+			return true
+		}
 	}
+	return false
 }
 
 func (op *areaOp) Decode(d []byte) {
@@ -303,3 +503,133 @@ func (op *areaOp) Hit(pos f32.Point) bool {
 		panic("invalid area kind")
 	}
 }
+
+// clipOp is the decoded form of draw.ClipOp.
+type clipOp struct {
+	bounds   f32.Rectangle
+	fillRule draw.FillRule
+}
+
+func (op *clipOp) Decode(d []byte) {
+	if ops.OpType(d[0]) != ops.TypeClip {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	*op = clipOp{
+		bounds: f32.Rectangle{
+			Min: f32.Point{
+				X: math.Float32frombits(bo.Uint32(d[1:])),
+				Y: math.Float32frombits(bo.Uint32(d[5:])),
+			},
+			Max: f32.Point{
+				X: math.Float32frombits(bo.Uint32(d[9:])),
+				Y: math.Float32frombits(bo.Uint32(d[13:])),
+			},
+		},
+		fillRule: draw.FillRule(d[17]),
+	}
+}
+
+// hit reports whether p is inside the clip path, using
+// the winding number of the path around p.
+func (c *clipPath) hit(p f32.Point) bool {
+	b := c.bounds
+	if p.X < b.Min.X || p.X >= b.Max.X || p.Y < b.Min.Y || p.Y >= b.Max.Y {
+		return false
+	}
+	if c.verts == nil {
+		return true
+	}
+	var w int
+	var start, pen f32.Point
+	first := true
+	verts := c.verts
+	for len(verts) >= 4*path.VertStride {
+		from, ctrl, to := path.DecodeCurve(verts)
+		verts = verts[4*path.VertStride:]
+		switch {
+		case first:
+			start = from
+			first = false
+		case from.X != pen.X:
+			// A new contour. Close the previous.
+			w += windingLine(p, pen, start)
+			start = from
+		case from.Y != pen.Y:
+			// A vertical curve discarded by the path
+			// builder.
+			w += windingLine(p, pen, from)
+		}
+		w += windingQuad(p, from, ctrl, to)
+		pen = to
+	}
+	if !first {
+		w += windingLine(p, pen, start)
+	}
+	if c.rule == draw.EvenOdd {
+		return w%2 != 0
+	}
+	return w != 0
+}
+
+// windingLine returns the number of times the line from
+// p0 to p1 crosses the ray from p in the positive X
+// direction, counting downward crossings as negative.
+func windingLine(p, p0, p1 f32.Point) int {
+	return windingQuad(p, p0, p0.Add(p1).Mul(.5), p1)
+}
+
+// windingQuad is like windingLine for the quadratic
+// bezier from p0 to p2 with control point p1.
+func windingQuad(p, p0, p1, p2 f32.Point) int {
+	// Split the curve into pieces monotonic in Y.
+	if a := p0.Y - 2*p1.Y + p2.Y; a != 0 {
+		if t := (p0.Y - p1.Y) / a; 0 < t && t < 1 {
+			c0 := lerp(p0, p1, t)
+			c1 := lerp(p1, p2, t)
+			mid := lerp(c0, c1, t)
+			return windingMonotoneQuad(p, p0, c0, mid) + windingMonotoneQuad(p, mid, c1, p2)
+		}
+	}
+	return windingMonotoneQuad(p, p0, p1, p2)
+}
+
+func windingMonotoneQuad(p, p0, p1, p2 f32.Point) int {
+	dir := 1
+	miny, maxy := p0.Y, p2.Y
+	if miny > maxy {
+		dir = -1
+		miny, maxy = maxy, miny
+	}
+	// Half-open intervals count shared end points once.
+	if p.Y < miny || p.Y >= maxy {
+		return 0
+	}
+	// Solve y(t) = p.Y for t, where
+	// y(t) = (1-t)²y0 + 2(1-t)t*y1 + t²y2.
+	y0, y1, y2 := float64(p0.Y), float64(p1.Y), float64(p2.Y)
+	a := y0 - 2*y1 + y2
+	b := 2 * (y1 - y0)
+	c := y0 - float64(p.Y)
+	var t float64
+	if math.Abs(a) < 1e-9 {
+		t = -c / b
+	} else {
+		d := math.Sqrt(math.Max(b*b-4*a*c, 0))
+		t = (-b + d) / (2 * a)
+		if t < 0 || t > 1 {
+			t = (-b - d) / (2 * a)
+		}
+	}
+	t = math.Max(0, math.Min(1, t))
+	x0, x1, x2 := float64(p0.X), float64(p1.X), float64(p2.X)
+	x := (1-t)*(1-t)*x0 + 2*(1-t)*t*x1 + t*t*x2
+	if x > float64(p.X) {
+		return dir
+	}
+	return 0
+}
+
+func lerp(p0, p1 f32.Point, t float32) f32.Point {
+	return p0.Mul(1 - t).Add(p1.Mul(t))
+}
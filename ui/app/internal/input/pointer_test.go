@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"image"
+	"math"
+	"reflect"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/input"
+	"gioui.org/ui/pointer"
+)
+
+func TestPointerEnterLeave(t *testing.T) {
+	h1, h2 := new(int), new(int)
+	ops := new(ui.Ops)
+	addHandler(ops, h1, f32.Point{})
+	addHandler(ops, h2, f32.Point{X: 20})
+	var r Router
+	r.Frame(ops)
+	// Drain the initial cancel events.
+	r.Events(h1)
+	r.Events(h2)
+
+	move := func(x float32) {
+		r.Add(pointer.Event{Type: pointer.Move, Position: f32.Point{X: x, Y: 5}})
+	}
+	move(5)
+	assertEventTypes(t, r.Events(h1), pointer.Enter, pointer.Move)
+	assertEventTypes(t, r.Events(h2))
+	move(25)
+	assertEventTypes(t, r.Events(h1), pointer.Leave)
+	assertEventTypes(t, r.Events(h2), pointer.Enter, pointer.Move)
+	// Pressed pointers keep delivering moves to the
+	// pressed handler.
+	r.Add(pointer.Event{Type: pointer.Press, Position: f32.Point{X: 25, Y: 5}, Buttons: pointer.ButtonPrimary})
+	move(5)
+	assertEventTypes(t, r.Events(h1), pointer.Enter)
+	assertEventTypes(t, r.Events(h2), pointer.Press, pointer.Leave, pointer.Move)
+	// Released touches leave.
+	r.Add(pointer.Event{Type: pointer.Press, Source: pointer.Touch, PointerID: 1, Position: f32.Point{X: 25, Y: 5}})
+	r.Add(pointer.Event{Type: pointer.Release, Source: pointer.Touch, PointerID: 1, Position: f32.Point{X: 25, Y: 5}})
+	assertEventTypes(t, r.Events(h2), pointer.Enter, pointer.Press, pointer.Release, pointer.Leave)
+}
+
+func TestPointerLeaveWindow(t *testing.T) {
+	h := new(int)
+	ops := new(ui.Ops)
+	addHandler(ops, h, f32.Point{})
+	var r Router
+	r.Frame(ops)
+	r.Events(h)
+
+	pos := f32.Point{X: 5, Y: 5}
+	r.Add(pointer.Event{Type: pointer.Move, Position: pos})
+	assertEventTypes(t, r.Events(h), pointer.Enter, pointer.Move)
+	// The mouse leaves the window.
+	r.Add(pointer.Event{Type: pointer.Leave, Position: pos})
+	assertEventTypes(t, r.Events(h), pointer.Leave)
+	r.Add(pointer.Event{Type: pointer.Move, Position: pos})
+	assertEventTypes(t, r.Events(h), pointer.Enter, pointer.Move)
+	// Cancelled pointers leave.
+	r.Add(pointer.Event{Type: pointer.Press, Source: pointer.Touch, PointerID: 1, Position: pos})
+	r.Events(h)
+	r.Add(pointer.Event{Type: pointer.Cancel})
+	assertEventTypes(t, r.Events(h), pointer.Leave, pointer.Leave)
+	r.Add(pointer.Event{Type: pointer.Move, Position: pos})
+	assertEventTypes(t, r.Events(h), pointer.Enter, pointer.Move)
+}
+
+func TestTransformedArea(t *testing.T) {
+	h := new(int)
+	ops := new(ui.Ops)
+	tr := ui.Offset(f32.Point{X: 50, Y: 50}).
+		Mul(ui.Rotate(math.Pi / 4)).
+		Mul(ui.Scale(f32.Point{X: 2, Y: 3}))
+	ui.TransformOp{Transform: tr}.Add(ops)
+	area := f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}
+	pointer.RectAreaOp{Size: image.Point{X: 10, Y: 10}}.Add(ops)
+	pointer.HandlerOp{Key: h}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	r.Events(h)
+	bounds, isRect := tr.TransformRect(area)
+	if isRect {
+		t.Fatalf("rotated area is a rectangle")
+	}
+	for _, tc := range []struct {
+		pos f32.Point
+		hit bool
+	}{
+		{tr.Transform(f32.Point{X: 5, Y: 5}), true},
+		{tr.Transform(f32.Point{X: 9, Y: 1}), true},
+		{tr.Transform(f32.Point{X: 11, Y: 5}), false},
+		{tr.Transform(f32.Point{X: 5, Y: -1}), false},
+		// Inside the bounds, outside the rotated area.
+		{bounds.Min.Add(f32.Point{X: 1, Y: 1}), false},
+		{bounds.Max.Sub(f32.Point{X: 1, Y: 1}), false},
+	} {
+		r.Add(pointer.Event{Type: pointer.Move, Position: tc.pos})
+		hit := false
+		for _, e := range r.Events(h) {
+			if e.(pointer.Event).Type != pointer.Leave {
+				hit = true
+			}
+		}
+		if hit != tc.hit {
+			t.Errorf("%v: got hit %v, expected %v", tc.pos, hit, tc.hit)
+		}
+	}
+}
+
+func addHandler(ops *ui.Ops, k input.Key, off f32.Point) {
+	var stack ui.StackOp
+	stack.Push(ops)
+	ui.TransformOp{Transform: ui.Offset(off)}.Add(ops)
+	pointer.RectAreaOp{Size: image.Point{X: 10, Y: 10}}.Add(ops)
+	pointer.HandlerOp{Key: k}.Add(ops)
+	stack.Pop()
+}
+
+func assertEventTypes(t *testing.T, events []input.Event, expected ...pointer.Type) {
+	t.Helper()
+	var got []pointer.Type
+	for _, e := range events {
+		got = append(got, e.(pointer.Event).Type)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v events, want %v", got, expected)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	ops := new(ui.Ops)
+	var stack ui.StackOp
+	stack.Push(ops)
+	pointer.RectAreaOp{Size: image.Point{X: 30, Y: 10}}.Add(ops)
+	pointer.CursorOp{Cursor: pointer.CursorText}.Add(ops)
+	stack.Pop()
+	// An area without a cursor hides the cursor below.
+	addHandler(ops, new(int), f32.Point{X: 20})
+	var r Router
+	r.Frame(ops)
+	if c := r.Cursor(); c != pointer.CursorDefault {
+		t.Errorf("got %v before the first mouse event, want %v", c, pointer.CursorDefault)
+	}
+	for _, tc := range []struct {
+		x      float32
+		cursor pointer.Cursor
+	}{
+		{5, pointer.CursorText},
+		{25, pointer.CursorDefault},
+		{40, pointer.CursorDefault},
+	} {
+		r.Add(pointer.Event{Type: pointer.Move, Source: pointer.Mouse, Position: f32.Point{X: tc.x, Y: 5}})
+		if c := r.Cursor(); c != tc.cursor {
+			t.Errorf("got %v at x=%v, want %v", c, tc.x, tc.cursor)
+		}
+	}
+}
+
+func TestPathArea(t *testing.T) {
+	square := func(b *draw.PathBuilder, min, size float32) {
+		b.Move(f32.Point{X: min, Y: min})
+		b.Line(f32.Point{X: size})
+		b.Line(f32.Point{Y: size})
+		b.Line(f32.Point{X: -size})
+		b.Line(f32.Point{Y: -size})
+	}
+	tests := []struct {
+		name  string
+		build func(b *draw.PathBuilder)
+		hits  []f32.Point
+		miss  []f32.Point
+	}{
+		{
+			name: "triangle",
+			build: func(b *draw.PathBuilder) {
+				b.Line(f32.Point{X: 20})
+				b.Line(f32.Point{X: -20, Y: 20})
+			},
+			hits: []f32.Point{{X: 2, Y: 2}, {X: 9, Y: 9}},
+			miss: []f32.Point{{X: 11, Y: 11}, {X: 15, Y: 15}, {X: -1, Y: 5}},
+		},
+		{
+			name: "quad",
+			build: func(b *draw.PathBuilder) {
+				b.Quad(f32.Point{X: 10, Y: 20}, f32.Point{X: 20})
+			},
+			hits: []f32.Point{{X: 10, Y: 5}, {X: 10, Y: 9.5}},
+			miss: []f32.Point{{X: 10, Y: 10.5}, {X: 2, Y: 5}},
+		},
+		{
+			name: "nonzero",
+			build: func(b *draw.PathBuilder) {
+				square(b, 0, 20)
+				square(b, 5, 10)
+			},
+			hits: []f32.Point{{X: 2, Y: 2}, {X: 10, Y: 10}},
+		},
+		{
+			name: "evenodd",
+			build: func(b *draw.PathBuilder) {
+				b.SetFillRule(draw.EvenOdd)
+				square(b, 0, 20)
+				square(b, 5, 10)
+			},
+			hits: []f32.Point{{X: 2, Y: 2}},
+			miss: []f32.Point{{X: 10, Y: 10}},
+		},
+	}
+	off := f32.Point{X: 100, Y: 50}
+	for _, test := range tests {
+		h := new(int)
+		ops := new(ui.Ops)
+		var stack ui.StackOp
+		stack.Push(ops)
+		ui.TransformOp{Transform: ui.Offset(off)}.Add(ops)
+		var b draw.PathBuilder
+		b.Init(ops)
+		test.build(&b)
+		b.End()
+		pointer.PathAreaOp{}.Add(ops)
+		pointer.HandlerOp{Key: h}.Add(ops)
+		stack.Pop()
+		var r Router
+		r.Frame(ops)
+		r.Events(h)
+		// Hit testing must not depend on the reused ops.
+		ops.Reset()
+		var b2 draw.PathBuilder
+		b2.Init(ops)
+		square(&b2, -1000, 1)
+		b2.End()
+		for _, p := range test.hits {
+			r.Add(pointer.Event{Type: pointer.Move, Position: p.Add(off)})
+			if len(r.Events(h)) == 0 {
+				t.Errorf("%s: %v not hit", test.name, p)
+			}
+		}
+		for _, p := range test.miss {
+			r.Add(pointer.Event{Type: pointer.Move, Position: p.Add(off)})
+			for _, e := range r.Events(h) {
+				if e.(pointer.Event).Type != pointer.Leave {
+					t.Errorf("%s: %v hit", test.name, p)
+				}
+			}
+		}
+	}
+}
+
+func TestPathAreaCopies(t *testing.T) {
+	ops := new(ui.Ops)
+	var b draw.PathBuilder
+	b.Init(ops)
+	b.Line(f32.Point{X: 20})
+	b.Line(f32.Point{X: -20, Y: 20})
+	b.End()
+	pointer.RectAreaOp{Size: image.Point{X: 10, Y: 10}}.Add(ops)
+	pointer.HandlerOp{Key: new(int)}.Add(ops)
+	var r Router
+	r.Frame(ops)
+	if n := len(r.pqueue.pathData); n != 0 {
+		t.Errorf("copied %d bytes of clip paths without path areas", n)
+	}
+	pointer.PathAreaOp{}.Add(ops)
+	pointer.PathAreaOp{}.Add(ops)
+	r.Frame(ops)
+	if n, exp := len(r.pqueue.pathData), len(r.pqueue.clips[0].verts); n != exp {
+		t.Errorf("copied %d bytes of clip paths, expected %d", n, exp)
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"time"
 
 	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/input"
 	"gioui.org/ui/internal/ops"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
+	"gioui.org/ui/semantic"
 	"gioui.org/ui/system"
 )
 
@@ -18,6 +20,8 @@ import (
 type Router struct {
 	pqueue pointerQueue
 	kqueue keyQueue
+	squeue semanticQueue
+	cqueue clipboardQueue
 
 	handlers handlerEvents
 
@@ -49,6 +53,7 @@ func (q *Router) Frame(ops *ui.Ops) {
 
 	q.pqueue.Frame(ops, &q.handlers)
 	q.kqueue.Frame(ops, &q.handlers)
+	q.squeue.Frame(ops)
 	if q.handlers.Updated() {
 		q.wakeup = true
 		q.wakeupTime = time.Time{}
@@ -61,10 +66,36 @@ func (q *Router) Add(e input.Event) bool {
 		q.pqueue.Push(e, &q.handlers)
 	case key.EditEvent, key.ChordEvent, key.FocusEvent:
 		q.kqueue.Push(e, &q.handlers)
+	case clipboard.Event:
+		q.cqueue.Push(e, &q.handlers)
 	}
 	return q.handlers.Updated()
 }
 
+// Semantics returns the semantic tree of the most
+// recent frame.
+func (q *Router) Semantics() *semantic.Node {
+	return q.squeue.Root()
+}
+
+// WriteClipboard returns the text to write to the
+// clipboard, if the most recent frame wrote any.
+func (q *Router) WriteClipboard() (string, bool) {
+	return q.cqueue.WriteClipboard()
+}
+
+// ReadClipboard reports whether the clipboard must be
+// read and delivered as a clipboard.Event.
+func (q *Router) ReadClipboard() bool {
+	return q.cqueue.ReadClipboard()
+}
+
+// Cursor returns the mouse cursor shape under the
+// mouse pointer.
+func (q *Router) Cursor() pointer.Cursor {
+	return q.pqueue.Cursor()
+}
+
 func (q *Router) TextInputState() TextInputState {
 	return q.kqueue.InputState()
 }
@@ -83,6 +114,10 @@ func (q *Router) collect() {
 			var op system.ProfileOp
 			op.Decode(encOp.Data, encOp.Refs)
 			q.profHandlers = append(q.profHandlers, op.Key)
+		case ops.TypeClipboardRead:
+			q.cqueue.ProcessReadClipboard(encOp.Data, encOp.Refs)
+		case ops.TypeClipboardWrite:
+			q.cqueue.ProcessWriteClipboard(encOp.Data, encOp.Refs)
 		}
 	}
 }
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/semantic"
+)
+
+type semanticQueue struct {
+	reader ui.OpsReader
+	root   *semantic.Node
+}
+
+// Frame builds the semantic tree of root.
+func (q *semanticQueue) Frame(root *ui.Ops) {
+	q.reader.Reset(root)
+	q.root = new(semantic.Node)
+	q.collect(&q.reader, ui.Transform{}, q.root)
+}
+
+// Root returns the root of the semantic tree of the
+// most recent frame, or nil before the first frame. The
+// root node has no description and holds the nodes of
+// the frame as its children.
+func (q *semanticQueue) Root() *semantic.Node {
+	return q.root
+}
+
+func (q *semanticQueue) collect(r *ui.OpsReader, t ui.Transform, parent *semantic.Node) {
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		switch ops.OpType(encOp.Data[0]) {
+		case ops.TypePush:
+			q.collect(r, t, parent)
+		case ops.TypePop:
+			return
+		case ops.TypeTransform:
+			var op ui.TransformOp
+			op.Decode(encOp.Data)
+			t = t.Mul(op.Transform)
+		case ops.TypeSemantic:
+			var op semantic.NodeOp
+			op.Decode(encOp.Data, encOp.Refs)
+			n := &semantic.Node{
+				NodeOp: op,
+				Bounds: transformBounds(t, op.Size),
+			}
+			parent.Children = append(parent.Children, n)
+			// The node covers the rest of the stack.
+			parent = n
+		}
+	}
+}
+
+// transformBounds returns the integer bounds of the
+// rectangle from the origin to sz transformed by t.
+func transformBounds(t ui.Transform, sz image.Point) image.Rectangle {
+	corners := [...]f32.Point{
+		{},
+		{X: float32(sz.X)},
+		{Y: float32(sz.Y)},
+		{X: float32(sz.X), Y: float32(sz.Y)},
+	}
+	min := f32.Point{X: math.MaxFloat32, Y: math.MaxFloat32}
+	max := f32.Point{X: -math.MaxFloat32, Y: -math.MaxFloat32}
+	for _, c := range corners {
+		p := t.Transform(c)
+		min.X = float32(math.Min(float64(min.X), float64(p.X)))
+		min.Y = float32(math.Min(float64(min.Y), float64(p.Y)))
+		max.X = float32(math.Max(float64(max.X), float64(p.X)))
+		max.Y = float32(math.Max(float64(max.Y), float64(p.Y)))
+	}
+	return image.Rectangle{
+		Min: image.Point{X: int(math.Floor(float64(min.X))), Y: int(math.Floor(float64(min.Y)))},
+		Max: image.Point{X: int(math.Ceil(float64(max.X))), Y: int(math.Ceil(float64(max.Y)))},
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package input
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/semantic"
+)
+
+func TestSemanticTree(t *testing.T) {
+	var ops ui.Ops
+	var list ui.StackOp
+	list.Push(&ops)
+	ui.TransformOp{Transform: ui.Offset(f32.Point{X: 10, Y: 20})}.Add(&ops)
+	semantic.NodeOp{Size: image.Pt(100, 50), Role: semantic.List}.Add(&ops)
+	for i, label := range []string{"OK", "Cancel"} {
+		var item ui.StackOp
+		item.Push(&ops)
+		ui.TransformOp{Transform: ui.Offset(f32.Point{Y: float32(i * 25)})}.Add(&ops)
+		semantic.NodeOp{
+			Size:     image.Pt(100, 25),
+			Role:     semantic.Button,
+			Label:    label,
+			Disabled: i == 1,
+			Actions:  semantic.ActionClick,
+		}.Add(&ops)
+		item.Pop()
+	}
+	list.Pop()
+	semantic.NodeOp{Size: image.Pt(5, 5), Role: semantic.Label, Label: "Status"}.Add(&ops)
+
+	var q semanticQueue
+	q.Frame(&ops)
+	const want = `Unknown "" (0,0)-(0,0)
+	List "" (10,20)-(110,70)
+		Button "OK" (10,20)-(110,45) actions=Click
+		Button "Cancel" (10,45)-(110,70) disabled actions=Click
+	Label "Status" (0,0)-(5,5)
+`
+	if got := q.Root().String(); got != want {
+		t.Errorf("got tree\n%s\nexpected\n%s", got, want)
+	}
+	if n := q.Root().Find(semantic.Button, "Cancel"); n == nil || !n.Disabled {
+		t.Errorf("Find: got %v", n)
+	}
+}
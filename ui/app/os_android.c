@@ -72,7 +72,7 @@ JNIEXPORT jint JNI_OnLoad(JavaVM *vm, void *reserved) {
 		},
 		{
 			.name = "onTouchEvent",
-			.signature = "(JIIIFFJ)V",
+			.signature = "(JIIIFFJII)V",
 			.fnPtr = onTouchEvent
 		},
 		{
@@ -94,6 +94,11 @@ JNIEXPORT jint JNI_OnLoad(JavaVM *vm, void *reserved) {
 			.name = "onFocusChange",
 			.signature = "(JZ)V",
 			.fnPtr = onFocusChange
+		},
+		{
+			.name = "onClipboard",
+			.signature = "(J[B)V",
+			.fnPtr = onClipboard
 		}
 	};
 	if ((*env)->RegisterNatives(env, viewClass, methods, sizeof(methods)/sizeof(methods[0])) != 0) {
@@ -151,10 +156,30 @@ void gio_jni_CallVoidMethod(JNIEnv *env, jobject obj, jmethodID methodID) {
 	(*env)->CallVoidMethod(env, obj, methodID);
 }
 
+void gio_jni_CallVoidMethod_I(JNIEnv *env, jobject obj, jmethodID methodID, jint a1) {
+	(*env)->CallVoidMethod(env, obj, methodID, a1);
+}
+
 void gio_jni_CallVoidMethod_J(JNIEnv *env, jobject obj, jmethodID methodID, jlong a1) {
 	(*env)->CallVoidMethod(env, obj, methodID, a1);
 }
 
+void gio_jni_CallVoidMethod_L(JNIEnv *env, jobject obj, jmethodID methodID, jobject a1) {
+	(*env)->CallVoidMethod(env, obj, methodID, a1);
+}
+
+jbyteArray gio_jni_NewByteArray(JNIEnv *env, jsize length) {
+	return (*env)->NewByteArray(env, length);
+}
+
+void gio_jni_SetByteArrayRegion(JNIEnv *env, jbyteArray arr, jsize start, jsize len, const jbyte *buf) {
+	(*env)->SetByteArrayRegion(env, arr, start, len, buf);
+}
+
+void gio_jni_DeleteLocalRef(JNIEnv *env, jobject obj) {
+	(*env)->DeleteLocalRef(env, obj);
+}
+
 jbyte *gio_jni_GetByteArrayElements(JNIEnv *env, jbyteArray arr) {
 	return (*env)->GetByteArrayElements(env, arr, NULL);
 }
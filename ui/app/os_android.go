@@ -25,6 +25,7 @@ import (
 	"unsafe"
 
 	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -50,6 +51,9 @@ type window struct {
 	mgetFontScale                  C.jmethodID
 	mshowTextInput                 C.jmethodID
 	mhideTextInput                 C.jmethodID
+	mreadClipboard                 C.jmethodID
+	mwriteClipboard                C.jmethodID
+	msetCursor                     C.jmethodID
 	mpostFrameCallback             C.jmethodID
 	mpostFrameCallbackOnMainThread C.jmethodID
 }
@@ -103,6 +107,9 @@ func onCreateView(env *C.JNIEnv, class C.jclass, view C.jobject) C.jlong {
 		mgetFontScale:                  jniGetMethodID(env, class, "getFontScale", "()F"),
 		mshowTextInput:                 jniGetMethodID(env, class, "showTextInput", "()V"),
 		mhideTextInput:                 jniGetMethodID(env, class, "hideTextInput", "()V"),
+		mreadClipboard:                 jniGetMethodID(env, class, "readClipboard", "()V"),
+		mwriteClipboard:                jniGetMethodID(env, class, "writeClipboard", "([B)V"),
+		msetCursor:                     jniGetMethodID(env, class, "setCursor", "(I)V"),
 		mpostFrameCallback:             jniGetMethodID(env, class, "postFrameCallback", "()V"),
 		mpostFrameCallbackOnMainThread: jniGetMethodID(env, class, "postFrameCallbackOnMainThread", "()V"),
 	}
@@ -373,7 +380,7 @@ func onKeyEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, keyCode, r C.jint
 }
 
 //export onTouchEvent
-func onTouchEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, action, pointerID, tool C.jint, x, y C.jfloat, t C.jlong) {
+func onTouchEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, action, pointerID, tool C.jint, x, y C.jfloat, t C.jlong, buttons, meta C.jint) {
 	w := views[handle]
 	var typ pointer.Type
 	switch action {
@@ -389,20 +396,45 @@ func onTouchEvent(env *C.JNIEnv, class C.jclass, handle C.jlong, action, pointer
 		return
 	}
 	var src pointer.Source
+	var btns pointer.Buttons
 	switch tool {
 	case C.AMOTION_EVENT_TOOL_TYPE_FINGER:
 		src = pointer.Touch
 	case C.AMOTION_EVENT_TOOL_TYPE_MOUSE:
 		src = pointer.Mouse
+		if buttons&C.AMOTION_EVENT_BUTTON_PRIMARY != 0 {
+			btns |= pointer.ButtonPrimary
+		}
+		if buttons&C.AMOTION_EVENT_BUTTON_SECONDARY != 0 {
+			btns |= pointer.ButtonSecondary
+		}
+		if buttons&C.AMOTION_EVENT_BUTTON_TERTIARY != 0 {
+			btns |= pointer.ButtonTertiary
+		}
+		if buttons&C.AMOTION_EVENT_BUTTON_BACK != 0 {
+			btns |= pointer.ButtonBack
+		}
+		if buttons&C.AMOTION_EVENT_BUTTON_FORWARD != 0 {
+			btns |= pointer.ButtonForward
+		}
 	default:
 		return
 	}
+	var mods key.Modifiers
+	if meta&C.AMETA_CTRL_ON != 0 {
+		mods |= key.ModCommand
+	}
+	if meta&C.AMETA_SHIFT_ON != 0 {
+		mods |= key.ModShift
+	}
 	w.event(pointer.Event{
 		Type:      typ,
 		Source:    src,
 		PointerID: pointer.ID(pointerID),
 		Time:      time.Duration(t) * time.Millisecond,
 		Position:  f32.Point{X: float32(x), Y: float32(y)},
+		Buttons:   btns,
+		Modifiers: mods,
 	})
 }
 
@@ -419,6 +451,89 @@ func (w *window) showTextInput(show bool) {
 	})
 }
 
+func (w *window) readClipboard() {
+	if w.view == 0 {
+		return
+	}
+	runInJVM(func(env *C.JNIEnv) {
+		C.gio_jni_CallVoidMethod(env, w.view, w.mreadClipboard)
+	})
+}
+
+func (w *window) writeClipboard(s string) {
+	if w.view == 0 {
+		return
+	}
+	runInJVM(func(env *C.JNIEnv) {
+		text := C.gio_jni_NewByteArray(env, C.jsize(len(s)))
+		if len(s) > 0 {
+			b := []byte(s)
+			C.gio_jni_SetByteArrayRegion(env, text, 0, C.jsize(len(b)), (*C.jbyte)(unsafe.Pointer(&b[0])))
+		}
+		C.gio_jni_CallVoidMethod_L(env, w.view, w.mwriteClipboard, C.jobject(text))
+		C.gio_jni_DeleteLocalRef(env, C.jobject(text))
+	})
+}
+
+func (w *window) setCursor(c pointer.Cursor) {
+	if w.view == 0 {
+		return
+	}
+	// The android.view.PointerIcon types.
+	const (
+		TYPE_NULL                    = 0
+		TYPE_ARROW                   = 1000
+		TYPE_HAND                    = 1002
+		TYPE_CROSSHAIR               = 1007
+		TYPE_TEXT                    = 1008
+		TYPE_NO_DROP                 = 1012
+		TYPE_HORIZONTAL_DOUBLE_ARROW = 1014
+		TYPE_VERTICAL_DOUBLE_ARROW   = 1015
+		TYPE_GRAB                    = 1020
+	)
+	var typ C.jint
+	switch c {
+	case pointer.CursorText:
+		typ = TYPE_TEXT
+	case pointer.CursorPointer:
+		typ = TYPE_HAND
+	case pointer.CursorCrossHair:
+		typ = TYPE_CROSSHAIR
+	case pointer.CursorColResize:
+		typ = TYPE_HORIZONTAL_DOUBLE_ARROW
+	case pointer.CursorRowResize:
+		typ = TYPE_VERTICAL_DOUBLE_ARROW
+	case pointer.CursorGrab:
+		typ = TYPE_GRAB
+	case pointer.CursorNotAllowed:
+		typ = TYPE_NO_DROP
+	case pointer.CursorNone:
+		typ = TYPE_NULL
+	default:
+		typ = TYPE_ARROW
+	}
+	runInJVM(func(env *C.JNIEnv) {
+		C.gio_jni_CallVoidMethod_I(env, w.view, w.msetCursor, typ)
+	})
+}
+
+func (w *window) submit(frame *ui.Ops) {}
+
+//export onClipboard
+func onClipboard(env *C.JNIEnv, class C.jclass, handle C.jlong, jtext C.jbyteArray) {
+	w := views[handle]
+	if w == nil {
+		return
+	}
+	var text string
+	if n := C.gio_jni_GetArrayLength(env, jtext); n > 0 {
+		b := C.gio_jni_GetByteArrayElements(env, jtext)
+		text = C.GoStringN((*C.char)(unsafe.Pointer(b)), n)
+		C.gio_jni_ReleaseByteArrayElements(env, jtext, b)
+	}
+	w.event(clipboard.Event{Text: text})
+}
+
 func Main() {
 }
 
@@ -13,7 +13,12 @@ __attribute__ ((visibility ("hidden"))) jint gio_jni_CallStaticIntMethodII(JNIEn
 __attribute__ ((visibility ("hidden"))) jfloat gio_jni_CallFloatMethod(JNIEnv *env, jobject obj, jmethodID methodID);
 __attribute__ ((visibility ("hidden"))) jint gio_jni_CallIntMethod(JNIEnv *env, jobject obj, jmethodID methodID);
 __attribute__ ((visibility ("hidden"))) void gio_jni_CallVoidMethod(JNIEnv *env, jobject obj, jmethodID methodID);
+__attribute__ ((visibility ("hidden"))) void gio_jni_CallVoidMethod_I(JNIEnv *env, jobject obj, jmethodID methodID, jint a1);
 __attribute__ ((visibility ("hidden"))) void gio_jni_CallVoidMethod_J(JNIEnv *env, jobject obj, jmethodID methodID, jlong a1);
+__attribute__ ((visibility ("hidden"))) void gio_jni_CallVoidMethod_L(JNIEnv *env, jobject obj, jmethodID methodID, jobject a1);
+__attribute__ ((visibility ("hidden"))) jbyteArray gio_jni_NewByteArray(JNIEnv *env, jsize length);
+__attribute__ ((visibility ("hidden"))) void gio_jni_SetByteArrayRegion(JNIEnv *env, jbyteArray arr, jsize start, jsize len, const jbyte *buf);
+__attribute__ ((visibility ("hidden"))) void gio_jni_DeleteLocalRef(JNIEnv *env, jobject obj);
 __attribute__ ((visibility ("hidden"))) jbyte *gio_jni_GetByteArrayElements(JNIEnv *env, jbyteArray arr);
 __attribute__ ((visibility ("hidden"))) void gio_jni_ReleaseByteArrayElements(JNIEnv *env, jbyteArray arr, jbyte *bytes);
 __attribute__ ((visibility ("hidden"))) jsize gio_jni_GetArrayLength(JNIEnv *env, jbyteArray arr);
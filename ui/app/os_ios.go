@@ -10,6 +10,7 @@ package app
 #include <CoreGraphics/CoreGraphics.h>
 #include <UIKit/UIKit.h>
 #include <stdint.h>
+#include <stdlib.h>
 #include "os_ios.h"
 
 */
@@ -21,8 +22,10 @@ import (
 	"runtime/debug"
 	"sync/atomic"
 	"time"
+	"unsafe"
 
 	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -246,6 +249,31 @@ func (w *window) showTextInput(show bool) {
 	}
 }
 
+func (w *window) readClipboard() {
+	if w.view == 0 {
+		return
+	}
+	C.gio_readClipboard(w.view)
+}
+
+func (w *window) writeClipboard(s string) {
+	cstr := C.CString(s)
+	defer C.free(unsafe.Pointer(cstr))
+	C.gio_writeClipboard(cstr)
+}
+
+// setCursor is a no-op, because iOS has no mouse cursor.
+func (w *window) setCursor(c pointer.Cursor) {}
+
+func (w *window) submit(frame *ui.Ops) {}
+
+//export onClipboard
+func onClipboard(view C.CFTypeRef, text *C.char) {
+	if w, exists := views[view]; exists {
+		w.w.event(clipboard.Event{Text: C.GoString(text)})
+	}
+}
+
 func createWindow(win *Window, opts *WindowOptions) error {
 	mainWindow.in <- windowAndOptions{win, opts}
 	return <-mainWindow.errs
@@ -6,3 +6,5 @@ __attribute__ ((visibility ("hidden"))) void gio_addLayerToView(CFTypeRef viewRe
 __attribute__ ((visibility ("hidden"))) void gio_updateView(CFTypeRef viewRef, CFTypeRef layerRef);
 __attribute__ ((visibility ("hidden"))) void gio_removeLayer(CFTypeRef layerRef);
 __attribute__ ((visibility ("hidden"))) void gio_setAnimating(CFTypeRef viewRef, int anim);
+__attribute__ ((visibility ("hidden"))) void gio_readClipboard(CFTypeRef viewRef);
+__attribute__ ((visibility ("hidden"))) void gio_writeClipboard(const char *text);
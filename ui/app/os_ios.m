@@ -247,6 +247,23 @@ void gio_hideTextInput(CFTypeRef viewRef) {
 	});
 }
 
+void gio_readClipboard(CFTypeRef viewRef) {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		NSString *text = [UIPasteboard generalPasteboard].string;
+		if (text == nil) {
+			text = @"";
+		}
+		onClipboard(viewRef, (char *)[text UTF8String]);
+	});
+}
+
+void gio_writeClipboard(const char *text) {
+	NSString *s = [NSString stringWithUTF8String:text];
+	dispatch_async(dispatch_get_main_queue(), ^{
+		[UIPasteboard generalPasteboard].string = s;
+	});
+}
+
 void gio_addLayerToView(CFTypeRef viewRef, CFTypeRef layerRef) {
 	UIView *view = (__bridge UIView *)viewRef;
 	CALayer *layer = (__bridge CALayer *)layerRef;
@@ -7,6 +7,8 @@ import (
 	"syscall/js"
 	"time"
 
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -115,6 +117,16 @@ func (w *window) addEventListeners() {
 		w.pointerEvent(pointer.Release, 0, 0, args[0])
 		return nil
 	})
+	w.addEventListener(w.cnv, "mouseleave", func(this js.Value, args []js.Value) interface{} {
+		w.pointerEvent(pointer.Leave, 0, 0, args[0])
+		return nil
+	})
+	w.addEventListener(w.cnv, "contextmenu", func(this js.Value, args []js.Value) interface{} {
+		// Deliver secondary button presses to the program
+		// instead of opening the browser menu.
+		args[0].Call("preventDefault")
+		return nil
+	})
 	w.addEventListener(w.cnv, "wheel", func(this js.Value, args []js.Value) interface{} {
 		e := args[0]
 		dx, dy := e.Get("deltaX").Float(), e.Get("deltaY").Float()
@@ -201,17 +213,24 @@ func (w *window) focus() {
 func (w *window) keyEvent(e js.Value) {
 	k := e.Get("key").String()
 	if n, ok := translateKey(k); ok {
-		cmd := key.ChordEvent{Name: n}
-		if e.Call("getModifierState", "Control").Bool() {
-			cmd.Modifiers |= key.ModCommand
-		}
-		if e.Call("getModifierState", "Shift").Bool() {
-			cmd.Modifiers |= key.ModShift
-		}
+		cmd := key.ChordEvent{Name: n, Modifiers: modifiersFor(e)}
 		w.w.event(cmd)
 	}
 }
 
+// modifiersFor returns the modifiers of the keyboard or mouse
+// event e.
+func modifiersFor(e js.Value) key.Modifiers {
+	var mods key.Modifiers
+	if e.Call("getModifierState", "Control").Bool() {
+		mods |= key.ModCommand
+	}
+	if e.Call("getModifierState", "Shift").Bool() {
+		mods |= key.ModShift
+	}
+	return mods
+}
+
 func (w *window) touchEvent(typ pointer.Type, e js.Value) {
 	e.Call("preventDefault")
 	t := time.Duration(e.Get("timeStamp").Int()) * time.Millisecond
@@ -271,12 +290,17 @@ func (w *window) pointerEvent(typ pointer.Type, dx, dy float32, e js.Value) {
 		Y: dy * scale,
 	}
 	t := time.Duration(e.Get("timeStamp").Int()) * time.Millisecond
+	// The bits of MouseEvent.buttons are in the order
+	// of the pointer.Buttons constants.
+	btns := pointer.Buttons(e.Get("buttons").Int() & 0x1f)
 	w.w.event(pointer.Event{
-		Type:     typ,
-		Source:   pointer.Mouse,
-		Position: pos,
-		Scroll:   scroll,
-		Time:     t,
+		Type:      typ,
+		Source:    pointer.Mouse,
+		Position:  pos,
+		Scroll:    scroll,
+		Buttons:   btns,
+		Modifiers: modifiersFor(e),
+		Time:      t,
 	})
 }
 
@@ -325,6 +349,73 @@ func (w *window) showTextInput(show bool) {
 	}
 }
 
+func (w *window) readClipboard() {
+	cb := js.Global().Get("navigator").Get("clipboard")
+	if cb == js.Undefined() {
+		// Deliver the event later from the event loop,
+		// because the window waits for readClipboard
+		// to return.
+		var f js.Func
+		f = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			f.Release()
+			w.w.event(clipboard.Event{})
+			return nil
+		})
+		w.window.Call("setTimeout", f, 0)
+		return
+	}
+	var then, catch js.Func
+	deliver := func(text string) {
+		then.Release()
+		catch.Release()
+		w.w.event(clipboard.Event{Text: text})
+	}
+	then = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		deliver(args[0].String())
+		return nil
+	})
+	catch = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		deliver("")
+		return nil
+	})
+	cb.Call("readText").Call("then", then, catch)
+}
+
+func (w *window) writeClipboard(s string) {
+	cb := js.Global().Get("navigator").Get("clipboard")
+	if cb == js.Undefined() {
+		return
+	}
+	cb.Call("writeText", s)
+}
+
+func (w *window) setCursor(c pointer.Cursor) {
+	var style string
+	switch c {
+	case pointer.CursorText:
+		style = "text"
+	case pointer.CursorPointer:
+		style = "pointer"
+	case pointer.CursorCrossHair:
+		style = "crosshair"
+	case pointer.CursorColResize:
+		style = "col-resize"
+	case pointer.CursorRowResize:
+		style = "row-resize"
+	case pointer.CursorGrab:
+		style = "grab"
+	case pointer.CursorNotAllowed:
+		style = "not-allowed"
+	case pointer.CursorNone:
+		style = "none"
+	default:
+		style = "default"
+	}
+	w.cnv.Get("style").Set("cursor", style)
+}
+
+func (w *window) submit(frame *ui.Ops) {}
+
 func (w *window) draw(sync bool) {
 	width, height, scale, cfg := w.config()
 	if cfg == (Config{}) {
@@ -7,6 +7,7 @@ package app
 /*
 #cgo CFLAGS: -DGL_SILENCE_DEPRECATION -Werror -fmodules -fobjc-arc -x objective-c
 
+#include <stdlib.h>
 #include <AppKit/AppKit.h>
 #include "os_macos.h"
 */
@@ -19,6 +20,8 @@ import (
 	"time"
 	"unsafe"
 
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -81,6 +84,54 @@ func (w *window) contextView() C.CFTypeRef {
 
 func (w *window) showTextInput(show bool) {}
 
+func (w *window) readClipboard() {
+	go func() {
+		var text string
+		if cstr := C.gio_readClipboard(); cstr != nil {
+			text = C.GoString(cstr)
+			C.free(unsafe.Pointer(cstr))
+		}
+		viewDo(w.view, func(views viewMap, view C.CFTypeRef) {
+			if w, exists := views[view]; exists {
+				w.w.event(clipboard.Event{Text: text})
+			}
+		})
+	}()
+}
+
+func (w *window) writeClipboard(s string) {
+	cstr := C.CString(s)
+	defer C.free(unsafe.Pointer(cstr))
+	C.gio_writeClipboard(cstr)
+}
+
+func (w *window) setCursor(c pointer.Cursor) {
+	var curID C.int
+	switch c {
+	case pointer.CursorText:
+		curID = C.GIO_CURSOR_TEXT
+	case pointer.CursorPointer:
+		curID = C.GIO_CURSOR_POINTER
+	case pointer.CursorCrossHair:
+		curID = C.GIO_CURSOR_CROSSHAIR
+	case pointer.CursorColResize:
+		curID = C.GIO_CURSOR_COLRESIZE
+	case pointer.CursorRowResize:
+		curID = C.GIO_CURSOR_ROWRESIZE
+	case pointer.CursorGrab:
+		curID = C.GIO_CURSOR_GRAB
+	case pointer.CursorNotAllowed:
+		curID = C.GIO_CURSOR_NOTALLOWED
+	case pointer.CursorNone:
+		curID = C.GIO_CURSOR_NONE
+	default:
+		curID = C.GIO_CURSOR_DEFAULT
+	}
+	C.gio_setCursor(curID)
+}
+
+func (w *window) submit(frame *ui.Ops) {}
+
 func (w *window) setAnimating(anim bool) {
 	var animb C.BOOL
 	if anim {
@@ -116,13 +167,7 @@ func gio_onFrameCallback(view C.CFTypeRef) {
 //export gio_onKeys
 func gio_onKeys(view C.CFTypeRef, cstr *C.char, ti C.double, mods C.NSUInteger) {
 	str := C.GoString(cstr)
-	var kmods key.Modifiers
-	if mods&C.NSEventModifierFlagCommand != 0 {
-		kmods |= key.ModCommand
-	}
-	if mods&C.NSEventModifierFlagShift != 0 {
-		kmods |= key.ModShift
-	}
+	kmods := convertMods(mods)
 	viewDo(view, func(views viewMap, view C.CFTypeRef) {
 		w := views[view]
 		for _, k := range str {
@@ -143,7 +188,7 @@ func gio_onText(view C.CFTypeRef, cstr *C.char) {
 }
 
 //export gio_onMouse
-func gio_onMouse(view C.CFTypeRef, cdir C.int, x, y, dx, dy C.CGFloat, ti C.double) {
+func gio_onMouse(view C.CFTypeRef, cdir C.int, cbtns C.NSUInteger, x, y, dx, dy C.CGFloat, ti C.double, mods C.NSUInteger) {
 	var typ pointer.Type
 	switch cdir {
 	case C.GIO_MOUSE_MOVE:
@@ -152,24 +197,54 @@ func gio_onMouse(view C.CFTypeRef, cdir C.int, x, y, dx, dy C.CGFloat, ti C.doub
 		typ = pointer.Release
 	case C.GIO_MOUSE_DOWN:
 		typ = pointer.Press
+	case C.GIO_MOUSE_LEAVE:
+		typ = pointer.Leave
 	default:
 		panic("invalid direction")
 	}
+	var btns pointer.Buttons
+	// The bits of NSEvent.pressedMouseButtons are in
+	// the order of the pointer.Buttons constants.
+	for i, b := range []pointer.Buttons{
+		pointer.ButtonPrimary,
+		pointer.ButtonSecondary,
+		pointer.ButtonTertiary,
+		pointer.ButtonBack,
+		pointer.ButtonForward,
+	} {
+		if cbtns&(1<<uint(i)) != 0 {
+			btns |= b
+		}
+	}
+	kmods := convertMods(mods)
 	t := time.Duration(float64(ti)*float64(time.Second) + .5)
 	viewDo(view, func(views viewMap, view C.CFTypeRef) {
 		w := views[view]
 		x, y := float32(x)*w.scale, float32(y)*w.scale
 		dx, dy := float32(dx)*w.scale, float32(dy)*w.scale
 		w.w.event(pointer.Event{
-			Type:     typ,
-			Source:   pointer.Mouse,
-			Time:     t,
-			Position: f32.Point{X: x, Y: y},
-			Scroll:   f32.Point{X: dx, Y: dy},
+			Type:      typ,
+			Source:    pointer.Mouse,
+			Time:      t,
+			Position:  f32.Point{X: x, Y: y},
+			Scroll:    f32.Point{X: dx, Y: dy},
+			Buttons:   btns,
+			Modifiers: kmods,
 		})
 	})
 }
 
+func convertMods(mods C.NSUInteger) key.Modifiers {
+	var kmods key.Modifiers
+	if mods&C.NSEventModifierFlagCommand != 0 {
+		kmods |= key.ModCommand
+	}
+	if mods&C.NSEventModifierFlagShift != 0 {
+		kmods |= key.ModShift
+	}
+	return kmods
+}
+
 //export gio_onDraw
 func gio_onDraw(view C.CFTypeRef) {
 	viewDo(view, func(views viewMap, view C.CFTypeRef) {
@@ -6,6 +6,17 @@
 #define GIO_MOUSE_MOVE 1
 #define GIO_MOUSE_UP 2
 #define GIO_MOUSE_DOWN 3
+#define GIO_MOUSE_LEAVE 4
+
+#define GIO_CURSOR_DEFAULT 0
+#define GIO_CURSOR_TEXT 1
+#define GIO_CURSOR_POINTER 2
+#define GIO_CURSOR_CROSSHAIR 3
+#define GIO_CURSOR_COLRESIZE 4
+#define GIO_CURSOR_ROWRESIZE 5
+#define GIO_CURSOR_GRAB 6
+#define GIO_CURSOR_NOTALLOWED 7
+#define GIO_CURSOR_NONE 8
 
 __attribute__ ((visibility ("hidden"))) void gio_main(CFTypeRef viewRef, const char *title, CGFloat width, CGFloat height);
 __attribute__ ((visibility ("hidden"))) CGFloat gio_viewWidth(CFTypeRef viewRef);
@@ -15,5 +26,8 @@ __attribute__ ((visibility ("hidden"))) void gio_updateDisplayLink(CFTypeRef vie
 __attribute__ ((visibility ("hidden"))) CGFloat gio_getPixelsPerDP(void);
 __attribute__ ((visibility ("hidden"))) CGFloat gio_getBackingScale(void);
 __attribute__ ((visibility ("hidden"))) CGFloat gio_getViewBackingScale(CFTypeRef viewRef);
+__attribute__ ((visibility ("hidden"))) char *gio_readClipboard(void);
+__attribute__ ((visibility ("hidden"))) void gio_writeClipboard(const char *text);
+__attribute__ ((visibility ("hidden"))) void gio_setCursor(int curID);
 
 #endif
@@ -4,6 +4,8 @@
 
 @import AppKit;
 
+#include <string.h>
+
 #include "os_macos.h"
 #include "_cgo_export.h"
 
@@ -81,6 +83,69 @@ CGFloat gio_getViewBackingScale(CFTypeRef viewRef) {
 	return [view.window backingScaleFactor];
 }
 
+// gio_readClipboard returns the clipboard text in a
+// buffer allocated with malloc, or NULL if there is none.
+char *gio_readClipboard(void) {
+	@autoreleasepool {
+		NSString *text = [[NSPasteboard generalPasteboard] stringForType:NSPasteboardTypeString];
+		if (text == nil) {
+			return NULL;
+		}
+		return strdup([text UTF8String]);
+	}
+}
+
+void gio_writeClipboard(const char *text) {
+	@autoreleasepool {
+		NSPasteboard *p = [NSPasteboard generalPasteboard];
+		[p clearContents];
+		[p setString:[NSString stringWithUTF8String:text] forType:NSPasteboardTypeString];
+	}
+}
+
+void gio_setCursor(int curID) {
+	dispatch_async(dispatch_get_main_queue(), ^{
+		static BOOL hidden = NO;
+		if (curID == GIO_CURSOR_NONE) {
+			if (!hidden) {
+				[NSCursor hide];
+				hidden = YES;
+			}
+			return;
+		}
+		if (hidden) {
+			[NSCursor unhide];
+			hidden = NO;
+		}
+		switch (curID) {
+		case GIO_CURSOR_TEXT:
+			[[NSCursor IBeamCursor] set];
+			break;
+		case GIO_CURSOR_POINTER:
+			[[NSCursor pointingHandCursor] set];
+			break;
+		case GIO_CURSOR_CROSSHAIR:
+			[[NSCursor crosshairCursor] set];
+			break;
+		case GIO_CURSOR_COLRESIZE:
+			[[NSCursor resizeLeftRightCursor] set];
+			break;
+		case GIO_CURSOR_ROWRESIZE:
+			[[NSCursor resizeUpDownCursor] set];
+			break;
+		case GIO_CURSOR_GRAB:
+			[[NSCursor openHandCursor] set];
+			break;
+		case GIO_CURSOR_NOTALLOWED:
+			[[NSCursor operationNotAllowedCursor] set];
+			break;
+		default:
+			[[NSCursor arrowCursor] set];
+			break;
+		}
+	});
+}
+
 void gio_main(CFTypeRef viewRef, const char *title, CGFloat width, CGFloat height) {
 	@autoreleasepool {
 		NSView *view = (NSView *)CFBridgingRelease(viewRef);
@@ -136,3 +136,68 @@ static const struct zwp_text_input_v3_listener zwp_text_input_v3_listener = {
 void gio_zwp_text_input_v3_add_listener(struct zwp_text_input_v3 *im) {
 	zwp_text_input_v3_add_listener(im, &zwp_text_input_v3_listener, NULL);
 }
+
+static void data_device_handle_leave(void *data, struct wl_data_device *device) {
+}
+
+static void data_device_handle_motion(void *data, struct wl_data_device *device, uint32_t time, wl_fixed_t x, wl_fixed_t y) {
+}
+
+static void data_device_handle_drop(void *data, struct wl_data_device *device) {
+}
+
+static const struct wl_data_device_listener wl_data_device_listener = {
+	.data_offer = gio_onDataDeviceOffer,
+	.enter = gio_onDataDeviceEnter,
+	.leave = data_device_handle_leave,
+	.motion = data_device_handle_motion,
+	.drop = data_device_handle_drop,
+	.selection = gio_onDataDeviceSelection,
+};
+
+void gio_wl_data_device_add_listener(struct wl_data_device *device) {
+	wl_data_device_add_listener(device, &wl_data_device_listener, NULL);
+}
+
+static void data_offer_handle_source_actions(void *data, struct wl_data_offer *offer, uint32_t source_actions) {
+}
+
+static void data_offer_handle_action(void *data, struct wl_data_offer *offer, uint32_t dnd_action) {
+}
+
+static const struct wl_data_offer_listener wl_data_offer_listener = {
+	// Cast away const parameter.
+	.offer = (void (*)(void *, struct wl_data_offer *, const char *))gio_onDataOfferOffer,
+	.source_actions = data_offer_handle_source_actions,
+	.action = data_offer_handle_action,
+};
+
+void gio_wl_data_offer_add_listener(struct wl_data_offer *offer) {
+	wl_data_offer_add_listener(offer, &wl_data_offer_listener, NULL);
+}
+
+static void data_source_handle_target(void *data, struct wl_data_source *source, const char *mime_type) {
+}
+
+static void data_source_handle_dnd_drop_performed(void *data, struct wl_data_source *source) {
+}
+
+static void data_source_handle_dnd_finished(void *data, struct wl_data_source *source) {
+}
+
+static void data_source_handle_action(void *data, struct wl_data_source *source, uint32_t dnd_action) {
+}
+
+static const struct wl_data_source_listener wl_data_source_listener = {
+	.target = data_source_handle_target,
+	// Cast away const parameter.
+	.send = (void (*)(void *, struct wl_data_source *, const char *, int32_t))gio_onDataSourceSend,
+	.cancelled = gio_onDataSourceCancelled,
+	.dnd_drop_performed = data_source_handle_dnd_drop_performed,
+	.dnd_finished = data_source_handle_dnd_finished,
+	.action = data_source_handle_action,
+};
+
+void gio_wl_data_source_add_listener(struct wl_data_source *source) {
+	wl_data_source_add_listener(source, &wl_data_source_listener, NULL);
+}
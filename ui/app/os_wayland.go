@@ -9,6 +9,8 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"os/exec"
@@ -19,6 +21,8 @@ import (
 	"unicode/utf8"
 	"unsafe"
 
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -78,6 +82,28 @@ type wlConn struct {
 	utf8Buf      []byte
 
 	repeat repeatState
+
+	dataDevManager *C.struct_wl_data_device_manager
+	dataDev        *C.struct_wl_data_device
+	// serial is the serial of the most recent input
+	// event, for setting the clipboard selection.
+	serial C.uint32_t
+	// offers maps data offers to their mime types.
+	offers map[*C.struct_wl_data_offer][]string
+	// selection is the offer of the clipboard
+	// content, if any.
+	selection *C.struct_wl_data_offer
+	// sources maps the data sources of clipboard
+	// content written by the program to their text.
+	sources map[*C.struct_wl_data_source]string
+
+	// cursorNone is set when the cursor is hidden.
+	cursorNone bool
+	// enterSerial is the serial of the most recent
+	// pointer enter event, and pointerFocus tracks
+	// whether the pointer is in a window.
+	enterSerial  C.uint32_t
+	pointerFocus bool
 }
 
 type repeatState struct {
@@ -108,9 +134,10 @@ type window struct {
 	discScroll        struct {
 		x, y int
 	}
-	scroll    f32.Point
-	lastPos   f32.Point
-	lastTouch f32.Point
+	scroll      f32.Point
+	lastPos     f32.Point
+	lastTouch   f32.Point
+	pointerBtns pointer.Buttons
 
 	stage             Stage
 	dead              bool
@@ -125,6 +152,16 @@ type window struct {
 	height   int
 	newScale bool
 	scale    int
+	// readClip and writeClip are the clipboard
+	// requests waiting for the event loop.
+	readClip  bool
+	writeClip *string
+	// clipText is the clipboard content waiting
+	// for delivery from the event loop.
+	clipText *string
+	// cursor is the cursor change waiting for
+	// the event loop, if any.
+	cursor *pointer.Cursor
 }
 
 type wlOutput struct {
@@ -147,6 +184,10 @@ var (
 	outputConfig = make(map[*C.struct_wl_output]*wlOutput)
 )
 
+// textMimes are the mime types of clipboard text,
+// in order of preference.
+var textMimes = []string{"text/plain;charset=utf-8", "UTF8_STRING", "text/plain"}
+
 var (
 	_XKB_MOD_NAME_CTRL  = []byte("Control\x00")
 	_XKB_MOD_NAME_SHIFT = []byte("Shift\x00")
@@ -266,6 +307,10 @@ func gio_onSeatCapabilities(data unsafe.Pointer, seat *C.struct_wl_seat, caps C.
 		conn.im = C.zwp_text_input_manager_v3_get_text_input(conn.imm, conn.seat)
 		C.gio_zwp_text_input_v3_add_listener(conn.im)
 	}
+	if conn.dataDev == nil && conn.dataDevManager != nil {
+		conn.dataDev = C.wl_data_device_manager_get_data_device(conn.dataDevManager, conn.seat)
+		C.gio_wl_data_device_add_listener(conn.dataDev)
+	}
 	switch {
 	case conn.pointer == nil && caps&C.WL_SEAT_CAPABILITY_POINTER != 0:
 		conn.pointer = C.wl_seat_get_pointer(seat)
@@ -403,6 +448,8 @@ func gio_onRegistryGlobal(data unsafe.Pointer, reg *C.struct_wl_registry, name C
 			conn.seat = (*C.struct_wl_seat)(C.wl_registry_bind(reg, name, &C.wl_seat_interface, 5))
 			C.gio_wl_seat_add_listener(conn.seat)
 		}
+	case "wl_data_device_manager":
+		conn.dataDevManager = (*C.struct_wl_data_device_manager)(C.wl_registry_bind(reg, name, &C.wl_data_device_manager_interface, 3))
 	case "wl_shm":
 		conn.shm = (*C.struct_wl_shm)(C.wl_registry_bind(reg, name, &C.wl_shm_interface, 1))
 	case "xdg_wm_base":
@@ -431,6 +478,12 @@ func gio_onRegistryGlobalRemove(data unsafe.Pointer, reg *C.struct_wl_registry,
 		if conn.keyboard != nil {
 			delete(winMap, conn.keyboard)
 		}
+		if conn.dataDev != nil {
+			conn.destroyOffer(conn.selection)
+			conn.selection = nil
+			C.wl_data_device_release(conn.dataDev)
+			conn.dataDev = nil
+		}
 		C.wl_seat_release(conn.seat)
 		conn.seat = nil
 	}
@@ -443,6 +496,7 @@ func gio_onRegistryGlobalRemove(data unsafe.Pointer, reg *C.struct_wl_registry,
 
 //export gio_onTouchDown
 func gio_onTouchDown(data unsafe.Pointer, touch *C.struct_wl_touch, serial, t C.uint32_t, surf *C.struct_wl_surface, id C.int32_t, x, y C.wl_fixed_t) {
+	conn.serial = serial
 	w := winMap[surf]
 	winMap[touch] = w
 	w.lastTouch = f32.Point{X: fromFixed(x), Y: fromFixed(y)}
@@ -495,16 +549,9 @@ func gio_onTouchCancel(data unsafe.Pointer, touch *C.struct_wl_touch) {
 
 //export gio_onPointerEnter
 func gio_onPointerEnter(data unsafe.Pointer, pointer *C.struct_wl_pointer, serial C.uint32_t, surf *C.struct_wl_surface, x, y C.wl_fixed_t) {
-	// Get images[0].
-	img := *conn.cursor.images
-	buf := C.wl_cursor_image_get_buffer(img)
-	if buf == nil {
-		return
-	}
-	C.wl_pointer_set_cursor(pointer, serial, conn.cursorSurf, C.int32_t(img.hotspot_x), C.int32_t(img.hotspot_y))
-	C.wl_surface_attach(conn.cursorSurf, buf, 0, 0)
-	C.wl_surface_damage(conn.cursorSurf, 0, 0, C.int32_t(img.width), C.int32_t(img.height))
-	C.wl_surface_commit(conn.cursorSurf)
+	conn.enterSerial = serial
+	conn.pointerFocus = true
+	conn.updateCursor()
 	w := winMap[surf]
 	winMap[pointer] = w
 	w.lastPos = f32.Point{X: fromFixed(x), Y: fromFixed(y)}
@@ -512,6 +559,20 @@ func gio_onPointerEnter(data unsafe.Pointer, pointer *C.struct_wl_pointer, seria
 
 //export gio_onPointerLeave
 func gio_onPointerLeave(data unsafe.Pointer, p *C.struct_wl_pointer, serial C.uint32_t, surface *C.struct_wl_surface) {
+	conn.pointerFocus = false
+	// The surface is nil if it was destroyed.
+	w, exists := winMap[surface]
+	if !exists {
+		return
+	}
+	w.flushScroll()
+	w.w.event(pointer.Event{
+		Type:      pointer.Leave,
+		Position:  w.lastPos,
+		Source:    pointer.Mouse,
+		Buttons:   w.pointerBtns,
+		Modifiers: keyModifiers(),
+	})
 }
 
 //export gio_onPointerMotion
@@ -522,25 +583,48 @@ func gio_onPointerMotion(data unsafe.Pointer, p *C.struct_wl_pointer, t C.uint32
 
 //export gio_onPointerButton
 func gio_onPointerButton(data unsafe.Pointer, p *C.struct_wl_pointer, serial, t, button, state C.uint32_t) {
+	conn.serial = serial
 	w := winMap[p]
 	// From linux-event-codes.h.
-	const BTN_LEFT = 0x110
-	if button != BTN_LEFT {
+	const (
+		BTN_LEFT   = 0x110
+		BTN_RIGHT  = 0x111
+		BTN_MIDDLE = 0x112
+		BTN_SIDE   = 0x113
+		BTN_EXTRA  = 0x114
+	)
+	var btn pointer.Buttons
+	switch button {
+	case BTN_LEFT:
+		btn = pointer.ButtonPrimary
+	case BTN_RIGHT:
+		btn = pointer.ButtonSecondary
+	case BTN_MIDDLE:
+		btn = pointer.ButtonTertiary
+	case BTN_SIDE:
+		btn = pointer.ButtonBack
+	case BTN_EXTRA:
+		btn = pointer.ButtonForward
+	default:
 		return
 	}
 	var typ pointer.Type
 	switch state {
 	case 0:
+		w.pointerBtns &^= btn
 		typ = pointer.Release
 	case 1:
+		w.pointerBtns |= btn
 		typ = pointer.Press
 	}
 	w.flushScroll()
 	w.w.event(pointer.Event{
-		Type:     typ,
-		Source:   pointer.Mouse,
-		Position: w.lastPos,
-		Time:     time.Duration(t) * time.Millisecond,
+		Type:      typ,
+		Source:    pointer.Mouse,
+		Position:  w.lastPos,
+		Buttons:   w.pointerBtns,
+		Modifiers: keyModifiers(),
+		Time:      time.Duration(t) * time.Millisecond,
 	})
 }
 
@@ -646,6 +730,7 @@ func gio_onKeyboardKeymap(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, f
 //export gio_onKeyboardEnter
 func gio_onKeyboardEnter(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, serial C.uint32_t, surf *C.struct_wl_surface, keys *C.struct_wl_array) {
 	conn.repeat.Stop(0)
+	conn.serial = serial
 	w := winMap[surf]
 	winMap[keyboard] = w
 	w.w.event(key.FocusEvent{Focus: true})
@@ -662,6 +747,7 @@ func gio_onKeyboardLeave(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, se
 func gio_onKeyboardKey(data unsafe.Pointer, keyboard *C.struct_wl_keyboard, serial, timestamp, keyCode, state C.uint32_t) {
 	t := time.Duration(timestamp) * time.Millisecond
 	conn.repeat.Stop(t)
+	conn.serial = serial
 	w := winMap[keyboard]
 	if state != C.WL_KEYBOARD_KEY_STATE_PRESSED || conn.xkbMap == nil || conn.xkbState == nil || conn.xkbCompState == nil {
 		return
@@ -801,6 +887,8 @@ loop:
 			break loop
 		}
 		conn.repeat.Repeat()
+		w.processClipboard()
+		w.processCursor()
 		if redraw {
 			w.draw(false)
 		}
@@ -825,10 +913,13 @@ func (w *window) notify() {
 }
 
 func (w *window) destroy() {
+	// Lock out notifications from clipboard reads.
+	w.mu.Lock()
 	if w.notWrite != 0 {
 		syscall.Close(w.notWrite)
 		w.notWrite = 0
 	}
+	w.mu.Unlock()
 	if w.notRead != 0 {
 		syscall.Close(w.notRead)
 		w.notRead = 0
@@ -850,19 +941,28 @@ func (w *window) destroy() {
 	}
 }
 
+// keyModifiers returns the active keyboard modifiers.
+func keyModifiers() key.Modifiers {
+	var mods key.Modifiers
+	if conn.xkbState == nil {
+		return mods
+	}
+	if C.xkb_state_mod_name_is_active(conn.xkbState, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_CTRL[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
+		mods |= key.ModCommand
+	}
+	if C.xkb_state_mod_name_is_active(conn.xkbState, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_SHIFT[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
+		mods |= key.ModShift
+	}
+	return mods
+}
+
 func (w *window) dispatchKey(keyCode C.uint32_t) {
 	if len(conn.utf8Buf) == 0 {
 		conn.utf8Buf = make([]byte, 1)
 	}
 	sym := C.xkb_state_key_get_one_sym(conn.xkbState, C.xkb_keycode_t(keyCode))
 	if n, ok := convertKeysym(sym); ok {
-		cmd := key.ChordEvent{Name: n}
-		if C.xkb_state_mod_name_is_active(conn.xkbState, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_CTRL[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
-			cmd.Modifiers |= key.ModCommand
-		}
-		if C.xkb_state_mod_name_is_active(conn.xkbState, (*C.char)(unsafe.Pointer(&_XKB_MOD_NAME_SHIFT[0])), C.XKB_STATE_MODS_EFFECTIVE) == 1 {
-			cmd.Modifiers |= key.ModShift
-		}
+		cmd := key.ChordEvent{Name: n, Modifiers: keyModifiers()}
 		w.w.event(cmd)
 	}
 	C.xkb_compose_state_feed(conn.xkbCompState, sym)
@@ -968,11 +1068,13 @@ func (w *window) flushScroll() {
 		w.scroll.Y *= discreteScale
 	}
 	w.w.event(pointer.Event{
-		Type:     pointer.Move,
-		Source:   pointer.Mouse,
-		Position: w.lastPos,
-		Scroll:   w.scroll,
-		Time:     w.scrollTime,
+		Type:      pointer.Move,
+		Source:    pointer.Mouse,
+		Position:  w.lastPos,
+		Scroll:    w.scroll,
+		Buttons:   w.pointerBtns,
+		Modifiers: keyModifiers(),
+		Time:      w.scrollTime,
 	})
 	w.scroll = f32.Point{}
 	w.discScroll.x = 0
@@ -983,10 +1085,12 @@ func (w *window) onPointerMotion(x, y C.wl_fixed_t, t C.uint32_t) {
 	w.flushScroll()
 	w.lastPos = f32.Point{X: fromFixed(x), Y: fromFixed(y)}
 	w.w.event(pointer.Event{
-		Type:     pointer.Move,
-		Position: w.lastPos,
-		Source:   pointer.Mouse,
-		Time:     time.Duration(t) * time.Millisecond,
+		Type:      pointer.Move,
+		Position:  w.lastPos,
+		Source:    pointer.Mouse,
+		Buttons:   w.pointerBtns,
+		Modifiers: keyModifiers(),
+		Time:      time.Duration(t) * time.Millisecond,
 	})
 }
 
@@ -1089,6 +1193,231 @@ func (w *window) nativeWindow(visID int) (unsafe.Pointer, int, int) {
 
 func (w *window) showTextInput(show bool) {}
 
+// cursorNames maps cursors to cursor theme names, in order of
+// preference.
+var cursorNames = map[pointer.Cursor][]string{
+	pointer.CursorDefault:    {"left_ptr"},
+	pointer.CursorText:       {"text", "xterm"},
+	pointer.CursorPointer:    {"pointer", "hand2"},
+	pointer.CursorCrossHair:  {"crosshair"},
+	pointer.CursorColResize:  {"col-resize", "sb_h_double_arrow"},
+	pointer.CursorRowResize:  {"row-resize", "sb_v_double_arrow"},
+	pointer.CursorGrab:       {"grab", "hand1"},
+	pointer.CursorNotAllowed: {"not-allowed", "crossed_circle"},
+}
+
+// setCursor changes the cursor shape. Shapes missing
+// from the cursor theme are replaced by the default
+// cursor.
+func (c *wlConn) setCursor(curs pointer.Cursor) {
+	c.cursorNone = curs == pointer.CursorNone
+	if !c.cursorNone {
+		names := append(cursorNames[curs], cursorNames[pointer.CursorDefault]...)
+		for _, name := range names {
+			cname := C.CString(name)
+			wc := C.wl_cursor_theme_get_cursor(c.cursorTheme, cname)
+			C.free(unsafe.Pointer(cname))
+			if wc != nil {
+				c.cursor = wc
+				break
+			}
+		}
+	}
+	c.updateCursor()
+}
+
+// updateCursor sets the cursor of the pointer, if it
+// is in a window.
+func (c *wlConn) updateCursor() {
+	if c.pointer == nil || !c.pointerFocus {
+		return
+	}
+	if c.cursorNone {
+		C.wl_pointer_set_cursor(c.pointer, c.enterSerial, nil, 0, 0)
+		return
+	}
+	// Get images[0].
+	img := *c.cursor.images
+	buf := C.wl_cursor_image_get_buffer(img)
+	if buf == nil {
+		return
+	}
+	C.wl_pointer_set_cursor(c.pointer, c.enterSerial, c.cursorSurf, C.int32_t(img.hotspot_x), C.int32_t(img.hotspot_y))
+	C.wl_surface_attach(c.cursorSurf, buf, 0, 0)
+	C.wl_surface_damage(c.cursorSurf, 0, 0, C.int32_t(img.width), C.int32_t(img.height))
+	C.wl_surface_commit(c.cursorSurf)
+}
+
+func (w *window) readClipboard() {
+	w.mu.Lock()
+	w.readClip = true
+	w.mu.Unlock()
+	w.notify()
+}
+
+func (w *window) writeClipboard(s string) {
+	w.mu.Lock()
+	w.writeClip = &s
+	w.mu.Unlock()
+	w.notify()
+}
+
+func (w *window) setCursor(c pointer.Cursor) {
+	w.mu.Lock()
+	w.cursor = &c
+	w.mu.Unlock()
+	w.notify()
+}
+
+func (w *window) submit(frame *ui.Ops) {}
+
+// processCursor carries out the cursor change
+// from the event loop.
+func (w *window) processCursor() {
+	w.mu.Lock()
+	c := w.cursor
+	w.cursor = nil
+	w.mu.Unlock()
+	if c != nil {
+		conn.setCursor(*c)
+	}
+}
+
+// processClipboard carries out the clipboard
+// requests from the event loop.
+func (w *window) processClipboard() {
+	w.mu.Lock()
+	read, write, text := w.readClip, w.writeClip, w.clipText
+	w.readClip, w.writeClip, w.clipText = false, nil, nil
+	w.mu.Unlock()
+	if write != nil {
+		conn.setSelection(*write)
+	}
+	if text != nil {
+		w.w.event(clipboard.Event{Text: *text})
+	}
+	if !read {
+		return
+	}
+	r, err := conn.receiveSelection()
+	if err != nil {
+		w.w.event(clipboard.Event{})
+		return
+	}
+	// The selection may be owned by another client,
+	// or by this window through the event loop.
+	go func() {
+		defer r.Close()
+		data, _ := ioutil.ReadAll(r)
+		text := string(data)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.clipText = &text
+		if w.notWrite != 0 {
+			w.notify()
+		}
+	}()
+}
+
+// setSelection replaces the clipboard content with text.
+func (c *wlConn) setSelection(text string) {
+	if c.dataDev == nil {
+		return
+	}
+	src := C.wl_data_device_manager_create_data_source(c.dataDevManager)
+	C.gio_wl_data_source_add_listener(src)
+	for _, mime := range textMimes {
+		cmime := C.CString(mime)
+		C.wl_data_source_offer(src, cmime)
+		C.free(unsafe.Pointer(cmime))
+	}
+	if c.sources == nil {
+		c.sources = make(map[*C.struct_wl_data_source]string)
+	}
+	c.sources[src] = text
+	C.wl_data_device_set_selection(c.dataDev, src, c.serial)
+}
+
+// receiveSelection returns a reader for the
+// clipboard content.
+func (c *wlConn) receiveSelection() (io.ReadCloser, error) {
+	if c.selection == nil {
+		return nil, errors.New("wayland: no clipboard content")
+	}
+	var mime string
+	for _, m := range textMimes {
+		for _, m2 := range c.offers[c.selection] {
+			if m == m2 && mime == "" {
+				mime = m
+			}
+		}
+	}
+	if mime == "" {
+		return nil, errors.New("wayland: no clipboard text")
+	}
+	pipe := make([]int, 2)
+	if err := syscall.Pipe2(pipe, syscall.O_CLOEXEC); err != nil {
+		return nil, fmt.Errorf("wayland: failed to create pipe: %v", err)
+	}
+	cmime := C.CString(mime)
+	C.wl_data_offer_receive(c.selection, cmime, C.int32_t(pipe[1]))
+	C.free(unsafe.Pointer(cmime))
+	// The request holds a copy of the write end.
+	syscall.Close(pipe[1])
+	return os.NewFile(uintptr(pipe[0]), "clipboard"), nil
+}
+
+func (c *wlConn) destroyOffer(offer *C.struct_wl_data_offer) {
+	if offer == nil {
+		return
+	}
+	delete(c.offers, offer)
+	C.wl_data_offer_destroy(offer)
+}
+
+//export gio_onDataDeviceOffer
+func gio_onDataDeviceOffer(data unsafe.Pointer, dev *C.struct_wl_data_device, offer *C.struct_wl_data_offer) {
+	if conn.offers == nil {
+		conn.offers = make(map[*C.struct_wl_data_offer][]string)
+	}
+	conn.offers[offer] = nil
+	C.gio_wl_data_offer_add_listener(offer)
+}
+
+//export gio_onDataOfferOffer
+func gio_onDataOfferOffer(data unsafe.Pointer, offer *C.struct_wl_data_offer, mime *C.char) {
+	conn.offers[offer] = append(conn.offers[offer], C.GoString(mime))
+}
+
+//export gio_onDataDeviceEnter
+func gio_onDataDeviceEnter(data unsafe.Pointer, dev *C.struct_wl_data_device, serial C.uint32_t, surf *C.struct_wl_surface, x, y C.wl_fixed_t, offer *C.struct_wl_data_offer) {
+	// Drag and drop is not supported.
+	conn.destroyOffer(offer)
+}
+
+//export gio_onDataDeviceSelection
+func gio_onDataDeviceSelection(data unsafe.Pointer, dev *C.struct_wl_data_device, offer *C.struct_wl_data_offer) {
+	conn.destroyOffer(conn.selection)
+	conn.selection = offer
+}
+
+//export gio_onDataSourceSend
+func gio_onDataSourceSend(data unsafe.Pointer, src *C.struct_wl_data_source, mime *C.char, fd C.int32_t) {
+	text := conn.sources[src]
+	f := os.NewFile(uintptr(fd), "clipboard")
+	// Don't block the event loop on the receiver.
+	go func() {
+		defer f.Close()
+		io.WriteString(f, text)
+	}()
+}
+
+//export gio_onDataSourceCancelled
+func gio_onDataSourceCancelled(data unsafe.Pointer, src *C.struct_wl_data_source) {
+	delete(conn.sources, src)
+	C.wl_data_source_destroy(src)
+}
+
 // detectFontScale reports current font scale, or 1.0
 // if it fails.
 func detectFontScale() float32 {
@@ -1198,6 +1527,16 @@ func (c *wlConn) destroy() {
 	if c.imm != nil {
 		C.zwp_text_input_manager_v3_destroy(c.imm)
 	}
+	for src := range c.sources {
+		C.wl_data_source_destroy(src)
+	}
+	c.destroyOffer(c.selection)
+	if c.dataDev != nil {
+		C.wl_data_device_release(c.dataDev)
+	}
+	if c.dataDevManager != nil {
+		C.wl_data_device_manager_destroy(c.dataDevManager)
+	}
 	if c.seat != nil {
 		C.wl_seat_release(c.seat)
 	}
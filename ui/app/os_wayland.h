@@ -12,3 +12,6 @@ __attribute__ ((visibility ("hidden"))) void gio_wl_pointer_add_listener(struct
 __attribute__ ((visibility ("hidden"))) void gio_wl_touch_add_listener(struct wl_touch *touch);
 __attribute__ ((visibility ("hidden"))) void gio_wl_keyboard_add_listener(struct wl_keyboard *keyboard);
 __attribute__ ((visibility ("hidden"))) void gio_zwp_text_input_v3_add_listener(struct zwp_text_input_v3 *im);
+__attribute__ ((visibility ("hidden"))) void gio_wl_data_device_add_listener(struct wl_data_device *device);
+__attribute__ ((visibility ("hidden"))) void gio_wl_data_offer_add_listener(struct wl_data_offer *offer);
+__attribute__ ((visibility ("hidden"))) void gio_wl_data_source_add_listener(struct wl_data_source *source);
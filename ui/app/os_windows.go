@@ -14,6 +14,8 @@ import (
 
 	syscall "golang.org/x/sys/windows"
 
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
@@ -54,6 +56,13 @@ type point struct {
 	x, y int32
 }
 
+type trackMouseEvent struct {
+	cbSize      uint32
+	dwFlags     uint32
+	hwndTrack   syscall.Handle
+	dwHoverTime uint32
+}
+
 type window struct {
 	hwnd   syscall.Handle
 	hdc    syscall.Handle
@@ -65,27 +74,60 @@ type window struct {
 
 	mu        sync.Mutex
 	animating bool
+	// cursor is the cursor for the client area. A
+	// zero handle hides the cursor.
+	cursor syscall.Handle
+	// clipboard is the text read by the latest
+	// readClipboard, delivered by _WM_CLIPBOARD.
+	clipboard string
+	// trackingMouse is set while the window waits
+	// for the _WM_MOUSELEAVE of the mouse.
+	trackingMouse bool
+	lastMouse     f32.Point
 }
 
 const (
+	_CF_UNICODETEXT = 13
+
 	_CS_HREDRAW = 0x0002
 	_CS_VREDRAW = 0x0001
 	_CS_OWNDC   = 0x0020
 
 	_CW_USEDEFAULT = -2147483648
 
-	_IDC_ARROW = 32512
+	_GMEM_MOVEABLE = 0x0002
+
+	_HTCLIENT = 1
+
+	_IDC_ARROW   = 32512
+	_IDC_CROSS   = 32515
+	_IDC_HAND    = 32649
+	_IDC_IBEAM   = 32513
+	_IDC_NO      = 32648
+	_IDC_SIZEALL = 32646
+	_IDC_SIZENS  = 32645
+	_IDC_SIZEWE  = 32644
 
 	_INFINITE = 0xFFFFFFFF
 
 	_LOGPIXELSX = 88
 
+	_MK_LBUTTON  = 0x0001
+	_MK_RBUTTON  = 0x0002
+	_MK_SHIFT    = 0x0004
+	_MK_CONTROL  = 0x0008
+	_MK_MBUTTON  = 0x0010
+	_MK_XBUTTON1 = 0x0020
+	_MK_XBUTTON2 = 0x0040
+
 	_SIZE_MAXIMIZED = 2
 	_SIZE_MINIMIZED = 1
 	_SIZE_RESTORED  = 0
 
 	_SW_SHOWDEFAULT = 10
 
+	_TME_LEAVE = 0x00000002
+
 	_USER_TIMER_MINIMUM = 0x0000000A
 
 	_VK_CONTROL = 0x11
@@ -114,10 +156,16 @@ const (
 	_WM_KEYUP       = 0x0101
 	_WM_LBUTTONDOWN = 0x0201
 	_WM_LBUTTONUP   = 0x0202
+	_WM_MBUTTONDOWN = 0x0207
+	_WM_MBUTTONUP   = 0x0208
+	_WM_MOUSELEAVE  = 0x02A3
 	_WM_MOUSEMOVE   = 0x0200
 	_WM_MOUSEWHEEL  = 0x020A
 	_WM_PAINT       = 0x000F
 	_WM_QUIT        = 0x0012
+	_WM_SETCURSOR   = 0x0020
+	_WM_RBUTTONDOWN = 0x0204
+	_WM_RBUTTONUP   = 0x0205
 	_WM_SETFOCUS    = 0x0007
 	_WM_KILLFOCUS   = 0x0008
 	_WM_SHOWWINDOW  = 0x0018
@@ -126,6 +174,8 @@ const (
 	_WM_TIMER       = 0x0113
 	_WM_UNICHAR     = 0x0109
 	_WM_USER        = 0x0400
+	_WM_XBUTTONDOWN = 0x020B
+	_WM_XBUTTONUP   = 0x020C
 
 	_WS_CLIPCHILDREN     = 0x00010000
 	_WS_CLIPSIBLINGS     = 0x04000000
@@ -152,7 +202,10 @@ const (
 	_PM_REMOVE = 0x0001
 )
 
-const _WM_REDRAW = _WM_USER + 0
+const (
+	_WM_REDRAW    = _WM_USER + 0
+	_WM_CLIPBOARD = _WM_USER + 1
+)
 
 var onceMu sync.Mutex
 var mainDone = make(chan struct{})
@@ -242,7 +295,8 @@ func createNativeWindow(opts *WindowOptions) (*window, error) {
 		return nil, err
 	}
 	w := &window{
-		hwnd: hwnd,
+		hwnd:   hwnd,
+		cursor: curs,
 	}
 	winMap[hwnd] = w
 	w.hdc, err = getDC(hwnd)
@@ -278,16 +332,12 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 			}
 			w.w.event(cmd)
 		}
-	case _WM_LBUTTONDOWN:
-		setCapture(w.hwnd)
-		x, y := coordsFromlParam(lParam)
-		p := f32.Point{X: float32(x), Y: float32(y)}
-		w.w.event(pointer.Event{
-			Type:     pointer.Press,
-			Source:   pointer.Mouse,
-			Position: p,
-			Time:     getMessageTime(),
-		})
+	case _WM_LBUTTONDOWN, _WM_RBUTTONDOWN, _WM_MBUTTONDOWN:
+		w.pointerButton(pointer.Press, wParam, lParam)
+	case _WM_XBUTTONDOWN:
+		w.pointerButton(pointer.Press, wParam, lParam)
+		// The message is processed.
+		return 1
 	case _WM_CANCELMODE:
 		w.w.event(pointer.Event{
 			Type: pointer.Cancel,
@@ -296,27 +346,48 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 		w.w.event(key.FocusEvent{Focus: true})
 	case _WM_KILLFOCUS:
 		w.w.event(key.FocusEvent{Focus: false})
-	case _WM_LBUTTONUP:
-		releaseCapture()
+	case _WM_LBUTTONUP, _WM_RBUTTONUP, _WM_MBUTTONUP:
+		w.pointerButton(pointer.Release, wParam, lParam)
+	case _WM_XBUTTONUP:
+		w.pointerButton(pointer.Release, wParam, lParam)
+		// The message is processed.
+		return 1
+	case _WM_MOUSEMOVE:
 		x, y := coordsFromlParam(lParam)
 		p := f32.Point{X: float32(x), Y: float32(y)}
+		btns, mods := pointerButtons(wParam)
+		if !w.trackingMouse {
+			// Ask for a _WM_MOUSELEAVE when the mouse
+			// leaves the window.
+			w.trackingMouse = trackMouseLeave(w.hwnd)
+		}
+		w.lastMouse = p
 		w.w.event(pointer.Event{
-			Type:     pointer.Release,
-			Source:   pointer.Mouse,
-			Position: p,
-			Time:     getMessageTime(),
+			Type:      pointer.Move,
+			Source:    pointer.Mouse,
+			Position:  p,
+			Buttons:   btns,
+			Modifiers: mods,
+			Time:      getMessageTime(),
 		})
-	case _WM_MOUSEMOVE:
-		x, y := coordsFromlParam(lParam)
-		p := f32.Point{X: float32(x), Y: float32(y)}
+		// Apply cursor changes caused by the move.
+		w.applyCursor()
+	case _WM_MOUSELEAVE:
+		w.trackingMouse = false
 		w.w.event(pointer.Event{
-			Type:     pointer.Move,
+			Type:     pointer.Leave,
 			Source:   pointer.Mouse,
-			Position: p,
+			Position: w.lastMouse,
 			Time:     getMessageTime(),
 		})
 	case _WM_MOUSEWHEEL:
 		w.scrollEvent(wParam, lParam)
+	case _WM_SETCURSOR:
+		if lParam&0xffff == _HTCLIENT {
+			w.applyCursor()
+			// The message is processed.
+			return 1
+		}
 	case _WM_DESTROY:
 		delete(winMap, hwnd)
 		w.dead = true
@@ -329,6 +400,11 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 			w.draw(false)
 			w.postRedraw()
 		}
+	case _WM_CLIPBOARD:
+		w.mu.Lock()
+		text := w.clipboard
+		w.mu.Unlock()
+		w.w.event(clipboard.Event{Text: text})
 	case _WM_PAINT:
 		w.draw(true)
 	case _WM_SIZE:
@@ -343,6 +419,101 @@ func windowProc(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) uintptr
 	return defWindowProc(hwnd, msg, wParam, lParam)
 }
 
+func (w *window) setCursor(c pointer.Cursor) {
+	var id uint16
+	switch c {
+	case pointer.CursorNone:
+		w.mu.Lock()
+		w.cursor = 0
+		w.mu.Unlock()
+		return
+	case pointer.CursorText:
+		id = _IDC_IBEAM
+	case pointer.CursorPointer:
+		id = _IDC_HAND
+	case pointer.CursorCrossHair:
+		id = _IDC_CROSS
+	case pointer.CursorColResize:
+		id = _IDC_SIZEWE
+	case pointer.CursorRowResize:
+		id = _IDC_SIZENS
+	case pointer.CursorGrab:
+		id = _IDC_SIZEALL
+	case pointer.CursorNotAllowed:
+		id = _IDC_NO
+	default:
+		id = _IDC_ARROW
+	}
+	curs, err := loadCursor(id)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	w.cursor = curs
+	w.mu.Unlock()
+}
+
+func (w *window) submit(frame *ui.Ops) {}
+
+// applyCursor sets the cursor of the window. It must be
+// called from the window thread.
+func (w *window) applyCursor() {
+	w.mu.Lock()
+	c := w.cursor
+	w.mu.Unlock()
+	setCursor(c)
+}
+
+// pointerButton delivers a press or release of a mouse button.
+func (w *window) pointerButton(typ pointer.Type, wParam, lParam uintptr) {
+	btns, mods := pointerButtons(wParam)
+	if typ == pointer.Press {
+		setCapture(w.hwnd)
+	} else if btns == 0 {
+		releaseCapture()
+	}
+	x, y := coordsFromlParam(lParam)
+	p := f32.Point{X: float32(x), Y: float32(y)}
+	w.w.event(pointer.Event{
+		Type:      typ,
+		Source:    pointer.Mouse,
+		Position:  p,
+		Buttons:   btns,
+		Modifiers: mods,
+		Time:      getMessageTime(),
+	})
+}
+
+// pointerButtons converts the MK_* flags of a mouse message
+// to the pressed buttons and modifiers.
+func pointerButtons(wParam uintptr) (pointer.Buttons, key.Modifiers) {
+	var btns pointer.Buttons
+	var mods key.Modifiers
+	flags := wParam & 0xffff
+	if flags&_MK_LBUTTON != 0 {
+		btns |= pointer.ButtonPrimary
+	}
+	if flags&_MK_RBUTTON != 0 {
+		btns |= pointer.ButtonSecondary
+	}
+	if flags&_MK_MBUTTON != 0 {
+		btns |= pointer.ButtonTertiary
+	}
+	if flags&_MK_XBUTTON1 != 0 {
+		btns |= pointer.ButtonBack
+	}
+	if flags&_MK_XBUTTON2 != 0 {
+		btns |= pointer.ButtonForward
+	}
+	if flags&_MK_CONTROL != 0 {
+		mods |= key.ModCommand
+	}
+	if flags&_MK_SHIFT != 0 {
+		mods |= key.ModShift
+	}
+	return btns, mods
+}
+
 func coordsFromlParam(lParam uintptr) (int, int) {
 	x := int(int16(lParam & 0xffff))
 	y := int(int16((lParam >> 16) & 0xffff))
@@ -357,12 +528,15 @@ func (w *window) scrollEvent(wParam, lParam uintptr) {
 	screenToClient(w.hwnd, &np)
 	p := f32.Point{X: float32(np.x), Y: float32(np.y)}
 	dist := float32(int16(wParam >> 16))
+	btns, mods := pointerButtons(wParam)
 	w.w.event(pointer.Event{
-		Type:     pointer.Move,
-		Source:   pointer.Mouse,
-		Position: p,
-		Scroll:   f32.Point{Y: -dist},
-		Time:     getMessageTime(),
+		Type:      pointer.Move,
+		Source:    pointer.Mouse,
+		Position:  p,
+		Scroll:    f32.Point{Y: -dist},
+		Buttons:   btns,
+		Modifiers: mods,
+		Time:      getMessageTime(),
 	})
 }
 
@@ -443,6 +617,83 @@ func (w *window) destroy() {
 
 func (w *window) showTextInput(show bool) {}
 
+func (w *window) readClipboard() {
+	// Don't block the window; the clipboard may be
+	// held open by another program. The text is
+	// delivered from the window thread.
+	hwnd := w.hwnd
+	go func() {
+		text, _ := readClipboard(hwnd)
+		w.mu.Lock()
+		w.clipboard = text
+		w.mu.Unlock()
+		// The window may be gone.
+		postMessage(hwnd, _WM_CLIPBOARD, 0, 0)
+	}()
+}
+
+func (w *window) writeClipboard(s string) {
+	writeClipboard(w.hwnd, s)
+}
+
+func readClipboard(hwnd syscall.Handle) (string, error) {
+	if err := openClipboard(hwnd); err != nil {
+		return "", err
+	}
+	defer closeClipboard()
+	mem, err := getClipboardData(_CF_UNICODETEXT)
+	if err != nil {
+		return "", err
+	}
+	ptr, err := globalLock(mem)
+	if err != nil {
+		return "", err
+	}
+	defer globalUnlock(mem)
+	// The text is zero terminated within the
+	// memory object.
+	n := globalSize(mem) / 2
+	if n == 0 {
+		return "", nil
+	}
+	u16 := make([]uint16, n)
+	copyFromGlobal(unsafe.Pointer(&u16[0]), ptr, n*2)
+	return syscall.UTF16ToString(u16), nil
+}
+
+func writeClipboard(hwnd syscall.Handle, s string) error {
+	u16, err := syscall.UTF16FromString(s)
+	if err != nil {
+		return err
+	}
+	if err := openClipboard(hwnd); err != nil {
+		return err
+	}
+	defer closeClipboard()
+	if err := emptyClipboard(); err != nil {
+		return err
+	}
+	n := len(u16) * int(unsafe.Sizeof(u16[0]))
+	mem, err := globalAlloc(_GMEM_MOVEABLE, uintptr(n))
+	if err != nil {
+		return err
+	}
+	ptr, err := globalLock(mem)
+	if err != nil {
+		globalFree(mem)
+		return err
+	}
+	copyToGlobal(ptr, unsafe.Pointer(&u16[0]), uintptr(n))
+	globalUnlock(mem)
+	if err := setClipboardData(_CF_UNICODETEXT, mem); err != nil {
+		// The clipboard owns the memory only if
+		// SetClipboardData succeeds.
+		globalFree(mem)
+		return err
+	}
+	return nil
+}
+
 func (w *window) display() uintptr {
 	return uintptr(w.hdc)
 }
@@ -503,14 +754,23 @@ func configForDC(hdc syscall.Handle) Config {
 var (
 	kernel32          = syscall.NewLazySystemDLL("kernel32.dll")
 	_GetModuleHandleW = kernel32.NewProc("GetModuleHandleW")
+	_GlobalAlloc      = kernel32.NewProc("GlobalAlloc")
+	_GlobalFree       = kernel32.NewProc("GlobalFree")
+	_GlobalLock       = kernel32.NewProc("GlobalLock")
+	_GlobalSize       = kernel32.NewProc("GlobalSize")
+	_GlobalUnlock     = kernel32.NewProc("GlobalUnlock")
+	_RtlMoveMemory    = kernel32.NewProc("RtlMoveMemory")
 
 	user32                       = syscall.NewLazySystemDLL("user32.dll")
 	_AdjustWindowRectEx          = user32.NewProc("AdjustWindowRectEx")
 	_CallMsgFilter               = user32.NewProc("CallMsgFilterW")
+	_CloseClipboard              = user32.NewProc("CloseClipboard")
 	_CreateWindowEx              = user32.NewProc("CreateWindowExW")
 	_DefWindowProc               = user32.NewProc("DefWindowProcW")
 	_DestroyWindow               = user32.NewProc("DestroyWindow")
 	_DispatchMessage             = user32.NewProc("DispatchMessageW")
+	_EmptyClipboard              = user32.NewProc("EmptyClipboard")
+	_GetClipboardData            = user32.NewProc("GetClipboardData")
 	_GetClientRect               = user32.NewProc("GetClientRect")
 	_GetDC                       = user32.NewProc("GetDC")
 	_GetKeyState                 = user32.NewProc("GetKeyState")
@@ -519,6 +779,7 @@ var (
 	_KillTimer                   = user32.NewProc("KillTimer")
 	_LoadCursor                  = user32.NewProc("LoadCursorW")
 	_MsgWaitForMultipleObjectsEx = user32.NewProc("MsgWaitForMultipleObjectsEx")
+	_OpenClipboard               = user32.NewProc("OpenClipboard")
 	_PeekMessage                 = user32.NewProc("PeekMessageW")
 	_PostMessage                 = user32.NewProc("PostMessageW")
 	_PostQuitMessage             = user32.NewProc("PostQuitMessage")
@@ -528,10 +789,13 @@ var (
 	_ScreenToClient              = user32.NewProc("ScreenToClient")
 	_ShowWindow                  = user32.NewProc("ShowWindow")
 	_SetCapture                  = user32.NewProc("SetCapture")
+	_SetClipboardData            = user32.NewProc("SetClipboardData")
+	_SetCursor                   = user32.NewProc("SetCursor")
 	_SetForegroundWindow         = user32.NewProc("SetForegroundWindow")
 	_SetFocus                    = user32.NewProc("SetFocus")
 	_SetProcessDPIAware          = user32.NewProc("SetProcessDPIAware")
 	_SetTimer                    = user32.NewProc("SetTimer")
+	_TrackMouseEvent             = user32.NewProc("TrackMouseEvent")
 	_TranslateMessage            = user32.NewProc("TranslateMessage")
 	_UnregisterClass             = user32.NewProc("UnregisterClassW")
 	_UpdateWindow                = user32.NewProc("UpdateWindow")
@@ -557,6 +821,14 @@ func callMsgFilter(m *msg, nCode uintptr) bool {
 	return r != 0
 }
 
+func closeClipboard() error {
+	r, _, err := _CloseClipboard.Call()
+	if r == 0 {
+		return fmt.Errorf("CloseClipboard failed: %v", err)
+	}
+	return nil
+}
+
 func createWindowEx(dwExStyle uint32, lpClassName uint16, lpWindowName string, dwStyle uint32, x, y, w, h int32, hWndParent, hMenu, hInstance syscall.Handle, lpParam uintptr) (syscall.Handle, error) {
 	hwnd, _, err := _CreateWindowEx.Call(
 		uintptr(dwExStyle),
@@ -588,6 +860,22 @@ func dispatchMessage(m *msg) {
 	_DispatchMessage.Call(uintptr(unsafe.Pointer(m)))
 }
 
+func emptyClipboard() error {
+	r, _, err := _EmptyClipboard.Call()
+	if r == 0 {
+		return fmt.Errorf("EmptyClipboard failed: %v", err)
+	}
+	return nil
+}
+
+func getClipboardData(format uint32) (syscall.Handle, error) {
+	r, _, err := _GetClipboardData.Call(uintptr(format))
+	if r == 0 {
+		return 0, fmt.Errorf("GetClipboardData failed: %v", err)
+	}
+	return syscall.Handle(r), nil
+}
+
 func getClientRect(hwnd syscall.Handle, r *rect) {
 	_GetClientRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(r)))
 }
@@ -623,6 +911,46 @@ func getMessageTime() time.Duration {
 	return time.Duration(r) * time.Millisecond
 }
 
+func globalAlloc(flags uint32, size uintptr) (syscall.Handle, error) {
+	r, _, err := _GlobalAlloc.Call(uintptr(flags), size)
+	if r == 0 {
+		return 0, fmt.Errorf("GlobalAlloc failed: %v", err)
+	}
+	return syscall.Handle(r), nil
+}
+
+func globalFree(h syscall.Handle) {
+	_GlobalFree.Call(uintptr(h))
+}
+
+// globalLock returns the address of the memory object h. The
+// address is not a Go pointer; use copyFromGlobal and copyToGlobal
+// to access the memory.
+func globalLock(h syscall.Handle) (uintptr, error) {
+	r, _, err := _GlobalLock.Call(uintptr(h))
+	if r == 0 {
+		return 0, fmt.Errorf("GlobalLock failed: %v", err)
+	}
+	return r, nil
+}
+
+func globalSize(h syscall.Handle) uintptr {
+	r, _, _ := _GlobalSize.Call(uintptr(h))
+	return r
+}
+
+func copyFromGlobal(dst unsafe.Pointer, src uintptr, n uintptr) {
+	_RtlMoveMemory.Call(uintptr(dst), src, n)
+}
+
+func copyToGlobal(dst uintptr, src unsafe.Pointer, n uintptr) {
+	_RtlMoveMemory.Call(dst, uintptr(src), n)
+}
+
+func globalUnlock(h syscall.Handle) {
+	_GlobalUnlock.Call(uintptr(h))
+}
+
 func killTimer(hwnd syscall.Handle, nIDEvent uintptr) error {
 	r, _, err := _SetTimer.Call(uintptr(hwnd), uintptr(nIDEvent), 0, 0)
 	if r == 0 {
@@ -648,6 +976,14 @@ func msgWaitForMultipleObjectsEx(nCount uint32, pHandles uintptr, millis, mask,
 	return res, nil
 }
 
+func openClipboard(hwnd syscall.Handle) error {
+	r, _, err := _OpenClipboard.Call(uintptr(hwnd))
+	if r == 0 {
+		return fmt.Errorf("OpenClipboard failed: %v", err)
+	}
+	return nil
+}
+
 func peekMessage(m *msg, hwnd syscall.Handle, wMsgFilterMin, wMsgFilterMax, wRemoveMsg uint32) bool {
 	r, _, _ := _PeekMessage.Call(uintptr(unsafe.Pointer(m)), uintptr(hwnd), uintptr(wMsgFilterMin), uintptr(wMsgFilterMax), uintptr(wRemoveMsg))
 	return r != 0
@@ -682,6 +1018,14 @@ func releaseDC(hdc syscall.Handle) {
 	_ReleaseDC.Call(uintptr(hdc))
 }
 
+func setClipboardData(format uint32, mem syscall.Handle) error {
+	r, _, err := _SetClipboardData.Call(uintptr(format), uintptr(mem))
+	if r == 0 {
+		return fmt.Errorf("SetClipboardData failed: %v", err)
+	}
+	return nil
+}
+
 func setForegroundWindow(hwnd syscall.Handle) {
 	_SetForegroundWindow.Call(uintptr(hwnd))
 }
@@ -699,6 +1043,10 @@ func setCapture(hwnd syscall.Handle) syscall.Handle {
 	return syscall.Handle(r)
 }
 
+func setCursor(h syscall.Handle) {
+	_SetCursor.Call(uintptr(h))
+}
+
 func setTimer(hwnd syscall.Handle, nIDEvent uintptr, uElapse uint32, timerProc uintptr) error {
 	r, _, err := _SetTimer.Call(uintptr(hwnd), uintptr(nIDEvent), uintptr(uElapse), timerProc)
 	if r == 0 {
@@ -715,6 +1063,18 @@ func showWindow(hwnd syscall.Handle, nCmdShow int32) {
 	_ShowWindow.Call(uintptr(hwnd), uintptr(nCmdShow))
 }
 
+// trackMouseLeave requests a _WM_MOUSELEAVE message for
+// hwnd, and reports whether the request succeeded.
+func trackMouseLeave(hwnd syscall.Handle) bool {
+	tme := trackMouseEvent{
+		dwFlags:   _TME_LEAVE,
+		hwndTrack: hwnd,
+	}
+	tme.cbSize = uint32(unsafe.Sizeof(tme))
+	r, _, _ := _TrackMouseEvent.Call(uintptr(unsafe.Pointer(&tme)))
+	return r != 0
+}
+
 func translateMessage(m *msg) {
 	_TranslateMessage.Call(uintptr(unsafe.Pointer(m)))
 }
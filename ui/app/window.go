@@ -6,12 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"image"
+	"sync"
 	"time"
 
 	"gioui.org/ui"
 	"gioui.org/ui/app/internal/gpu"
 	iinput "gioui.org/ui/app/internal/input"
 	"gioui.org/ui/input"
+	"gioui.org/ui/pointer"
+	"gioui.org/ui/semantic"
 	"gioui.org/ui/system"
 )
 
@@ -22,7 +25,7 @@ type WindowOptions struct {
 }
 
 type Window struct {
-	driver    *window
+	driver    driver
 	lastFrame time.Time
 	drawStart time.Time
 	gpu       *gpu.GPU
@@ -38,32 +41,60 @@ type Window struct {
 	hasNextFrame bool
 	nextFrame    time.Time
 	delayedDraw  *time.Timer
+	// clock, if set, replaces the real clock for
+	// scheduling redraws.
+	clock func() time.Time
 
-	queue Queue
+	queue  Queue
+	cursor pointer.Cursor
+
+	// semMu protects semantics.
+	semMu     sync.Mutex
+	semantics *semantic.Node
 }
 
 // Queue is an input.Queue implementation that distributes
 // system input events to the input handlers declared through
-// Draw.
+// Draw. Events is safe for concurrent use, for
+// example by goroutines laying out separate Ops.
 type Queue struct {
-	q iinput.Router
+	// mu serializes concurrent calls to Events.
+	mu sync.Mutex
+	q  iinput.Router
 }
 
 // driverEvent is sent when a new native driver
 // is available for the Window.
 type driverEvent struct {
-	driver *window
+	driver driver
 }
 
 // driver is the interface for the platform implementation
 // of a Window.
-var _ interface {
+type driver interface {
 	// setAnimating sets the animation flag. When the window is animating,
 	// DrawEvents are delivered as fast as the display can handle them.
 	setAnimating(anim bool)
 	// showTextInput updates the virtual keyboard state.
 	showTextInput(show bool)
-} = (*window)(nil)
+	// readClipboard requests the clipboard content. The
+	// content is delivered as a clipboard.Event, which is
+	// empty if the clipboard can't be read.
+	readClipboard()
+	// writeClipboard replaces the clipboard content.
+	writeClipboard(s string)
+	// setCursor changes the mouse cursor shape.
+	setCursor(c pointer.Cursor)
+	// newContext creates the GPU context for rendering frames,
+	// or returns nil if the driver doesn't render.
+	newContext() (*context, error)
+	// submit is called with each frame drawn by the program,
+	// or nil if the program didn't draw, after the Window
+	// has rendered and processed it.
+	submit(frame *ui.Ops)
+}
+
+var _ driver = (*window)(nil)
 
 // Pre-allocate the ack event to avoid garbage.
 var ackEvent Event
@@ -85,15 +116,21 @@ func NewWindow(opts *WindowOptions) *Window {
 		panic("window width and height must be larger than 0")
 	}
 
-	w := &Window{
+	w := newWindow()
+	go w.run(func() error {
+		return createWindow(w, opts)
+	})
+	return w
+}
+
+func newWindow() *Window {
+	return &Window{
 		in:          make(chan Event),
 		out:         make(chan Event),
 		ack:         make(chan struct{}),
 		invalidates: make(chan struct{}, 1),
 		frames:      make(chan *ui.Ops),
 	}
-	go w.run(opts)
-	return w
 }
 
 func (w *Window) Events() <-chan Event {
@@ -104,6 +141,18 @@ func (w *Window) Queue() *Queue {
 	return &w.queue
 }
 
+// Semantics returns the semantic tree of the most recent
+// frame, for bridging to platform accessibility services.
+// The tree must not be modified.
+func (w *Window) Semantics() *semantic.Node {
+	w.semMu.Lock()
+	defer w.semMu.Unlock()
+	if w.semantics == nil {
+		return new(semantic.Node)
+	}
+	return w.semantics
+}
+
 func (w *Window) Draw(frame *ui.Ops) {
 	w.frames <- frame
 }
@@ -114,8 +163,13 @@ func (w *Window) draw(size image.Point, frame *ui.Ops) {
 		drawDur = time.Since(w.drawStart)
 		w.drawStart = time.Time{}
 	}
-	w.gpu.Draw(w.queue.q.Profiling(), size, frame)
+	if w.gpu != nil {
+		w.gpu.Draw(w.queue.q.Profiling(), size, frame)
+	}
 	w.queue.q.Frame(frame)
+	w.semMu.Lock()
+	w.semantics = w.queue.q.Semantics()
+	w.semMu.Unlock()
 	now := time.Now()
 	switch w.queue.q.TextInputState() {
 	case iinput.TextInputOpen:
@@ -123,12 +177,23 @@ func (w *Window) draw(size image.Point, frame *ui.Ops) {
 	case iinput.TextInputClose:
 		w.driver.showTextInput(false)
 	}
+	if text, ok := w.queue.q.WriteClipboard(); ok {
+		w.driver.writeClipboard(text)
+	}
+	if w.queue.q.ReadClipboard() {
+		w.driver.readClipboard()
+	}
+	w.updateCursor()
 	frameDur := now.Sub(w.lastFrame)
 	frameDur = frameDur.Truncate(100 * time.Microsecond)
 	w.lastFrame = now
 	if w.queue.q.Profiling() {
 		q := 100 * time.Microsecond
-		timings := fmt.Sprintf("tot:%7s cpu:%7s %s", frameDur.Round(q), drawDur.Round(q), w.gpu.Timings())
+		var gpuTimings string
+		if w.gpu != nil {
+			gpuTimings = w.gpu.Timings()
+		}
+		timings := fmt.Sprintf("tot:%7s cpu:%7s %s", frameDur.Round(q), drawDur.Round(q), gpuTimings)
 		w.queue.q.AddProfile(system.ProfileEvent{Timings: timings})
 		w.setNextFrame(time.Time{})
 	}
@@ -138,6 +203,13 @@ func (w *Window) draw(size image.Point, frame *ui.Ops) {
 	w.updateAnimation()
 }
 
+func (w *Window) updateCursor() {
+	if c := w.queue.q.Cursor(); c != w.cursor {
+		w.cursor = c
+		w.driver.setCursor(c)
+	}
+}
+
 // Invalidate the current window such that a DrawEvent will be generated
 // immediately. If the window is not active, the redraw will trigger
 // when the window becomes active.
@@ -155,7 +227,11 @@ func (w *Window) updateAnimation() {
 		w.delayedDraw = nil
 	}
 	if w.stage >= StageRunning && w.hasNextFrame {
-		if dt := time.Until(w.nextFrame); dt <= 0 {
+		if w.clock != nil {
+			// Virtual clocks don't advance by themselves,
+			// so there is no point in waiting for them.
+			animate = !w.nextFrame.After(w.clock())
+		} else if dt := time.Until(w.nextFrame); dt <= 0 {
 			animate = true
 		} else {
 			w.delayedDraw = time.NewTimer(dt)
@@ -174,7 +250,7 @@ func (w *Window) setNextFrame(at time.Time) {
 	}
 }
 
-func (w *Window) setDriver(d *window) {
+func (w *Window) setDriver(d driver) {
 	w.event(driverEvent{d})
 }
 
@@ -203,10 +279,10 @@ func (w *Window) destroy(err error) {
 	}
 }
 
-func (w *Window) run(opts *WindowOptions) {
+func (w *Window) run(create func() error) {
 	defer close(w.in)
 	defer close(w.out)
-	if err := createWindow(w, opts); err != nil {
+	if err := create(); err != nil {
 		w.out <- DestroyEvent{err}
 		return
 	}
@@ -266,19 +342,21 @@ func (w *Window) run(opts *WindowOptions) {
 						return
 					}
 				} else {
-					ctx, err := newContext(w.driver)
+					ctx, err := w.driver.newContext()
 					if err != nil {
 						w.destroy(err)
 						return
 					}
-					w.gpu, err = gpu.NewGPU(ctx)
-					if err != nil {
-						w.destroy(err)
-						return
+					if ctx != nil {
+						w.gpu, err = gpu.NewGPU(ctx)
+						if err != nil {
+							w.destroy(err)
+							return
+						}
 					}
 				}
 				w.draw(e2.Size, frame)
-				if e2.sync {
+				if e2.sync && w.gpu != nil {
 					if err := w.gpu.Flush(); err != nil {
 						w.gpu.Release()
 						w.gpu = nil
@@ -286,6 +364,7 @@ func (w *Window) run(opts *WindowOptions) {
 						return
 					}
 				}
+				w.driver.submit(frame)
 			case *CommandEvent:
 				w.out <- e
 				w.waitAck()
@@ -294,6 +373,7 @@ func (w *Window) run(opts *WindowOptions) {
 					w.setNextFrame(time.Time{})
 					w.updateAnimation()
 				}
+				w.updateCursor()
 				w.out <- e
 			case driverEvent:
 				w.driver = e2.driver
@@ -308,6 +388,8 @@ func (w *Window) run(opts *WindowOptions) {
 }
 
 func (q *Queue) Events(k input.Key) []input.Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	return q.q.Events(k)
 }
 
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package ui
+
+// CacheOp retains the ops of a subtree across frames. Unlike
+// the recording of a MacroOp, the ops of a CacheOp survive
+// Reset of the Ops it is added to, and as long as its Key and
+// Version stay the same, the subtree is neither recorded again
+// nor changes identity. Renderers use the identity to reuse
+// work from earlier frames, such as the stencils of clip paths.
+//
+// For example, to draw a complex icon:
+//
+//	c := ui.CacheOp{Key: icon, Version: icon.version}
+//	if rec := c.Record(ops); rec != nil {
+//		icon.Layout(rec)
+//	}
+//	c.Add(ops)
+//
+// A retained subtree is released by the first Reset of the
+// Ops after a frame where the subtree was not added.
+type CacheOp struct {
+	// Key identifies the subtree and must be comparable.
+	Key interface{}
+	// Version identifies the contents of the subtree, for
+	// example a counter or a hash of the state it is drawn
+	// from. A new Version records the subtree again.
+	Version int
+
+	entry *cacheEntry
+}
+
+type cacheKey struct {
+	key     interface{}
+	version int
+}
+
+type cacheEntry struct {
+	ops   Ops
+	macro MacroOp
+	// used tracks whether the subtree was added
+	// since the last Reset.
+	used bool
+}
+
+// Record returns the Ops to record the subtree into, or
+// nil if the subtree for the Key and Version is retained
+// from an earlier frame.
+func (c *CacheOp) Record(o *Ops) *Ops {
+	k := cacheKey{key: c.Key, version: c.Version}
+	if e, ok := o.cache[k]; ok {
+		c.entry = e
+		return nil
+	}
+	if o.cache == nil {
+		o.cache = make(map[cacheKey]*cacheEntry)
+	}
+	e := new(cacheEntry)
+	e.ops.checked = o.checked
+	e.macro.Record(&e.ops)
+	o.cache[k] = e
+	c.entry = e
+	return &e.ops
+}
+
+// Add the subtree to o, ending the recording started by
+// Record, if any.
+func (c CacheOp) Add(o *Ops) {
+	e := c.entry
+	if e == nil {
+		return
+	}
+	if e.macro.recording {
+		e.macro.Stop()
+	}
+	e.used = true
+	e.macro.Add(o)
+}
+
+// sweep releases the subtrees that were not added
+// since the previous sweep.
+func (o *Ops) sweep() {
+	for k, e := range o.cache {
+		if !e.used {
+			delete(o.cache, k)
+			continue
+		}
+		e.used = false
+	}
+}
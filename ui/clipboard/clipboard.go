@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package clipboard implements the ops and events for
+reading and writing the system clipboard.
+
+A WriteOp replaces the clipboard content when its frame is
+drawn. A ReadOp requests the clipboard content, which arrives
+later as an Event for the key of the op.
+
+For example, to paste into a handler h:
+
+	clipboard.ReadOp{Key: h}.Add(ops)
+	...
+	for _, e := range queue.Events(h) {
+		if e, ok := e.(clipboard.Event); ok {
+			... // Insert e.Text.
+		}
+	}
+*/
+package clipboard
+
+import (
+	"gioui.org/ui"
+	"gioui.org/ui/input"
+	"gioui.org/ui/internal/ops"
+)
+
+// WriteOp replaces the clipboard content with Text.
+type WriteOp struct {
+	Text string
+}
+
+// ReadOp requests the clipboard content. The content
+// is delivered as an Event to Key.
+type ReadOp struct {
+	Key input.Key
+}
+
+// Event contains the clipboard content requested
+// by a ReadOp.
+type Event struct {
+	Text string
+}
+
+func (op WriteOp) Add(o *ui.Ops) {
+	o.Write(ops.TypeClipboardWrite, op.Text)
+}
+
+func (op *WriteOp) Decode(d []byte, refs []interface{}) {
+	if ops.OpType(d[0]) != ops.TypeClipboardWrite {
+		panic("invalid op")
+	}
+	*op = WriteOp{
+		Text: refs[0].(string),
+	}
+}
+
+func (op ReadOp) Add(o *ui.Ops) {
+	o.Write(ops.TypeClipboardRead, op.Key)
+}
+
+func (op *ReadOp) Decode(d []byte, refs []interface{}) {
+	if ops.OpType(d[0]) != ops.TypeClipboardRead {
+		panic("invalid op")
+	}
+	*op = ReadOp{
+		Key: refs[0].(input.Key),
+	}
+}
+
+func (Event) ImplementsEvent()      {}
+func (Event) ImplementsInputEvent() {}
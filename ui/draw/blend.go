@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package draw
+
+import (
+	"gioui.org/ui"
+	"gioui.org/ui/internal/ops"
+)
+
+// BlendOp sets the blend mode of later DrawOps in the
+// current StackOp. The layer of an OpacityOp is blended
+// with the mode in effect when the OpacityOp was added,
+// and the ops in the layer start out with BlendSrcOver.
+type BlendOp struct {
+	Mode BlendMode
+}
+
+// BlendMode determines how the color of a DrawOp is
+// combined with the color already drawn, the destination.
+// Colors are blended in linear light.
+type BlendMode uint8
+
+const (
+	// BlendSrcOver draws the source over the destination.
+	BlendSrcOver BlendMode = iota
+	// BlendMultiply multiplies the source and
+	// destination colors, darkening the destination.
+	BlendMultiply
+	// BlendScreen multiplies the complements of the
+	// source and destination colors, lightening the
+	// destination.
+	BlendScreen
+	// BlendOverlay multiplies dark destination colors and
+	// screens light destination colors with the source.
+	BlendOverlay
+	// BlendDarken selects the darker of the source
+	// and destination colors.
+	BlendDarken
+	// BlendLighten selects the lighter of the source
+	// and destination colors.
+	BlendLighten
+	// BlendAdd adds the source and destination colors.
+	BlendAdd
+	// BlendClear erases the destination in proportion to
+	// the source alpha. The source color is not used.
+	BlendClear
+)
+
+func (b BlendOp) Add(o *ui.Ops) {
+	data := o.Write(ops.TypeBlend)
+	data[1] = byte(b.Mode)
+}
+
+func (b *BlendOp) Decode(data []byte, refs []interface{}) {
+	if ops.OpType(data[0]) != ops.TypeBlend {
+		panic("invalid op")
+	}
+	*b = BlendOp{
+		Mode: BlendMode(data[1]),
+	}
+}
@@ -27,14 +27,12 @@ type DrawOp struct {
 }
 
 func (i ImageOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypeImageLen)
-	data[0] = byte(ops.TypeImage)
+	data := o.Write(ops.TypeImage, i.Src)
 	bo := binary.LittleEndian
 	bo.PutUint32(data[1:], uint32(i.Rect.Min.X))
 	bo.PutUint32(data[5:], uint32(i.Rect.Min.Y))
 	bo.PutUint32(data[9:], uint32(i.Rect.Max.X))
 	bo.PutUint32(data[13:], uint32(i.Rect.Max.Y))
-	o.Write(data, i.Src)
 }
 
 func (i *ImageOp) Decode(data []byte, refs []interface{}) {
@@ -59,13 +57,11 @@ func (i *ImageOp) Decode(data []byte, refs []interface{}) {
 }
 
 func (c ColorOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypeColorLen)
-	data[0] = byte(ops.TypeColor)
+	data := o.Write(ops.TypeColor)
 	data[1] = c.Color.R
 	data[2] = c.Color.G
 	data[3] = c.Color.B
 	data[4] = c.Color.A
-	o.Write(data)
 }
 
 func (c *ColorOp) Decode(data []byte, refs []interface{}) {
@@ -83,14 +79,12 @@ func (c *ColorOp) Decode(data []byte, refs []interface{}) {
 }
 
 func (d DrawOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypeDrawLen)
-	data[0] = byte(ops.TypeDraw)
+	data := o.Write(ops.TypeDraw)
 	bo := binary.LittleEndian
 	bo.PutUint32(data[1:], math.Float32bits(d.Rect.Min.X))
 	bo.PutUint32(data[5:], math.Float32bits(d.Rect.Min.Y))
 	bo.PutUint32(data[9:], math.Float32bits(d.Rect.Max.X))
 	bo.PutUint32(data[13:], math.Float32bits(d.Rect.Max.Y))
-	o.Write(data)
 }
 
 func (d *DrawOp) Decode(data []byte, refs []interface{}) {
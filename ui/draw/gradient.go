@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package draw
+
+import (
+	"encoding/binary"
+	"image/color"
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/gradient"
+	"gioui.org/ui/internal/ops"
+)
+
+// LinearGradientOp sets the material of later DrawOps to
+// a gradient that varies along the line from Start to End.
+// The gradient is constant along lines perpendicular to it.
+type LinearGradientOp struct {
+	// Start and End are in the coordinate space
+	// of the DrawOps.
+	Start, End f32.Point
+	// Stops are copied into the ops by Add.
+	Stops  []ColorStop
+	Spread Spread
+}
+
+// RadialGradientOp sets the material of later DrawOps to
+// a gradient that varies with the distance from Center.
+type RadialGradientOp struct {
+	// Center is in the coordinate space of the DrawOps.
+	Center f32.Point
+	// Radius is the distance from Center
+	// that corresponds to offset 1.
+	Radius float32
+	// Stops are copied into the ops by Add.
+	Stops  []ColorStop
+	Spread Spread
+}
+
+// ColorStop is the color at an offset of a gradient.
+// Offset 0 is the start of the gradient and offset 1
+// is the end. The colors between stops are
+// interpolated in linear light.
+type ColorStop struct {
+	Offset float32
+	Color  color.RGBA
+}
+
+// Spread determines the gradient color outside the
+// range between offset 0 and 1.
+type Spread uint8
+
+const (
+	// SpreadPad extends the colors at the ends.
+	SpreadPad Spread = iota
+	// SpreadRepeat repeats the gradient.
+	SpreadRepeat
+	// SpreadReflect repeats the gradient,
+	// mirroring every other repetition.
+	SpreadReflect
+)
+
+// Add the op. The stops must be sorted by offset.
+func (g LinearGradientOp) Add(o *ui.Ops) {
+	writeStops(o, g.Stops)
+	data := o.Write(ops.TypeLinearGradient)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(g.Start.X))
+	bo.PutUint32(data[5:], math.Float32bits(g.Start.Y))
+	bo.PutUint32(data[9:], math.Float32bits(g.End.X))
+	bo.PutUint32(data[13:], math.Float32bits(g.End.Y))
+	data[17] = byte(g.Spread)
+}
+
+// Decode the op from its data and the aux data of its
+// stops, if any. Internal use only.
+func (g *LinearGradientOp) Decode(data, stops []byte) {
+	bo := binary.LittleEndian
+	if ops.OpType(data[0]) != ops.TypeLinearGradient {
+		panic("invalid op")
+	}
+	*g = LinearGradientOp{
+		Start: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[1:])),
+			Y: math.Float32frombits(bo.Uint32(data[5:])),
+		},
+		End: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[9:])),
+			Y: math.Float32frombits(bo.Uint32(data[13:])),
+		},
+		Spread: Spread(data[17]),
+		Stops:  decodeStops(stops),
+	}
+}
+
+// Add the op. The stops must be sorted by offset.
+func (g RadialGradientOp) Add(o *ui.Ops) {
+	writeStops(o, g.Stops)
+	data := o.Write(ops.TypeRadialGradient)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(g.Center.X))
+	bo.PutUint32(data[5:], math.Float32bits(g.Center.Y))
+	bo.PutUint32(data[9:], math.Float32bits(g.Radius))
+	data[13] = byte(g.Spread)
+}
+
+// Decode the op from its data and the aux data of its
+// stops, if any. Internal use only.
+func (g *RadialGradientOp) Decode(data, stops []byte) {
+	bo := binary.LittleEndian
+	if ops.OpType(data[0]) != ops.TypeRadialGradient {
+		panic("invalid op")
+	}
+	*g = RadialGradientOp{
+		Center: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[1:])),
+			Y: math.Float32frombits(bo.Uint32(data[5:])),
+		},
+		Radius: math.Float32frombits(bo.Uint32(data[9:])),
+		Spread: Spread(data[13]),
+		Stops:  decodeStops(stops),
+	}
+}
+
+// writeStops adds the stops as aux data for the
+// gradient op that follows.
+func writeStops(o *ui.Ops, stops []ColorStop) {
+	if len(stops) == 0 {
+		return
+	}
+	aux := o.WriteAux(len(stops) * gradient.StopSize)
+	for i, s := range stops {
+		gradient.EncodeStop(aux[i*gradient.StopSize:], s.Offset, s.Color)
+	}
+}
+
+func decodeStops(aux []byte) []ColorStop {
+	if len(aux) == 0 {
+		return nil
+	}
+	stops := make([]ColorStop, len(aux)/gradient.StopSize)
+	for i := range stops {
+		stops[i].Offset, stops[i].Color = gradient.DecodeStop(aux[i*gradient.StopSize:])
+	}
+	return stops
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package draw
+
+import (
+	"encoding/binary"
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/internal/ops"
+)
+
+// OpacityOp draws the rest of the ops in the current
+// StackOp as a group, into an offscreen layer that is then
+// composited with Alpha. Overlapping translucent content
+// in the group is drawn as if it were opaque, unlike setting
+// the alpha of each ColorOp or ImageOp.
+//
+// For example, to fade a subtree:
+//
+//	var stack ui.StackOp
+//	stack.Push(ops)
+//	draw.OpacityOp{Alpha: 0.5}.Add(ops)
+//	... // Draw the subtree.
+//	stack.Pop()
+type OpacityOp struct {
+	// Alpha is the opacity of the layer, between
+	// 0 (transparent) and 1 (opaque).
+	Alpha float32
+}
+
+func (op OpacityOp) Add(o *ui.Ops) {
+	data := o.Write(ops.TypeLayer)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(op.Alpha))
+}
+
+func (op *OpacityOp) Decode(data []byte, refs []interface{}) {
+	bo := binary.LittleEndian
+	if ops.OpType(data[0]) != ops.TypeLayer {
+		panic("invalid op")
+	}
+	alpha := math.Float32frombits(bo.Uint32(data[1:]))
+	if alpha < 0 {
+		alpha = 0
+	}
+	if alpha > 1 {
+		alpha = 1
+	}
+	*op = OpacityOp{
+		Alpha: alpha,
+	}
+}
@@ -21,35 +21,68 @@ type PathBuilder struct {
 	pen       f32.Point
 	bounds    f32.Rectangle
 	hasBounds bool
+	fillRule  FillRule
+
+	// stroke is the stroke style of stroked paths.
+	stroke *Stroke
+	// contours are the flattened contours of a
+	// stroked path.
+	contours [][]strokeVertex
 }
 
 // ClipOp structure must match opClip in package ui/internal/gpu.
 
 type ClipOp struct {
-	bounds f32.Rectangle
+	bounds   f32.Rectangle
+	fillRule FillRule
 }
 
+// FillRule determines which areas enclosed by a
+// path are inside the path.
+type FillRule uint8
+
+const (
+	// NonZero fills the areas that the path
+	// winds around at least once.
+	NonZero FillRule = iota
+	// EvenOdd fills the areas that the path
+	// winds around an odd number of times.
+	EvenOdd
+)
+
 func (p ClipOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypeClipLen)
-	data[0] = byte(ops.TypeClip)
+	data := o.Write(ops.TypeClip)
 	bo := binary.LittleEndian
 	bo.PutUint32(data[1:], math.Float32bits(p.bounds.Min.X))
 	bo.PutUint32(data[5:], math.Float32bits(p.bounds.Min.Y))
 	bo.PutUint32(data[9:], math.Float32bits(p.bounds.Max.X))
 	bo.PutUint32(data[13:], math.Float32bits(p.bounds.Max.Y))
-	o.Write(data)
+	data[17] = byte(p.fillRule)
 }
 
 func (p *PathBuilder) Init(ops *ui.Ops) {
 	p.ops = ops
 }
 
+// SetFillRule sets the fill rule of the path. The default
+// rule is NonZero. Stroked paths are always filled with
+// NonZero.
+func (p *PathBuilder) SetFillRule(r FillRule) {
+	p.fillRule = r
+}
+
 // MoveTo moves the pen to the given position.
 func (p *PathBuilder) Move(to f32.Point) {
+	p.moveTo(to.Add(p.pen))
+}
+
+func (p *PathBuilder) moveTo(to f32.Point) {
 	p.end()
-	to = to.Add(p.pen)
 	p.maxy = to.Y
 	p.pen = to
+	if p.stroke != nil {
+		p.strokeMove(to)
+	}
 }
 
 // end completes the current contour.
@@ -84,6 +117,11 @@ func (p *PathBuilder) Quad(ctrl, to f32.Point) {
 }
 
 func (p *PathBuilder) quadTo(ctrl, to f32.Point) {
+	if p.stroke != nil {
+		p.strokeQuad(ctrl, to)
+		p.pen = to
+		return
+	}
 	// Zero width curves don't contribute to stenciling.
 	if p.pen.X == to.X && p.pen.X == ctrl.X {
 		p.pen = to
@@ -238,21 +276,19 @@ func (p *PathBuilder) vertex(cornerx, cornery int16, ctrl, to f32.Point) {
 		ToX:     to.X,
 		ToY:     to.Y,
 	}
-	data := make([]byte, path.VertStride+1)
-	data[0] = byte(ops.TypeAux)
+	data := p.ops.WriteAux(path.VertStride)
 	bo := binary.LittleEndian
-	data[1] = byte(uint16(v.CornerX))
-	data[2] = byte(uint16(v.CornerX) >> 8)
-	data[3] = byte(uint16(v.CornerY))
-	data[4] = byte(uint16(v.CornerY) >> 8)
-	bo.PutUint32(data[5:], math.Float32bits(v.MaxY))
-	bo.PutUint32(data[9:], math.Float32bits(v.FromX))
-	bo.PutUint32(data[13:], math.Float32bits(v.FromY))
-	bo.PutUint32(data[17:], math.Float32bits(v.CtrlX))
-	bo.PutUint32(data[21:], math.Float32bits(v.CtrlY))
-	bo.PutUint32(data[25:], math.Float32bits(v.ToX))
-	bo.PutUint32(data[29:], math.Float32bits(v.ToY))
-	p.ops.Write(data)
+	data[0] = byte(uint16(v.CornerX))
+	data[1] = byte(uint16(v.CornerX) >> 8)
+	data[2] = byte(uint16(v.CornerY))
+	data[3] = byte(uint16(v.CornerY) >> 8)
+	bo.PutUint32(data[4:], math.Float32bits(v.MaxY))
+	bo.PutUint32(data[8:], math.Float32bits(v.FromX))
+	bo.PutUint32(data[12:], math.Float32bits(v.FromY))
+	bo.PutUint32(data[16:], math.Float32bits(v.CtrlX))
+	bo.PutUint32(data[20:], math.Float32bits(v.CtrlY))
+	bo.PutUint32(data[24:], math.Float32bits(v.ToX))
+	bo.PutUint32(data[28:], math.Float32bits(v.ToY))
 }
 
 func (p *PathBuilder) simpleQuadTo(ctrl, to f32.Point) {
@@ -277,8 +313,16 @@ func (p *PathBuilder) simpleQuadTo(ctrl, to f32.Point) {
 }
 
 func (p *PathBuilder) End() {
+	rule := p.fillRule
+	if p.stroke != nil {
+		// The stroke outline relies on overlapping
+		// contours.
+		rule = NonZero
+		p.strokeOutline()
+	}
 	p.end()
 	ClipOp{
-		bounds: p.bounds,
+		bounds:   p.bounds,
+		fillRule: rule,
 	}.Add(p.ops)
 }
@@ -0,0 +1,369 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package draw
+
+import (
+	"math"
+
+	"gioui.org/ui/f32"
+)
+
+// Stroke describes the outline of a stroked path.
+type Stroke struct {
+	// Width of the stroke.
+	Width float32
+	// Join is the shape of the corners between segments.
+	Join Join
+	// Cap is the shape of the ends of open contours
+	// and dashes.
+	Cap Cap
+	// MiterLimit is the maximum ratio between the length
+	// of a miter and the stroke width. Miter joins that
+	// exceed the limit are beveled. The zero value
+	// means 4.
+	MiterLimit float32
+	// Dashes lists the lengths of alternating dashes
+	// and gaps. An empty list means a solid stroke.
+	Dashes []float32
+	// DashOffset is the distance into the dash pattern
+	// at the start of every contour.
+	DashOffset float32
+}
+
+// Join is the shape of stroke corners.
+type Join uint8
+
+// Cap is the shape of stroke ends.
+type Cap uint8
+
+const (
+	MiterJoin Join = iota
+	RoundJoin
+	BevelJoin
+)
+
+const (
+	ButtCap Cap = iota
+	RoundCap
+	SquareCap
+)
+
+// strokeVertex is a vertex of a flattened contour.
+type strokeVertex struct {
+	p f32.Point
+	// smooth is set for vertices inside flattened
+	// curves. They are always beveled.
+	smooth bool
+}
+
+const (
+	// strokeFlatness is the maximum distance between
+	// a stroked curve and its line approximation.
+	strokeFlatness = 0.05
+	// strokeMaxAngle is the maximum angle in radians
+	// between the lines approximating a stroked curve.
+	strokeMaxAngle = math.Pi / 32
+)
+
+// Stroke makes the path an outline of the stroke s
+// instead of a filled area. Stroke must be called before
+// any path segments are added.
+//
+// Contours that end where they started are closed and
+// have no caps.
+func (p *PathBuilder) Stroke(s Stroke) {
+	p.stroke = &s
+}
+
+// strokeMove starts a new stroked contour.
+func (p *PathBuilder) strokeMove(to f32.Point) {
+	p.contours = append(p.contours, []strokeVertex{{p: to}})
+}
+
+// strokeQuad records the flattened quadratic bezier from
+// the pen to end.
+func (p *PathBuilder) strokeQuad(ctrl, to f32.Point) {
+	if len(p.contours) == 0 {
+		p.strokeMove(p.pen)
+	}
+	from := p.pen
+	// The maximum deviation of a quadratic bezier from n
+	// chords is |from - 2ctrl + to|/(4n²).
+	n := int(math.Ceil(math.Sqrt(float64(length(from.Sub(ctrl.Mul(2)).Add(to)) / (4 * strokeFlatness)))))
+	v0, v1 := ctrl.Sub(from), to.Sub(ctrl)
+	angle := math.Abs(math.Atan2(float64(cross(v0, v1)), float64(dot(v0, v1))))
+	if na := int(math.Ceil(angle / strokeMaxAngle)); na > n {
+		n = na
+	}
+	if n < 1 {
+		n = 1
+	}
+	c := &p.contours[len(p.contours)-1]
+	for i := 1; i < n; i++ {
+		t := float32(i) / float32(n)
+		c0 := from.Mul(1 - t).Add(ctrl.Mul(t))
+		c1 := ctrl.Mul(1 - t).Add(to.Mul(t))
+		*c = appendVertex(*c, strokeVertex{p: c0.Mul(1 - t).Add(c1.Mul(t)), smooth: true})
+	}
+	*c = appendVertex(*c, strokeVertex{p: to})
+}
+
+// strokeOutline replaces the recorded contours with
+// the outline of their stroke.
+func (p *PathBuilder) strokeOutline() {
+	s := p.stroke
+	p.stroke = nil
+	contours := p.contours
+	p.contours = nil
+	if s.Width <= 0 {
+		return
+	}
+	for _, c := range contours {
+		closed := len(c) > 2 && c[0].p == c[len(c)-1].p
+		if len(s.Dashes) == 0 {
+			p.strokeContour(s, c, closed)
+			continue
+		}
+		dashes, closed := s.dash(c, closed)
+		for _, d := range dashes {
+			p.strokeContour(s, d, closed)
+		}
+	}
+}
+
+// dash splits a contour into dashes. It reports whether the
+// contour is closed and remains in one piece.
+func (s *Stroke) dash(c []strokeVertex, closed bool) ([][]strokeVertex, bool) {
+	pattern := s.Dashes
+	if len(pattern)%2 == 1 {
+		// Repeat odd patterns to alternate dashes and gaps.
+		pattern = append(pattern[:len(pattern):len(pattern)], pattern...)
+	}
+	var total float32
+	for _, l := range pattern {
+		if l < 0 {
+			return [][]strokeVertex{c}, closed
+		}
+		total += l
+	}
+	if total <= 0 {
+		return [][]strokeVertex{c}, closed
+	}
+	// Find the position in the pattern at the start.
+	phase := float32(math.Mod(float64(s.DashOffset), float64(total)))
+	if phase < 0 {
+		phase += total
+	}
+	i := 0
+	for phase >= pattern[i] {
+		phase -= pattern[i]
+		i = (i + 1) % len(pattern)
+	}
+	rem := pattern[i] - phase
+	on := i%2 == 0
+	startOn := on
+	var dashes [][]strokeVertex
+	var dash []strokeVertex
+	if on {
+		dash = append(dash, c[0])
+	}
+	cut := false
+	for j := 1; j < len(c); j++ {
+		a, b := c[j-1].p, c[j].p
+		l := length(b.Sub(a))
+		var pos float32
+		for l-pos > rem {
+			pos += rem
+			v := strokeVertex{p: a.Add(b.Sub(a).Mul(pos / l))}
+			if on {
+				dashes = append(dashes, appendVertex(dash, v))
+				dash = nil
+			} else {
+				dash = []strokeVertex{v}
+			}
+			cut = true
+			on = !on
+			i = (i + 1) % len(pattern)
+			rem = pattern[i]
+		}
+		rem -= l - pos
+		if on {
+			dash = appendVertex(dash, c[j])
+		}
+	}
+	if !on {
+		return dashes, false
+	}
+	if !cut {
+		// The contour fits in a single dash.
+		return [][]strokeVertex{dash}, closed
+	}
+	if closed && startOn {
+		// Join the dashes across the start of the contour.
+		first := dashes[0]
+		for _, v := range first[1:] {
+			dash = appendVertex(dash, v)
+		}
+		dashes[0] = dash
+		return dashes, false
+	}
+	return append(dashes, dash), false
+}
+
+// strokeContour outlines a flattened contour with
+// line segments, joins and caps.
+func (p *PathBuilder) strokeContour(s *Stroke, c []strokeVertex, closed bool) {
+	hw := s.Width / 2
+	if len(c) == 1 {
+		// Only caps contribute to zero length contours.
+		pt := c[0].p
+		switch s.Cap {
+		case RoundCap:
+			p.circle(pt, hw)
+		case SquareCap:
+			d := f32.Point{X: hw, Y: hw}
+			p.polygon(pt.Sub(d), f32.Point{X: pt.X + hw, Y: pt.Y - hw}, pt.Add(d), f32.Point{X: pt.X - hw, Y: pt.Y + hw})
+		}
+		return
+	}
+	for i := 1; i < len(c); i++ {
+		a, b := c[i-1].p, c[i].p
+		n := normal(b.Sub(a)).Mul(hw)
+		p.polygon(a.Add(n), b.Add(n), b.Sub(n), a.Sub(n))
+	}
+	for i := 1; i < len(c)-1; i++ {
+		p.join(s, c[i-1].p, c[i], c[i+1].p)
+	}
+	if closed {
+		p.join(s, c[len(c)-2].p, c[0], c[1].p)
+		return
+	}
+	p.cap(s, c[1].p, c[0].p)
+	p.cap(s, c[len(c)-2].p, c[len(c)-1].p)
+}
+
+// join outlines the corner at v between the
+// segments from a and to b.
+func (p *PathBuilder) join(s *Stroke, a f32.Point, v strokeVertex, b f32.Point) {
+	hw := s.Width / 2
+	join := s.Join
+	if v.smooth {
+		join = BevelJoin
+	}
+	if join == RoundJoin {
+		p.circle(v.p, hw)
+		return
+	}
+	n0, n1 := normal(v.p.Sub(a)), normal(b.Sub(v.p))
+	cr := cross(n0, n1)
+	if cr == 0 {
+		// Straight or reversing.
+		return
+	}
+	// The outer side of the corner.
+	side := hw
+	if cr > 0 {
+		side = -hw
+	}
+	p0, p1 := v.p.Add(n0.Mul(side)), v.p.Add(n1.Mul(side))
+	if join == MiterJoin {
+		limit := s.MiterLimit
+		if limit == 0 {
+			limit = 4
+		}
+		// The ratio between miter length and width
+		// is 1/cos(θ/2) for the angle θ between the
+		// normals.
+		cosθ := dot(n0, n1)
+		if cos2 := (1 + cosθ) / 2; cos2*limit*limit >= 1 {
+			m := v.p.Add(n0.Add(n1).Mul(side / (1 + cosθ)))
+			p.polygon(v.p, p0, m, p1)
+			return
+		}
+	}
+	p.polygon(v.p, p0, p1)
+}
+
+// cap outlines the cap at end of the segment from a.
+func (p *PathBuilder) cap(s *Stroke, a, end f32.Point) {
+	hw := s.Width / 2
+	switch s.Cap {
+	case RoundCap:
+		p.circle(end, hw)
+	case SquareCap:
+		d := end.Sub(a)
+		d = d.Mul(hw / length(d))
+		n := normal(d)
+		p.polygon(end.Add(n), end.Add(n).Add(d), end.Sub(n).Add(d), end.Sub(n))
+	}
+}
+
+// polygon adds a closed contour through pts. The stroke
+// outline is the union of many overlapping contours, so all
+// contours are added with the same orientation to ensure that
+// their coverage adds up instead of cancelling out.
+func (p *PathBuilder) polygon(pts ...f32.Point) {
+	var area float32
+	for i, p0 := range pts {
+		p1 := pts[(i+1)%len(pts)]
+		area += cross(p0, p1)
+	}
+	if area == 0 {
+		return
+	}
+	p.moveTo(pts[0])
+	if area > 0 {
+		for _, pt := range pts[1:] {
+			p.lineTo(pt)
+		}
+	} else {
+		for i := len(pts) - 1; i > 0; i-- {
+			p.lineTo(pts[i])
+		}
+	}
+	p.lineTo(pts[0])
+}
+
+// circle adds a circle with the orientation of polygon.
+func (p *PathBuilder) circle(c f32.Point, r float32) {
+	const n = 8
+	const da = 2 * math.Pi / n
+	// Distance to the control points.
+	rc := r / float32(math.Cos(da/2))
+	p.moveTo(c.Add(f32.Point{X: r}))
+	for i := 0; i < n; i++ {
+		a := float64(i) * da
+		sin, cos := math.Sincos(a + da/2)
+		ctrl := c.Add(f32.Point{X: float32(cos), Y: float32(sin)}.Mul(rc))
+		sin, cos = math.Sincos(a + da)
+		to := c.Add(f32.Point{X: float32(cos), Y: float32(sin)}.Mul(r))
+		p.quadTo(ctrl, to)
+	}
+}
+
+// appendVertex appends v to c unless it
+// duplicates the last vertex.
+func appendVertex(c []strokeVertex, v strokeVertex) []strokeVertex {
+	if n := len(c); n > 0 && c[n-1].p == v.p {
+		return c
+	}
+	return append(c, v)
+}
+
+// normal returns the unit normal of v.
+func normal(v f32.Point) f32.Point {
+	l := length(v)
+	return f32.Point{X: -v.Y / l, Y: v.X / l}
+}
+
+func length(v f32.Point) float32 {
+	return float32(math.Sqrt(float64(v.X*v.X + v.Y*v.Y)))
+}
+
+func dot(a, b f32.Point) float32 {
+	return a.X*b.X + a.Y*b.Y
+}
+
+func cross(a, b f32.Point) float32 {
+	return a.X*b.Y - a.Y*b.X
+}
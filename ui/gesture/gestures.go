@@ -10,6 +10,7 @@ import (
 	"gioui.org/ui"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/input"
+	"gioui.org/ui/key"
 	"gioui.org/ui/pointer"
 )
 
@@ -17,6 +18,10 @@ type ClickEvent struct {
 	Type     ClickType
 	Position f32.Point
 	Source   pointer.Source
+	// Buttons are the mouse buttons of the press
+	// that started the click.
+	Buttons   pointer.Buttons
+	Modifiers key.Modifiers
 }
 
 type ClickState uint8
@@ -24,6 +29,16 @@ type ClickType uint8
 
 type Click struct {
 	State ClickState
+	// Buttons is the set of mouse buttons that can
+	// start a click. The zero value means
+	// pointer.ButtonPrimary. Touch presses always
+	// start a click.
+	Buttons pointer.Buttons
+
+	// pressed are the buttons of the press that
+	// started the current click, or zero for a
+	// touch press.
+	pressed pointer.Buttons
 }
 
 type Scroll struct {
@@ -91,9 +106,13 @@ func (c *Click) Events(q input.Queue) []ClickEvent {
 		switch e.Type {
 		case pointer.Release:
 			wasPressed := c.State == StatePressed
+			if wasPressed && c.pressed != 0 && e.Buttons&c.pressed == c.pressed {
+				// Another button was released.
+				break
+			}
 			c.State = StateNormal
 			if wasPressed {
-				events = append(events, ClickEvent{Type: TypeClick, Position: e.Position, Source: e.Source})
+				events = append(events, ClickEvent{Type: TypeClick, Position: e.Position, Source: e.Source, Buttons: c.pressed, Modifiers: e.Modifiers})
 			}
 		case pointer.Cancel:
 			c.State = StateNormal
@@ -101,19 +120,46 @@ func (c *Click) Events(q input.Queue) []ClickEvent {
 			if c.State == StatePressed || !e.Hit {
 				break
 			}
+			btns := e.Buttons
+			if e.Source == pointer.Mouse {
+				if btns == 0 {
+					// Treat mouse presses without buttons
+					// as primary presses.
+					btns = pointer.ButtonPrimary
+				}
+				if btns&c.buttons() == 0 {
+					break
+				}
+			}
 			c.State = StatePressed
-			events = append(events, ClickEvent{Type: TypePress, Position: e.Position, Source: e.Source})
+			c.pressed = btns & c.buttons()
+			events = append(events, ClickEvent{Type: TypePress, Position: e.Position, Source: e.Source, Buttons: c.pressed, Modifiers: e.Modifiers})
 		case pointer.Move:
 			if c.State == StatePressed && !e.Hit {
 				c.State = StateNormal
-			} else if c.State < StateFocused {
+			} else if c.State < StateFocused && e.Hit {
 				c.State = StateFocused
 			}
+		case pointer.Enter:
+			if c.State < StateFocused {
+				c.State = StateFocused
+			}
+		case pointer.Leave:
+			if c.State == StateFocused {
+				c.State = StateNormal
+			}
 		}
 	}
 	return events
 }
 
+func (c *Click) buttons() pointer.Buttons {
+	if c.Buttons == 0 {
+		return pointer.ButtonPrimary
+	}
+	return c.Buttons
+}
+
 func (s *Scroll) Add(ops *ui.Ops) {
 	oph := pointer.HandlerOp{Key: s, Grab: s.grab}
 	oph.Add(ops)
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gesture
+
+import (
+	"testing"
+
+	"gioui.org/ui/input"
+	"gioui.org/ui/key"
+	"gioui.org/ui/pointer"
+)
+
+type queue []input.Event
+
+func (q queue) Events(k input.Key) []input.Event {
+	return q
+}
+
+func TestClickButtons(t *testing.T) {
+	secondary := queue{
+		pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Hit: true, Buttons: pointer.ButtonSecondary},
+		pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Hit: true},
+	}
+	var c Click
+	if evts := c.Events(secondary); len(evts) != 0 {
+		t.Errorf("got %v for a secondary click, want no events", evts)
+	}
+	c.Buttons = pointer.ButtonSecondary
+	evts := c.Events(secondary)
+	if len(evts) != 2 || evts[1].Type != TypeClick || evts[1].Buttons != pointer.ButtonSecondary {
+		t.Errorf("got %v for a secondary click, want press and click", evts)
+	}
+	primary := queue{
+		pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Hit: true, Buttons: pointer.ButtonPrimary, Modifiers: key.ModShift},
+		pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Hit: true, Modifiers: key.ModShift},
+	}
+	c = Click{}
+	evts = c.Events(primary)
+	if len(evts) != 2 || !evts[1].Modifiers.Contain(key.ModShift) {
+		t.Errorf("got %v for a shift click, want press and click with ModShift", evts)
+	}
+	touch := queue{
+		pointer.Event{Type: pointer.Press, Source: pointer.Touch, Hit: true},
+		pointer.Event{Type: pointer.Release, Source: pointer.Touch, Hit: true},
+	}
+	c = Click{Buttons: pointer.ButtonSecondary}
+	if evts := c.Events(touch); len(evts) != 2 {
+		t.Errorf("got %v for a touch click, want press and click", evts)
+	}
+	// Mouse presses without buttons are primary presses.
+	c = Click{}
+	noButtons := queue{
+		pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Hit: true},
+		pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Hit: true},
+	}
+	evts = c.Events(noButtons)
+	if len(evts) != 2 || evts[1].Type != TypeClick || evts[1].Buttons != pointer.ButtonPrimary {
+		t.Errorf("got %v for a click without buttons, want primary press and click", evts)
+	}
+	// Releasing another button doesn't click.
+	c = Click{}
+	chord := queue{
+		pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Hit: true, Buttons: pointer.ButtonPrimary},
+		pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Hit: true, Buttons: pointer.ButtonPrimary | pointer.ButtonSecondary},
+		pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Hit: true, Buttons: pointer.ButtonPrimary},
+	}
+	evts = c.Events(chord)
+	if len(evts) != 1 || c.State != StatePressed {
+		t.Errorf("got %v and state %v after a secondary release, want only a press", evts, c.State)
+	}
+	evts = c.Events(queue{pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Hit: true}})
+	if len(evts) != 1 || evts[0].Type != TypeClick {
+		t.Errorf("got %v for the primary release, want a click", evts)
+	}
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package gradient contains the encoding and geometry of the
+// gradient ops shared by the draw package and the renderers.
+package gradient
+
+import (
+	"encoding/binary"
+	"image/color"
+	"math"
+
+	"gioui.org/ui"
+	"gioui.org/ui/f32"
+)
+
+// StopSize is the size of an encoded color stop. The stops of
+// a gradient op are stored in the aux data before the op.
+const StopSize = 4 + 4
+
+// EncodeStop encodes a color stop into b.
+func EncodeStop(b []byte, offset float32, c color.RGBA) {
+	binary.LittleEndian.PutUint32(b, math.Float32bits(offset))
+	b[4], b[5], b[6], b[7] = c.R, c.G, c.B, c.A
+}
+
+// DecodeStop decodes the color stop encoded in b.
+func DecodeStop(b []byte) (float32, color.RGBA) {
+	offset := math.Float32frombits(binary.LittleEndian.Uint32(b))
+	return offset, color.RGBA{R: b[4], G: b[5], B: b[6], A: b[7]}
+}
+
+// LinearSpace returns the transformation from the coordinate
+// space of a linear gradient from start to end to the
+// gradient offset in the x coordinate.
+func LinearSpace(start, end f32.Point) ui.Transform {
+	d := end.Sub(start)
+	l := float32(math.Hypot(float64(d.X), float64(d.Y)))
+	if l == 0 {
+		// Use the color at the end, like SVG.
+		return ui.Offset(f32.Point{X: 1}).Mul(ui.Scale(f32.Point{}))
+	}
+	angle := float32(math.Atan2(float64(d.Y), float64(d.X)))
+	return ui.Scale(f32.Point{X: 1 / l, Y: 1 / l}).
+		Mul(ui.Rotate(-angle)).
+		Mul(ui.Offset(start.Mul(-1)))
+}
+
+// RadialSpace returns the transformation from the coordinate
+// space of a radial gradient to a space where the gradient
+// offset is the distance from the origin.
+func RadialSpace(center f32.Point, radius float32) ui.Transform {
+	if radius == 0 {
+		// Use the color at the end, like SVG.
+		return ui.Offset(f32.Point{X: 1}).Mul(ui.Scale(f32.Point{}))
+	}
+	r := 1 / radius
+	return ui.Scale(f32.Point{X: r, Y: r}).Mul(ui.Offset(center.Mul(-1)))
+}
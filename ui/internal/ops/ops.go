@@ -24,13 +24,21 @@ const (
 	TypeAux
 	TypeClip
 	TypeProfile
+	TypeLinearGradient
+	TypeRadialGradient
+	TypeBlend
+	TypeSplice
+	TypeSemantic
+	TypeClipboardRead
+	TypeClipboardWrite
+	TypeCursor
 )
 
 const (
 	TypeMacroDefLen       = 1 + 4 + 4
 	TypeMacroLen          = 1 + 4 + 4 + 4
-	TypeTransformLen      = 1 + 4*2
-	TypeLayerLen          = 1
+	TypeTransformLen      = 1 + 4*6
+	TypeLayerLen          = 1 + 4
 	TypeRedrawLen         = 1 + 8
 	TypeImageLen          = 1 + 4*4
 	TypeDrawLen           = 1 + 4*4
@@ -43,37 +51,98 @@ const (
 	TypePushLen           = 1
 	TypePopLen            = 1
 	TypeAuxLen            = 1 + 4
-	TypeClipLen           = 1 + 4*4
+	TypeClipLen           = 1 + 4*4 + 1
 	TypeProfileLen        = 1
+	TypeLinearGradientLen = 1 + 4*4 + 1
+	TypeRadialGradientLen = 1 + 4*3 + 1
+	TypeBlendLen          = 1 + 1
+	TypeSpliceLen         = 1 + 4 + 4 + 4
+	TypeSemanticLen       = 1 + 4*2 + 1 + 1 + 1
+	TypeClipboardReadLen  = 1
+	TypeClipboardWriteLen = 1
+	TypeCursorLen         = 1 + 1
 )
 
 func (t OpType) Size() int {
-	return [...]int{
-		TypeMacroDefLen,
-		TypeMacroLen,
-		TypeTransformLen,
-		TypeLayerLen,
-		TypeRedrawLen,
-		TypeImageLen,
-		TypeDrawLen,
-		TypeColorLen,
-		TypeAreaLen,
-		TypePointerHandlerLen,
-		TypePassLen,
-		TypeKeyHandlerLen,
-		TypeHideInputLen,
-		TypePushLen,
-		TypePopLen,
-		TypeAuxLen,
-		TypeClipLen,
-		TypeProfileLen,
-	}[t-firstOpIndex]
+	return opSizes[t-firstOpIndex]
+}
+
+// Valid reports whether t is a known op type.
+func (t OpType) Valid() bool {
+	return t >= firstOpIndex && int(t-firstOpIndex) < len(opSizes)
+}
+
+var opSizes = [...]int{
+	TypeMacroDefLen,
+	TypeMacroLen,
+	TypeTransformLen,
+	TypeLayerLen,
+	TypeRedrawLen,
+	TypeImageLen,
+	TypeDrawLen,
+	TypeColorLen,
+	TypeAreaLen,
+	TypePointerHandlerLen,
+	TypePassLen,
+	TypeKeyHandlerLen,
+	TypeHideInputLen,
+	TypePushLen,
+	TypePopLen,
+	TypeAuxLen,
+	TypeClipLen,
+	TypeProfileLen,
+	TypeLinearGradientLen,
+	TypeRadialGradientLen,
+	TypeBlendLen,
+	TypeSpliceLen,
+	TypeSemanticLen,
+	TypeClipboardReadLen,
+	TypeClipboardWriteLen,
+	TypeCursorLen,
+}
+
+func (t OpType) String() string {
+	if !t.Valid() {
+		return "invalid"
+	}
+	return opNames[t-firstOpIndex]
+}
+
+var opNames = [...]string{
+	"macrodef",
+	"macro",
+	"transform",
+	"layer",
+	"invalidate",
+	"image",
+	"draw",
+	"color",
+	"area",
+	"pointer",
+	"pass",
+	"key",
+	"hideinput",
+	"push",
+	"pop",
+	"aux",
+	"clip",
+	"profile",
+	"lineargradient",
+	"radialgradient",
+	"blend",
+	"splice",
+	"semantic",
+	"clipboardread",
+	"clipboardwrite",
+	"cursor",
 }
 
 func (t OpType) NumRefs() int {
 	switch t {
-	case TypeMacro, TypeImage, TypeKeyHandler, TypePointerHandler, TypeProfile:
+	case TypeMacro, TypeSplice, TypeImage, TypeKeyHandler, TypePointerHandler, TypeProfile, TypeClipboardRead, TypeClipboardWrite:
 		return 1
+	case TypeSemantic:
+		return 3
 	default:
 		return 0
 	}
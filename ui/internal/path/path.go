@@ -3,7 +3,11 @@
 package path
 
 import (
+	"encoding/binary"
+	"math"
 	"unsafe"
+
+	"gioui.org/ui/f32"
 )
 
 // The vertex data suitable for passing to vertex programs.
@@ -24,3 +28,22 @@ func init() {
 		panic("unexpected struct size")
 	}
 }
+
+// DecodeCurve decodes the curve from the first of the four
+// vertices that make up a curve.
+func DecodeCurve(v []byte) (from, ctrl, to f32.Point) {
+	bo := binary.LittleEndian
+	from = f32.Point{
+		X: math.Float32frombits(bo.Uint32(v[8:])),
+		Y: math.Float32frombits(bo.Uint32(v[12:])),
+	}
+	ctrl = f32.Point{
+		X: math.Float32frombits(bo.Uint32(v[16:])),
+		Y: math.Float32frombits(bo.Uint32(v[20:])),
+	}
+	to = f32.Point{
+		X: math.Float32frombits(bo.Uint32(v[24:])),
+		Y: math.Float32frombits(bo.Uint32(v[28:])),
+	}
+	return
+}
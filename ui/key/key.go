@@ -56,12 +56,10 @@ func (m Modifiers) Contain(m2 Modifiers) bool {
 }
 
 func (h HandlerOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypeKeyHandlerLen)
-	data[0] = byte(ops.TypeKeyHandler)
+	data := o.Write(ops.TypeKeyHandler, h.Key)
 	if h.Focus {
 		data[1] = 1
 	}
-	o.Write(data, h.Key)
 }
 
 func (h *HandlerOp) Decode(d []byte, refs []interface{}) {
@@ -75,9 +73,7 @@ func (h *HandlerOp) Decode(d []byte, refs []interface{}) {
 }
 
 func (h HideInputOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypeHideInputLen)
-	data[0] = byte(ops.TypeHideInput)
-	o.Write(data)
+	o.Write(ops.TypeHideInput)
 }
 
 func (EditEvent) ImplementsEvent()       {}
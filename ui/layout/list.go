@@ -17,6 +17,13 @@ type scrollChild struct {
 	macro ui.MacroOp
 }
 
+// List lays out a scrollable list of elements.
+//
+// A List is not safe for concurrent use, and records
+// into the single Ops given to Init. To lay out the
+// elements of a List concurrently, record each element
+// into its own Ops and Splice it into the Ops given to
+// Init between the calls to Next and Elem.
 type List struct {
 	Axis               Axis
 	Invert             bool
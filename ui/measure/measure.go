@@ -4,6 +4,7 @@ package measure
 
 import (
 	"math"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
@@ -16,7 +17,17 @@ import (
 	"golang.org/x/image/math/fixed"
 )
 
+// Faces caches the text faces of fonts and the layouts and
+// paths of text. Faces is safe for concurrent use, and so are
+// the text.Faces it returns. However, a single mutex serializes
+// the Layout and Path calls of all faces, so laying out text
+// from several goroutines is no faster than from one.
+//
+// Faces must not be copied after first use.
 type Faces struct {
+	// mu protects the caches and the glyph buffers
+	// of the faces.
+	mu          sync.Mutex
 	config      ui.Config
 	faceCache   map[faceKey]*textFace
 	layoutCache map[layoutKey]cachedLayout
@@ -58,6 +69,8 @@ type textFace struct {
 }
 
 func (f *Faces) Reset(c ui.Config) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.config = c
 	f.init()
 	for pk, p := range f.pathCache {
@@ -79,6 +92,8 @@ func (f *Faces) Reset(c ui.Config) {
 }
 
 func (f *Faces) For(fnt *sfnt.Font, size ui.Value) text.Face {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.init()
 	fk := faceKey{fnt, size}
 	if f, exist := f.faceCache[fk]; exist {
@@ -103,6 +118,8 @@ func (f *Faces) init() {
 }
 
 func (f *textFace) Layout(str string, opts text.LayoutOptions) *text.Layout {
+	f.faces.mu.Lock()
+	defer f.faces.mu.Unlock()
 	ppem := fixed.Int26_6(f.faces.config.Px(f.size) * 64)
 	lk := layoutKey{
 		f:    f.font.Font,
@@ -121,6 +138,8 @@ func (f *textFace) Layout(str string, opts text.LayoutOptions) *text.Layout {
 }
 
 func (f *textFace) Path(str text.String) ui.MacroOp {
+	f.faces.mu.Lock()
+	defer f.faces.mu.Unlock()
 	ppem := fixed.Int26_6(f.faces.config.Px(f.size) * 64)
 	pk := pathKey{
 		f:    f.font.Font,
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package measure
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/text"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+// TestFacesConcurrent lays out text from several goroutines, each
+// recording into its own Ops spliced into a parent Ops. Run with
+// -race to detect unsynchronized access.
+func TestFacesConcurrent(t *testing.T) {
+	fnt, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var faces Faces
+	faces.Reset(&config{})
+	const n = 8
+	children := make([]ui.Ops, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := range children {
+		go func(o *ui.Ops, i int) {
+			defer wg.Done()
+			o.SetChecked(true)
+			// Share some faces and strings between goroutines.
+			face := faces.For(fnt, ui.Sp(float32(10+i%2)))
+			for _, s := range []string{"Hello", "Hello, World", "Gio"} {
+				l := face.Layout(s, text.LayoutOptions{MaxWidth: 50})
+				for _, line := range l.Lines {
+					face.Path(line.Text).Add(o)
+				}
+			}
+		}(&children[i], i)
+	}
+	wg.Wait()
+	ops := new(ui.Ops)
+	for i := range children {
+		if err := children[i].Validate(); err != nil {
+			t.Errorf("goroutine %d: %v", i, err)
+		}
+		ops.Splice(&children[i])
+	}
+	if err := ops.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := len(faces.faceCache); n != 2 {
+		t.Errorf("got %d cached faces, expected 2", n)
+	}
+}
+
+type config struct{}
+
+func (config) Now() time.Time {
+	return time.Time{}
+}
+
+func (config) Px(v ui.Value) int {
+	return int(v.V + .5)
+}
@@ -2,11 +2,18 @@ package ui
 
 import (
 	"encoding/binary"
+	"sync/atomic"
 
 	"gioui.org/ui/internal/ops"
 )
 
 // Ops holds a list of serialized Ops.
+//
+// An Ops is not safe for concurrent use. To record ops
+// concurrently, record independent subtrees into separate
+// Ops and add them to a parent Ops with Splice. In checked
+// mode, concurrent writes are detected on a best effort basis
+// and reported by Validate.
 type Ops struct {
 	version int
 	// Serialized ops.
@@ -19,6 +26,18 @@ type Ops struct {
 	inAux  bool
 	auxOff int
 	auxLen int
+
+	// checked reports misuse in err instead
+	// of panicking.
+	checked bool
+	err     *OpError
+	// writing is set during writes in checked mode.
+	writing int32
+	// concurrent is set when a write overlapped another.
+	concurrent int32
+
+	// cache holds the subtrees of CacheOps.
+	cache map[cacheKey]*cacheEntry
 }
 
 // OpsReader parses an ops list. Internal use only.
@@ -43,10 +62,23 @@ type OpKey struct {
 	version int
 }
 
+// MacroOrigin is the location where an expanded
+// macro was recorded. Internal use only.
+type MacroOrigin struct {
+	Ops *Ops
+	// PC is the offset of the macro in the data of Ops,
+	// or 0 for Ops added with Splice.
+	PC int
+	// ret distinguishes repeated expansions.
+	ret pc
+}
+
 type macro struct {
 	ops   *Ops
 	retPC pc
 	endPC pc
+	// origin of the macro ops.
+	origin MacroOrigin
 }
 
 type pc struct {
@@ -75,28 +107,40 @@ type opAux struct {
 	len int
 }
 
+type opSplice struct {
+	ops     *Ops
+	endpc   pc
+	version int
+}
+
 func (s *StackOp) Push(o *Ops) {
 	if s.active {
-		panic("unbalanced push")
+		o.misuse(ops.TypePush, "unbalanced push")
+		return
 	}
 	s.active = true
 	s.ops = o
 	o.stackDepth++
 	s.depth = o.stackDepth
-	o.Write([]byte{byte(ops.TypePush)})
+	o.Write(ops.TypePush)
 }
 
 func (s *StackOp) Pop() {
 	if !s.active {
-		panic("unbalanced pop")
+		if s.ops == nil {
+			panic("unbalanced pop")
+		}
+		s.ops.misuse(ops.TypePop, "unbalanced pop")
+		return
 	}
 	d := s.ops.stackDepth
 	if d != s.depth {
-		panic("unbalanced pop")
+		s.ops.misuse(ops.TypePop, "unbalanced pop")
+		return
 	}
 	s.active = false
 	s.ops.stackDepth--
-	s.ops.Write([]byte{byte(ops.TypePop)})
+	s.ops.Write(ops.TypePop)
 }
 
 func (op *opAux) decode(data []byte) {
@@ -128,6 +172,9 @@ func (op *opMacroDef) decode(data []byte) {
 func (o *Ops) Reset() {
 	o.inAux = false
 	o.stackDepth = 0
+	o.err = nil
+	atomic.StoreInt32(&o.concurrent, 0)
+	o.sweep()
 	// Leave references to the GC.
 	for i := range o.refs {
 		o.refs[i] = nil
@@ -145,37 +192,60 @@ func (o *Ops) Aux() []byte {
 	return o.data[o.auxOff+ops.TypeAuxLen : o.auxOff+ops.TypeAuxLen+o.auxLen]
 }
 
-func (d *Ops) write(op []byte, refs ...interface{}) {
-	d.data = append(d.data, op...)
+func (d *Ops) write(n int, refs ...interface{}) []byte {
+	if d.checked {
+		if !atomic.CompareAndSwapInt32(&d.writing, 0, 1) {
+			atomic.StoreInt32(&d.concurrent, 1)
+		} else {
+			defer atomic.StoreInt32(&d.writing, 0)
+		}
+	}
+	// The append of make is optimized to not allocate.
+	d.data = append(d.data, make([]byte, n)...)
 	d.refs = append(d.refs, refs...)
+	return d.data[len(d.data)-n:]
 }
 
-func (o *Ops) Write(op []byte, refs ...interface{}) {
-	t := ops.OpType(op[0])
+// Write reserves room for an op of type t that refers to refs, and
+// returns the reserved bytes for the caller to encode the op into.
+// The first byte is set to t. The bytes are only valid until the
+// next write. Internal use only.
+func (o *Ops) Write(t ops.OpType, refs ...interface{}) []byte {
 	if len(refs) != t.NumRefs() {
-		panic("invalid ref count")
+		o.misuse(t, "invalid ref count")
+		// Drop the op.
+		data := make([]byte, t.Size())
+		data[0] = byte(t)
+		return data
 	}
-	switch t {
-	case ops.TypeAux:
-		// Write only the data.
-		op = op[1:]
-		if !o.inAux {
-			o.inAux = true
-			o.auxOff = o.pc().data
-			o.auxLen = 0
-			header := make([]byte, ops.TypeAuxLen)
-			header[0] = byte(ops.TypeAux)
-			o.write(header)
-		}
-		o.auxLen += len(op)
-	default:
-		if o.inAux {
-			o.inAux = false
-			bo := binary.LittleEndian
-			bo.PutUint32(o.data[o.auxOff+1:], uint32(o.auxLen))
-		}
+	o.endAux()
+	data := o.write(t.Size(), refs...)
+	data[0] = byte(t)
+	return data
+}
+
+// endAux terminates the current aux block, if any.
+func (o *Ops) endAux() {
+	if !o.inAux {
+		return
+	}
+	o.inAux = false
+	bo := binary.LittleEndian
+	bo.PutUint32(o.data[o.auxOff+1:], uint32(o.auxLen))
+}
+
+// WriteAux reserves n bytes of aux data. Consecutive aux data
+// is merged into a single aux op. Internal use only.
+func (o *Ops) WriteAux(n int) []byte {
+	if !o.inAux {
+		o.inAux = true
+		o.auxOff = o.pc().data
+		o.auxLen = 0
+		header := o.write(ops.TypeAuxLen)
+		header[0] = byte(ops.TypeAux)
 	}
-	o.write(op, refs...)
+	o.auxLen += n
+	return o.write(n)
 }
 
 func (d *Ops) pc() pc {
@@ -185,19 +255,26 @@ func (d *Ops) pc() pc {
 // Record a macro of operations.
 func (m *MacroOp) Record(o *Ops) {
 	if m.recording {
-		panic("already recording")
+		o.misuse(ops.TypeMacroDef, "already recording")
+		return
 	}
 	m.recording = true
 	m.ops = o
 	m.pc = o.pc()
 	// Make room for a macro definition. Filled out in Stop.
-	m.ops.Write(make([]byte, ops.TypeMacroDefLen))
+	// The zeroed op type marks the macro as unterminated.
+	m.ops.endAux()
+	m.ops.write(ops.TypeMacroDefLen)
 }
 
 // Stop recording the macro.
 func (m *MacroOp) Stop() {
 	if !m.recording {
-		panic("not recording")
+		if m.ops == nil {
+			panic("not recording")
+		}
+		m.ops.misuse(ops.TypeMacroDef, "not recording")
+		return
 	}
 	m.recording = false
 	pc := m.ops.pc()
@@ -230,18 +307,64 @@ func (m *MacroOp) decode(data []byte, refs []interface{}) {
 
 func (m MacroOp) Add(o *Ops) {
 	if m.recording {
-		panic("a recording is in progress")
+		o.misuse(ops.TypeMacro, "a recording is in progress")
+		return
 	}
 	if m.ops == nil {
 		return
 	}
-	data := make([]byte, ops.TypeMacroLen)
-	data[0] = byte(ops.TypeMacro)
+	data := o.Write(ops.TypeMacro, m.ops)
 	bo := binary.LittleEndian
 	bo.PutUint32(data[1:], uint32(m.pc.data))
 	bo.PutUint32(data[5:], uint32(m.pc.refs))
 	bo.PutUint32(data[9:], uint32(m.version))
-	o.Write(data, m.ops)
+}
+
+// Splice adds the ops recorded so far in child to o, in
+// place of recording them in o directly. The ops are not
+// copied, so references and macros in child remain valid,
+// but child must not be Reset while o is in use.
+//
+// For example, to lay out two subtrees concurrently:
+//
+//	var left, right ui.Ops
+//	var wg sync.WaitGroup
+//	wg.Add(2)
+//	go func() { layoutLeft(&left); wg.Done() }()
+//	go func() { layoutRight(&right); wg.Done() }()
+//	wg.Wait()
+//	ops.Splice(&left)
+//	ops.Splice(&right)
+func (o *Ops) Splice(child *Ops) {
+	if child.stackDepth != 0 {
+		o.misuse(ops.TypeSplice, "splice of unbalanced push")
+		return
+	}
+	child.endAux()
+	end := child.pc()
+	data := o.Write(ops.TypeSplice, child)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], uint32(end.data))
+	bo.PutUint32(data[5:], uint32(end.refs))
+	bo.PutUint32(data[9:], uint32(child.version))
+}
+
+func (op *opSplice) decode(data []byte, refs []interface{}) {
+	if ops.OpType(data[0]) != ops.TypeSplice {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	dataIdx := int(bo.Uint32(data[1:]))
+	refsIdx := int(bo.Uint32(data[5:]))
+	version := int(bo.Uint32(data[9:]))
+	*op = opSplice{
+		ops: refs[0].(*Ops),
+		endpc: pc{
+			data: dataIdx,
+			refs: refsIdx,
+		},
+		version: version,
+	}
 }
 
 // Reset start reading from the op list.
@@ -255,6 +378,15 @@ func (r *OpsReader) Reset(ops *Ops) {
 	r.ops = ops
 }
 
+// Macros appends the origins of the macros that contain the
+// most recently decoded op to origins, outermost first.
+func (r *OpsReader) Macros(origins []MacroOrigin) []MacroOrigin {
+	for _, m := range r.stack {
+		origins = append(origins, m.origin)
+	}
+	return origins
+}
+
 func (r *OpsReader) Decode() (EncodedOp, bool) {
 	if r.ops == nil {
 		return EncodedOp{}, false
@@ -303,12 +435,38 @@ func (r *OpsReader) Decode() (EncodedOp, bool) {
 				ops:   r.ops,
 				retPC: retPC,
 				endPC: opDef.endpc,
+				origin: MacroOrigin{
+					Ops: macroOps,
+					PC:  op.pc.data,
+					ret: retPC,
+				},
 			})
 			r.ops = macroOps
 			r.pc = op.pc
 			r.pc.data += ops.TypeMacroDef.Size()
 			r.pc.refs += ops.TypeMacroDef.NumRefs()
 			continue
+		case ops.TypeSplice:
+			var op opSplice
+			op.decode(data, refs)
+			if op.version != op.ops.version {
+				panic("invalid splice of reset Ops")
+			}
+			retPC := r.pc
+			retPC.data += n
+			retPC.refs += nrefs
+			r.stack = append(r.stack, macro{
+				ops:   r.ops,
+				retPC: retPC,
+				endPC: op.endpc,
+				origin: MacroOrigin{
+					Ops: op.ops,
+					ret: retPC,
+				},
+			})
+			r.ops = op.ops
+			r.pc = pc{}
+			continue
 		case ops.TypeMacroDef:
 			var op opMacroDef
 			op.decode(data)
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package ui_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/key"
+	"gioui.org/ui/pointer"
+)
+
+func TestOpsAllocs(t *testing.T) {
+	o := new(ui.Ops)
+	h := new(int)
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	allocs := testing.AllocsPerRun(10, func() {
+		o.Reset()
+		frame(o, h, src)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations per frame, expected 0", allocs)
+	}
+}
+
+func BenchmarkOps(b *testing.B) {
+	o := new(ui.Ops)
+	h := new(int)
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		o.Reset()
+		frame(o, h, src)
+	}
+}
+
+func TestGradientAllocs(t *testing.T) {
+	o := new(ui.Ops)
+	stops := []draw.ColorStop{
+		{Offset: 0, Color: color.RGBA{R: 0xff, A: 0xff}},
+		{Offset: 1, Color: color.RGBA{B: 0xff, A: 0xff}},
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		o.Reset()
+		for i := 0; i < 1000; i++ {
+			draw.LinearGradientOp{End: f32.Point{X: 10}, Stops: stops}.Add(o)
+			draw.RadialGradientOp{Radius: 10, Stops: stops}.Add(o)
+			draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(o)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations per frame, expected 0", allocs)
+	}
+}
+
+func frame(o *ui.Ops, h *int, src image.Image) {
+	for i := 0; i < 1000; i++ {
+		var stack ui.StackOp
+		stack.Push(o)
+		ui.TransformOp{Transform: ui.Offset(f32.Point{X: float32(i)})}.Add(o)
+		pointer.RectAreaOp{Size: image.Point{X: 10, Y: 10}}.Add(o)
+		pointer.HandlerOp{Key: h}.Add(o)
+		key.HandlerOp{Key: h}.Add(o)
+		draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(o)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(o)
+		draw.ImageOp{Src: src, Rect: src.Bounds()}.Add(o)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(o)
+		var p draw.PathBuilder
+		p.Init(o)
+		p.Line(f32.Point{X: 10, Y: 10})
+		p.Line(f32.Point{X: -10})
+		p.End()
+		stack.Pop()
+	}
+	ui.InvalidateOp{At: time.Unix(1, 0)}.Add(o)
+}
+
+func TestCacheOp(t *testing.T) {
+	o := new(ui.Ops)
+	key := new(int)
+	// cached adds the subtree for version and reports
+	// whether it was retained, and the key of its op.
+	cached := func(version int) (bool, ui.OpKey) {
+		c := ui.CacheOp{Key: key, Version: version}
+		rec := c.Record(o)
+		if rec != nil {
+			draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(rec)
+		}
+		c.Add(o)
+		var r ui.OpsReader
+		r.Reset(o)
+		op, ok := r.Decode()
+		if !ok {
+			t.Fatal("missing cached op")
+		}
+		return rec == nil, op.Key
+	}
+	if hit, _ := cached(1); hit {
+		t.Error("first frame: unexpected cache hit")
+	}
+	o.Reset()
+	hit, k1 := cached(1)
+	if !hit {
+		t.Error("unchanged version: expected cache hit")
+	}
+	o.Reset()
+	if hit, k2 := cached(1); !hit || k1 != k2 {
+		t.Error("unchanged version: expected the same op")
+	}
+	o.Reset()
+	if hit, _ := cached(2); hit {
+		t.Error("new version: unexpected cache hit")
+	}
+	o.Reset()
+	o.Reset()
+	if hit, _ := cached(2); hit {
+		t.Error("unused subtree: expected release")
+	}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSplice(t *testing.T) {
+	children := make([]ui.Ops, 4)
+	done := make(chan struct{})
+	for i := range children {
+		go func(o *ui.Ops, i int) {
+			// Record the color through a macro to
+			// exercise references between Ops.
+			var m ui.MacroOp
+			m.Record(o)
+			draw.ColorOp{Color: color.RGBA{R: uint8(i), A: 0xff}}.Add(o)
+			m.Stop()
+			m.Add(o)
+			done <- struct{}{}
+		}(&children[i], i)
+	}
+	for range children {
+		<-done
+	}
+	o := new(ui.Ops)
+	for i := range children {
+		o.Splice(&children[i])
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var r ui.OpsReader
+	r.Reset(o)
+	n := 0
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		var op draw.ColorOp
+		op.Decode(encOp.Data, encOp.Refs)
+		if got := int(op.Color.R); got != n {
+			t.Errorf("op %d: got color from child %d", n, got)
+		}
+		n++
+	}
+	if n != len(children) {
+		t.Errorf("got %d ops, expected %d", n, len(children))
+	}
+}
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package opsfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
+	gdraw "gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/input"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/key"
+	"gioui.org/ui/pointer"
+	"gioui.org/ui/semantic"
+	"gioui.org/ui/system"
+)
+
+type dumper struct {
+	w      *bufio.Writer
+	indent int
+	// t is the transformation at the current op.
+	t      ui.Transform
+	stack  []ui.Transform
+	macros []ui.MacroOrigin
+	// opsIDs and keys number Ops and handler keys
+	// in the order they appear.
+	opsIDs map[*ui.Ops]int
+	keys   map[input.Key]int
+	counts [256]int
+	sizes  [256]int
+	// aux is the data of the preceding aux op, if any.
+	aux []byte
+}
+
+var fillRules = [...]string{
+	gdraw.NonZero: "nonzero",
+	gdraw.EvenOdd: "evenodd",
+}
+
+var blendModes = [...]string{
+	gdraw.BlendSrcOver:  "srcover",
+	gdraw.BlendMultiply: "multiply",
+	gdraw.BlendScreen:   "screen",
+	gdraw.BlendOverlay:  "overlay",
+	gdraw.BlendDarken:   "darken",
+	gdraw.BlendLighten:  "lighten",
+	gdraw.BlendAdd:      "add",
+	gdraw.BlendClear:    "clear",
+}
+
+var spreads = [...]string{
+	gdraw.SpreadPad:     "pad",
+	gdraw.SpreadRepeat:  "repeat",
+	gdraw.SpreadReflect: "reflect",
+}
+
+var areaKinds = [...]string{"rect", "ellipse", "path"}
+
+// Dump writes a description of the ops of root to w. Ops are
+// listed one per line and indented by the stack and macro
+// nesting, followed by a summary of the number of ops and
+// their size in bytes for each op type.
+func Dump(w io.Writer, root *ui.Ops) error {
+	d := &dumper{
+		w:      bufio.NewWriter(w),
+		opsIDs: map[*ui.Ops]int{root: 0},
+		keys:   make(map[input.Key]int),
+	}
+	var r ui.OpsReader
+	r.Reset(root)
+	var macros []ui.MacroOrigin
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		macros = r.Macros(macros[:0])
+		d.enterMacros(macros)
+		d.op(encOp)
+	}
+	d.enterMacros(nil)
+	d.summary()
+	return d.w.Flush()
+}
+
+// enterMacros prints the start and end of macros
+// to match the macros of the next op.
+func (d *dumper) enterMacros(macros []ui.MacroOrigin) {
+	n := 0
+	for n < len(d.macros) && n < len(macros) && d.macros[n] == macros[n] {
+		n++
+	}
+	for len(d.macros) > n {
+		d.macros = d.macros[:len(d.macros)-1]
+		d.indent--
+		d.printf("end macro")
+	}
+	for _, m := range macros[n:] {
+		id, ok := d.opsIDs[m.Ops]
+		if !ok {
+			id = len(d.opsIDs)
+			d.opsIDs[m.Ops] = id
+		}
+		d.printf("macro recorded in ops %d at %d", id, m.PC)
+		d.macros = append(d.macros, m)
+		d.indent++
+	}
+}
+
+func (d *dumper) op(encOp ui.EncodedOp) {
+	t := ops.OpType(encOp.Data[0])
+	d.counts[t]++
+	d.sizes[t] += len(encOp.Data)
+	aux := d.aux
+	d.aux = nil
+	switch t {
+	case ops.TypePush:
+		d.printf("push")
+		d.stack = append(d.stack, d.t)
+		d.indent++
+	case ops.TypePop:
+		if n := len(d.stack); n > 0 {
+			d.t = d.stack[n-1]
+			d.stack = d.stack[:n-1]
+			d.indent--
+		}
+		d.printf("pop")
+	case ops.TypeTransform:
+		var op ui.TransformOp
+		op.Decode(encOp.Data)
+		d.t = d.t.Mul(op.Transform)
+		d.printf("transform %s, total %s", transformString(op.Transform), transformString(d.t))
+	case ops.TypeAux:
+		d.aux = encOp.Data[ops.TypeAuxLen:]
+		d.printf("aux %d bytes", len(d.aux))
+	case ops.TypeClip:
+		bo := binary.LittleEndian
+		data := encOp.Data
+		r := f32.Rectangle{
+			Min: f32.Point{
+				X: math.Float32frombits(bo.Uint32(data[1:])),
+				Y: math.Float32frombits(bo.Uint32(data[5:])),
+			},
+			Max: f32.Point{
+				X: math.Float32frombits(bo.Uint32(data[9:])),
+				Y: math.Float32frombits(bo.Uint32(data[13:])),
+			},
+		}
+		d.printf("clip %s %s", rectString(r), name(fillRules[:], data[17]))
+	case ops.TypeColor:
+		var op gdraw.ColorOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("color %s", colorString(op.Color))
+	case ops.TypeImage:
+		var op gdraw.ImageOp
+		op.Decode(encOp.Data, encOp.Refs)
+		sz := op.Src.Bounds().Size()
+		d.printf("image %dx%d, rect %v", sz.X, sz.Y, op.Rect)
+	case ops.TypeLinearGradient:
+		var op gdraw.LinearGradientOp
+		op.Decode(encOp.Data, aux)
+		d.printf("linear gradient %s-%s, %s, %s", pointString(op.Start), pointString(op.End), name(spreads[:], byte(op.Spread)), stopsString(op.Stops))
+	case ops.TypeRadialGradient:
+		var op gdraw.RadialGradientOp
+		op.Decode(encOp.Data, aux)
+		d.printf("radial gradient %s r %g, %s, %s", pointString(op.Center), op.Radius, name(spreads[:], byte(op.Spread)), stopsString(op.Stops))
+	case ops.TypeDraw:
+		var op gdraw.DrawOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("draw %s", rectString(op.Rect))
+	case ops.TypeLayer:
+		var op gdraw.OpacityOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("opacity %g", op.Alpha)
+	case ops.TypeBlend:
+		var op gdraw.BlendOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("blend %s", name(blendModes[:], byte(op.Mode)))
+	case ops.TypeArea:
+		bo := binary.LittleEndian
+		data := encOp.Data
+		d.printf("area %s %dx%d", name(areaKinds[:], data[1]), bo.Uint32(data[2:]), bo.Uint32(data[6:]))
+	case ops.TypePointerHandler:
+		var op pointer.HandlerOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("pointer handler %d, grab %v", d.key(op.Key), op.Grab)
+	case ops.TypeCursor:
+		var op pointer.CursorOp
+		op.Decode(encOp.Data)
+		d.printf("cursor %v", op.Cursor)
+	case ops.TypePass:
+		var op pointer.PassOp
+		op.Decode(encOp.Data)
+		d.printf("pass %v", op.Pass)
+	case ops.TypeKeyHandler:
+		var op key.HandlerOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("key handler %d, focus %v", d.key(op.Key), op.Focus)
+	case ops.TypeProfile:
+		var op system.ProfileOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("profile %d", d.key(op.Key))
+	case ops.TypeSemantic:
+		var op semantic.NodeOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("semantic %s %q %dx%d", op.Role, op.Label, op.Size.X, op.Size.Y)
+	case ops.TypeClipboardRead:
+		var op clipboard.ReadOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("clipboard read %d", d.key(op.Key))
+	case ops.TypeClipboardWrite:
+		var op clipboard.WriteOp
+		op.Decode(encOp.Data, encOp.Refs)
+		d.printf("clipboard write %q", op.Text)
+	case ops.TypeInvalidate:
+		var op ui.InvalidateOp
+		op.Decode(encOp.Data)
+		if op.At.IsZero() {
+			d.printf("invalidate")
+		} else {
+			d.printf("invalidate at %v", op.At)
+		}
+	default:
+		d.printf("%v", t)
+	}
+}
+
+// summary prints the number of ops and their
+// size for every op type.
+func (d *dumper) summary() {
+	d.printf("")
+	d.printf("%-16s %8s %8s", "type", "count", "bytes")
+	var count, size int
+	for i, n := range d.counts {
+		if n == 0 {
+			continue
+		}
+		d.printf("%-16v %8d %8d", ops.OpType(i), n, d.sizes[i])
+		count += n
+		size += d.sizes[i]
+	}
+	d.printf("%-16s %8d %8d", "total", count, size)
+}
+
+// key returns the number of the handler key k.
+func (d *dumper) key(k input.Key) int {
+	id, ok := d.keys[k]
+	if !ok {
+		id = len(d.keys)
+		d.keys[k] = id
+	}
+	return id
+}
+
+func (d *dumper) printf(format string, args ...interface{}) {
+	if d.indent > 0 {
+		d.w.WriteString(strings.Repeat("  ", d.indent))
+	}
+	fmt.Fprintf(d.w, format, args...)
+	d.w.WriteByte('\n')
+}
+
+func name(names []string, v byte) string {
+	if int(v) < len(names) {
+		return names[v]
+	}
+	return fmt.Sprintf("unknown(%d)", v)
+}
+
+func transformString(t ui.Transform) string {
+	sx, hx, ox, hy, sy, oy := t.Elems()
+	off := pointString(f32.Point{X: ox, Y: oy})
+	if sx == 1 && hx == 0 && hy == 0 && sy == 1 {
+		return "offset " + off
+	}
+	return fmt.Sprintf("[%g %g %g %g] offset %s", sx, hx, hy, sy, off)
+}
+
+func pointString(p f32.Point) string {
+	return fmt.Sprintf("(%g,%g)", p.X, p.Y)
+}
+
+func rectString(r f32.Rectangle) string {
+	return pointString(r.Min) + "-" + pointString(r.Max)
+}
+
+func colorString(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+func stopsString(stops []gdraw.ColorStop) string {
+	var b strings.Builder
+	b.WriteString("stops")
+	for _, s := range stops {
+		fmt.Fprintf(&b, " %g:%s", s.Offset, colorString(s.Color))
+	}
+	return b.String()
+}
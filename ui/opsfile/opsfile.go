@@ -0,0 +1,327 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package opsfile encodes operation lists into self-contained
+files and decodes them back, for attaching frames to bug reports,
+rendering frames offline and collecting regression test corpora.
+
+The file holds the ops of a frame together with the values
+they refer to. MacroOps and spliced Ops are flattened, images
+are stored by value and the keys of input handlers are replaced
+by Keys numbered in the order they appear.
+
+The encoding of ops is versioned, and Decode rejects files
+from other versions.
+
+Dump prints a readable description of ops for debugging.
+The gio tool runs Dump on files with the ops subcommand.
+*/
+package opsfile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"reflect"
+
+	"gioui.org/ui"
+	"gioui.org/ui/input"
+	"gioui.org/ui/internal/ops"
+)
+
+// Key replaces the handler keys of encoded ops.
+type Key int
+
+// Version is the version of the file format. It changes
+// whenever the encoding of ops changes.
+const Version = 2
+
+const magic = "gioops"
+
+const (
+	imageUniform byte = iota
+	imageRGBA
+)
+
+const (
+	// maxPixels limits the size of decoded images.
+	maxPixels = 1 << 26
+	// maxString limits the length of decoded strings.
+	maxString = 1 << 20
+	// maxAux limits the length of decoded aux data.
+	maxAux = 1 << 26
+)
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+	// images maps images to their index in the
+	// file, for images that occur more than once.
+	images map[interface{}]int
+	nimg   int
+	keys   map[input.Key]int
+}
+
+type decoder struct {
+	r      *bufio.Reader
+	images []image.Image
+}
+
+// Encode writes the ops of root to w.
+func Encode(w io.Writer, root *ui.Ops) error {
+	e := &encoder{
+		w:      bufio.NewWriter(w),
+		images: make(map[interface{}]int),
+		keys:   make(map[input.Key]int),
+	}
+	e.write([]byte(magic))
+	e.uvarint(Version)
+	var r ui.OpsReader
+	r.Reset(root)
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		if err := e.op(encOp); err != nil {
+			return err
+		}
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+// op writes an op and its refs. Aux ops are
+// written with their length.
+func (e *encoder) op(op ui.EncodedOp) error {
+	t := ops.OpType(op.Data[0])
+	e.write(op.Data)
+	for _, ref := range op.Refs {
+		switch t {
+		case ops.TypeImage:
+			e.image(ref.(image.Image))
+		case ops.TypeKeyHandler, ops.TypePointerHandler, ops.TypeProfile, ops.TypeClipboardRead:
+			id, ok := e.keys[ref]
+			if !ok {
+				id = len(e.keys)
+				e.keys[ref] = id
+			}
+			e.uvarint(uint64(id))
+		case ops.TypeSemantic, ops.TypeClipboardWrite:
+			str := ref.(string)
+			e.uvarint(uint64(len(str)))
+			e.write([]byte(str))
+		default:
+			return fmt.Errorf("opsfile: unsupported reference %T in op %d", ref, t)
+		}
+	}
+	return nil
+}
+
+// image writes a reference to img, followed by the image
+// itself the first time it is written.
+func (e *encoder) image(img image.Image) {
+	if reflect.TypeOf(img).Comparable() {
+		if idx, ok := e.images[img]; ok {
+			e.uvarint(uint64(idx))
+			return
+		}
+		e.images[img] = e.nimg
+	}
+	e.uvarint(uint64(e.nimg))
+	e.nimg++
+	if u, ok := img.(*image.Uniform); ok {
+		e.write([]byte{imageUniform})
+		c := color.RGBA64Model.Convert(u.C).(color.RGBA64)
+		for _, v := range []uint16{c.R, c.G, c.B, c.A} {
+			e.write([]byte{byte(v), byte(v >> 8)})
+		}
+		return
+	}
+	e.write([]byte{imageRGBA})
+	b := img.Bounds()
+	for _, v := range []int{b.Min.X, b.Min.Y, b.Max.X, b.Max.Y} {
+		e.uint32(uint32(v))
+	}
+	rgba, ok := img.(*image.RGBA)
+	if !ok || rgba.Stride != 4*b.Dx() {
+		rgba = image.NewRGBA(b)
+		draw.Draw(rgba, b, img, b.Min, draw.Src)
+	}
+	e.write(rgba.Pix[:4*b.Dx()*b.Dy()])
+}
+
+func (e *encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.Write(b)
+}
+
+func (e *encoder) uvarint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	e.write(buf[:n])
+}
+
+func (e *encoder) uint32(v uint32) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	e.write(buf[:])
+}
+
+// Decode reads ops written by Encode.
+func Decode(r io.Reader) (*ui.Ops, error) {
+	d := &decoder{r: bufio.NewReader(r)}
+	o := new(ui.Ops)
+	if err := d.decode(o); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+func (d *decoder) decode(o *ui.Ops) error {
+	m := make([]byte, len(magic))
+	if _, err := io.ReadFull(d.r, m); err != nil {
+		return err
+	}
+	if string(m) != magic {
+		return errors.New("opsfile: not an ops file")
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return err
+	}
+	if v != Version {
+		return fmt.Errorf("opsfile: unsupported version %d", v)
+	}
+	for {
+		b, err := d.r.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := d.op(o, ops.OpType(b)); err != nil {
+			return err
+		}
+	}
+}
+
+// op reads an op of type t and adds it to o.
+func (d *decoder) op(o *ui.Ops, t ops.OpType) error {
+	if !t.Valid() || t == ops.TypeMacro || t == ops.TypeMacroDef || t == ops.TypeSplice {
+		return fmt.Errorf("opsfile: invalid op type %d", t)
+	}
+	data := make([]byte, t.Size())
+	data[0] = byte(t)
+	if _, err := io.ReadFull(d.r, data[1:]); err != nil {
+		return err
+	}
+	if t == ops.TypeAux {
+		n := binary.LittleEndian.Uint32(data[1:])
+		if n > maxAux {
+			return fmt.Errorf("opsfile: invalid aux length %d", n)
+		}
+		// WriteAux adds the aux header.
+		_, err := io.ReadFull(d.r, o.WriteAux(int(n)))
+		return err
+	}
+	var refs []interface{}
+	for i := 0; i < t.NumRefs(); i++ {
+		var ref interface{}
+		var err error
+		switch t {
+		case ops.TypeImage:
+			ref, err = d.image()
+		case ops.TypeKeyHandler, ops.TypePointerHandler, ops.TypeProfile, ops.TypeClipboardRead:
+			var id uint64
+			id, err = binary.ReadUvarint(d.r)
+			ref = Key(id)
+		case ops.TypeSemantic, ops.TypeClipboardWrite:
+			ref, err = d.string()
+		}
+		if err != nil {
+			return err
+		}
+		refs = append(refs, ref)
+	}
+	copy(o.Write(t, refs...), data)
+	return nil
+}
+
+func (d *decoder) image() (image.Image, error) {
+	idx, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return nil, err
+	}
+	if idx < uint64(len(d.images)) {
+		return d.images[idx], nil
+	}
+	if idx != uint64(len(d.images)) {
+		return nil, fmt.Errorf("opsfile: invalid image index %d", idx)
+	}
+	kind, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var img image.Image
+	switch kind {
+	case imageUniform:
+		var buf [8]byte
+		if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+			return nil, err
+		}
+		bo := binary.LittleEndian
+		img = image.NewUniform(color.RGBA64{
+			R: bo.Uint16(buf[0:]),
+			G: bo.Uint16(buf[2:]),
+			B: bo.Uint16(buf[4:]),
+			A: bo.Uint16(buf[6:]),
+		})
+	case imageRGBA:
+		var buf [16]byte
+		if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+			return nil, err
+		}
+		bo := binary.LittleEndian
+		b := image.Rectangle{
+			Min: image.Point{X: int(int32(bo.Uint32(buf[0:]))), Y: int(int32(bo.Uint32(buf[4:])))},
+			Max: image.Point{X: int(int32(bo.Uint32(buf[8:]))), Y: int(int32(bo.Uint32(buf[12:])))},
+		}
+		if w, h := b.Dx(), b.Dy(); w < 0 || h < 0 || int64(w)*int64(h) > maxPixels {
+			return nil, fmt.Errorf("opsfile: invalid image bounds %v", b)
+		}
+		rgba := image.NewRGBA(b)
+		if _, err := io.ReadFull(d.r, rgba.Pix); err != nil {
+			return nil, err
+		}
+		img = rgba
+	default:
+		return nil, fmt.Errorf("opsfile: invalid image kind %d", kind)
+	}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+func (d *decoder) string() (string, error) {
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return "", err
+	}
+	if n > maxString {
+		return "", fmt.Errorf("opsfile: invalid string length %d", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
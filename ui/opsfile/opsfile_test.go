@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package opsfile
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/key"
+	"gioui.org/ui/pointer"
+	"gioui.org/ui/raster"
+	"gioui.org/ui/semantic"
+)
+
+func testOps() *ui.Ops {
+	o := new(ui.Ops)
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.Set(0, 0, color.NRGBA{R: 0xff, A: 0x80})
+	src.Set(1, 1, color.NRGBA{B: 0xff, A: 0xff})
+	var m ui.MacroOp
+	m.Record(o)
+	draw.ImageOp{Src: src, Rect: src.Bounds()}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 8, Y: 8}}}.Add(o)
+	m.Stop()
+	h := new(int)
+	pointer.HandlerOp{Key: h, Grab: true}.Add(o)
+	pointer.CursorOp{Cursor: pointer.CursorText}.Add(o)
+	pointer.PathAreaOp{}.Add(o)
+	key.HandlerOp{Key: h}.Add(o)
+	draw.ColorOp{Color: color.RGBA{G: 0x80, A: 0xff}}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(o)
+	var stack ui.StackOp
+	stack.Push(o)
+	m.Add(o)
+	ui.TransformOp{Transform: ui.Offset(f32.Point{X: 8})}.Add(o)
+	m.Add(o)
+	stack.Pop()
+	var p draw.PathBuilder
+	p.Init(o)
+	p.Line(f32.Point{X: 10, Y: 10})
+	p.Line(f32.Point{X: -10})
+	p.End()
+	draw.LinearGradientOp{
+		End: f32.Point{X: 10},
+		Stops: []draw.ColorStop{
+			{Offset: 0, Color: color.RGBA{R: 0xff, A: 0xff}},
+			{Offset: 1, Color: color.RGBA{B: 0xff, A: 0xff}},
+		},
+	}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(o)
+	semantic.NodeOp{Size: image.Pt(20, 20), Role: semantic.Button, Label: "OK"}.Add(o)
+	clipboard.WriteOp{Text: "copied"}.Add(o)
+	clipboard.ReadOp{Key: new(int)}.Add(o)
+
+	return o
+}
+
+func TestRoundTrip(t *testing.T) {
+	o := testOps()
+	var buf bytes.Buffer
+	if err := Encode(&buf, o); err != nil {
+		t.Fatal(err)
+	}
+	o2, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf2 bytes.Buffer
+	if err := Encode(&buf2, o2); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), buf2.Bytes()) {
+		t.Error("re-encoded ops differ")
+	}
+	var r ui.OpsReader
+	r.Reset(o2)
+	var ph pointer.HandlerOp
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		if ops.OpType(encOp.Data[0]) == ops.TypePointerHandler {
+			ph.Decode(encOp.Data, encOp.Refs)
+			break
+		}
+	}
+	if ph.Key != Key(0) || !ph.Grab {
+		t.Errorf("got handler %+v, expected key 0 with grab", ph)
+	}
+
+	img1 := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	img2 := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	var rr raster.Renderer
+	rr.Render(img1, o)
+	rr.Render(img2, o2)
+	if !bytes.Equal(img1.Pix, img2.Pix) {
+		t.Error("decoded ops render differently")
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	o := new(ui.Ops)
+	draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(o)
+	var buf bytes.Buffer
+	if err := Encode(&buf, o); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for i := 0; i < len(data); i++ {
+		if _, err := Decode(bytes.NewReader(data[:i])); err == nil && i != len(magic)+1 {
+			t.Errorf("decoding %d bytes: expected error", i)
+		}
+	}
+	bad := append([]byte(nil), data...)
+	bad[len(magic)+1] = 0xff
+	if _, err := Decode(bytes.NewReader(bad)); err == nil {
+		t.Error("decoding invalid op: expected error")
+	}
+	aux := append([]byte(nil), data[:len(magic)+1]...)
+	aux = append(aux, byte(ops.TypeAux), 0xff, 0xff, 0xff, 0xff)
+	if _, err := Decode(bytes.NewReader(aux)); err == nil {
+		t.Error("decoding oversized aux data: expected error")
+	}
+	aux = append(aux[:len(magic)+2], 8, 0, 0, 0, 1, 2, 3)
+	if _, err := Decode(bytes.NewReader(aux)); err == nil {
+		t.Error("decoding truncated aux data: expected error")
+	}
+}
+
+func TestDump(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Dump(&buf, testOps()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, exp := range []string{
+		"pointer handler 0, grab true\n",
+		"key handler 0, focus false\n",
+		"  transform offset (8,0), total offset (8,0)\n",
+		"  macro recorded in ops 0 at 0\n    image 2x2",
+		"clip (0,0)-(10,10) nonzero\n",
+		fmt.Sprintf("%-16s %8d %8d\n", "draw", 4, 4*ops.TypeDrawLen),
+	} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("dump does not contain %q:\n%s", exp, out)
+		}
+	}
+}
@@ -0,0 +1,669 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package pdf encodes operation lists into PDF documents, for
+printing and saving screens such as invoices and reports.
+
+Each page is the ops of a frame laid out with a Config, which
+maps dp and sp values to points, 1/72 inch. The pages hold
+native PDF vector graphics: StackOps save and restore the
+graphics state, TransformOps change the transformation matrix
+and clip paths become clipping paths. Text drawn by package
+measure becomes glyph outlines. Images are embedded with their
+alpha channel.
+
+For example, to save a two page report:
+
+	cfg := new(pdf.Config)
+	w := pdf.NewWriter(f, cfg)
+	ops := new(ui.Ops)
+	for _, page := range pages {
+		ops.Reset()
+		page.Layout(cfg, ops, layout.RigidConstraints(cfg.Size()))
+		if err := w.Page(ops); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+
+The document is a close but not exact match of the renderers:
+colors are blended in sRGB instead of linear light, and
+gradients pad their ends and ignore the alpha of their stops.
+BlendAdd draws like BlendScreen, and BlendClear has no PDF
+equivalent; draws and layers with that blend mode are left out.
+*/
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gioui.org/ui"
+	gdraw "gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/internal/path"
+	"golang.org/x/image/draw"
+)
+
+// Config implements the ui.Config interface for laying out
+// pages. A pixel is a point.
+type Config struct {
+	// PageSize is the size of pages in points. The zero
+	// value means A4.
+	PageSize f32.Point
+	// PtPerDp is the number of points per dp. The zero
+	// value means 0.75, the size of a CSS pixel in print.
+	PtPerDp float32
+	// PtPerSp is the number of points per sp. The zero
+	// value means PtPerDp.
+	PtPerSp float32
+	// Time is the value returned by Now.
+	Time time.Time
+}
+
+// Writer writes the pages of a PDF document.
+type Writer struct {
+	w   countWriter
+	cfg *Config
+	// offsets holds the offset of every object,
+	// indexed by object number - 1.
+	offsets []int64
+	pages   []int
+	err     error
+
+	reader ui.OpsReader
+	// content receives the content stream of the
+	// current page or layer.
+	content *bytes.Buffer
+	// res holds the resources of the document,
+	// shared by all pages.
+	xobjects, gstates, shadings []resource
+	images                      map[imageKey]string
+	gstateNames                 map[gstate]string
+}
+
+type countWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+// resource is a named entry in a resource dictionary.
+type resource struct {
+	name  string
+	value string
+}
+
+type imageKey struct {
+	img  image.Image
+	rect image.Rectangle
+}
+
+// gstate is the graphics state parameters of a
+// draw or layer.
+type gstate struct {
+	alpha float32
+	blend gdraw.BlendMode
+}
+
+type drawState struct {
+	// Current ImageOp image and rect, if any.
+	img     image.Image
+	imgRect image.Rectangle
+	// Current ColorOp, if any.
+	color color.RGBA
+	// Current gradient op, if any.
+	gradient *gradient
+	blend    gdraw.BlendMode
+}
+
+// gradient is a decoded LinearGradientOp or
+// RadialGradientOp.
+type gradient struct {
+	linear gdraw.LinearGradientOp
+	radial *gdraw.RadialGradientOp
+	// name of the shading resource, or empty if
+	// the shading is not yet written.
+	name string
+}
+
+// opClip structure must match opClip in package ui/draw.
+type opClip struct {
+	bounds   f32.Rectangle
+	fillRule gdraw.FillRule
+}
+
+// The objects written by Close.
+const (
+	catalogID = 1 + iota
+	pagesID
+	resourcesID
+)
+
+// a4 is the size of A4 pages in points.
+var a4 = f32.Point{X: 595, Y: 842}
+
+var blendModes = [...]string{
+	gdraw.BlendSrcOver:  "Normal",
+	gdraw.BlendMultiply: "Multiply",
+	gdraw.BlendScreen:   "Screen",
+	gdraw.BlendOverlay:  "Overlay",
+	gdraw.BlendDarken:   "Darken",
+	gdraw.BlendLighten:  "Lighten",
+	gdraw.BlendAdd:      "Screen",
+}
+
+func (c *Config) Now() time.Time {
+	return c.Time
+}
+
+func (c *Config) Px(v ui.Value) int {
+	ptPerDp := c.PtPerDp
+	if ptPerDp == 0 {
+		ptPerDp = .75
+	}
+	ptPerSp := c.PtPerSp
+	if ptPerSp == 0 {
+		ptPerSp = ptPerDp
+	}
+	var r float32
+	switch v.U {
+	case ui.UnitPx:
+		r = v.V
+	case ui.UnitDp:
+		r = v.V * ptPerDp
+	case ui.UnitSp:
+		r = v.V * ptPerSp
+	default:
+		panic("unknown unit")
+	}
+	return int(math.Round(float64(r)))
+}
+
+// Size returns the page size rounded down to whole
+// pixels, for the layout constraints of pages.
+func (c *Config) Size() image.Point {
+	sz := c.pageSize()
+	return image.Point{X: int(sz.X), Y: int(sz.Y)}
+}
+
+func (c *Config) pageSize() f32.Point {
+	if c.PageSize == (f32.Point{}) {
+		return a4
+	}
+	return c.PageSize
+}
+
+// NewWriter returns a Writer that writes a document with the
+// page size of cfg to w. The document is complete when Close
+// returns.
+func NewWriter(w io.Writer, cfg *Config) *Writer {
+	pw := &Writer{
+		w:           countWriter{w: bufio.NewWriter(w)},
+		cfg:         cfg,
+		offsets:     make([]int64, resourcesID),
+		images:      make(map[imageKey]string),
+		gstateNames: make(map[gstate]string),
+	}
+	// The binary comment marks the file as binary.
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	return pw
+}
+
+// Page adds a page with the ops of root. The ops are written
+// before Page returns, so root may be reset afterwards.
+func (w *Writer) Page(root *ui.Ops) error {
+	if w.err != nil {
+		return w.err
+	}
+	sz := w.cfg.pageSize()
+	w.content = new(bytes.Buffer)
+	// Flip the y axis to match ops.
+	w.contentf("1 0 0 -1 0 %s cm", num(sz.Y))
+	w.reader.Reset(root)
+	w.collectOps(drawState{})
+	contentID := w.stream("", w.content.Bytes())
+	pageID := w.object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R /Resources %d 0 R /Group << /S /Transparency /CS /DeviceRGB >> >>",
+		pagesID, num(sz.X), num(sz.Y), contentID, resourcesID))
+	w.pages = append(w.pages, pageID)
+	return w.err
+}
+
+// Close writes the rest of the document. Close doesn't
+// close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	var kids strings.Builder
+	for i, id := range w.pages {
+		if i > 0 {
+			kids.WriteString(" ")
+		}
+		fmt.Fprintf(&kids, "%d 0 R", id)
+	}
+	w.writeObject(catalogID, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID))
+	w.writeObject(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(w.pages)))
+	var res strings.Builder
+	res.WriteString("<<")
+	for _, d := range []struct {
+		name string
+		res  []resource
+	}{
+		{"XObject", w.xobjects},
+		{"ExtGState", w.gstates},
+		{"Shading", w.shadings},
+	} {
+		if len(d.res) == 0 {
+			continue
+		}
+		fmt.Fprintf(&res, " /%s <<", d.name)
+		for _, r := range d.res {
+			fmt.Fprintf(&res, " /%s %s", r.name, r.value)
+		}
+		res.WriteString(" >>")
+	}
+	res.WriteString(" >>")
+	w.writeObject(resourcesID, res.String())
+	xref := w.w.n
+	w.printf("xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		w.printf("%010d 00000 n \n", off)
+	}
+	w.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, catalogID, xref)
+	if err := w.w.w.Flush(); err != nil && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+// collectOps writes the content of the ops up to the
+// end of the current stack.
+func (w *Writer) collectOps(state drawState) {
+	var aux []byte
+	for encOp, ok := w.reader.Decode(); ok; encOp, ok = w.reader.Decode() {
+		switch ops.OpType(encOp.Data[0]) {
+		case ops.TypeTransform:
+			var op ui.TransformOp
+			op.Decode(encOp.Data)
+			sx, hx, ox, hy, sy, oy := op.Transform.Elems()
+			w.contentf("%s %s %s %s %s %s cm", num(sx), num(hy), num(hx), num(sy), num(ox), num(oy))
+		case ops.TypeAux:
+			aux = encOp.Data[ops.TypeAuxLen:]
+		case ops.TypeClip:
+			var op opClip
+			op.decode(encOp.Data)
+			if len(aux) > 0 {
+				w.pathContent(aux)
+				if op.fillRule == gdraw.EvenOdd {
+					w.contentf("W* n")
+				} else {
+					w.contentf("W n")
+				}
+			} else {
+				w.rectContent(op.bounds)
+				w.contentf("W n")
+			}
+			aux = nil
+		case ops.TypeColor:
+			var op gdraw.ColorOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.img = nil
+			state.gradient = nil
+			state.color = op.Color
+		case ops.TypeImage:
+			var op gdraw.ImageOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.img = op.Src
+			state.imgRect = op.Rect
+			state.gradient = nil
+		case ops.TypeLinearGradient:
+			var op gdraw.LinearGradientOp
+			op.Decode(encOp.Data, aux)
+			aux = nil
+			state.img = nil
+			state.gradient = &gradient{linear: op}
+		case ops.TypeRadialGradient:
+			var op gdraw.RadialGradientOp
+			op.Decode(encOp.Data, aux)
+			aux = nil
+			state.img = nil
+			state.gradient = &gradient{radial: &op}
+		case ops.TypeBlend:
+			var op gdraw.BlendOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.blend = op.Mode
+		case ops.TypeLayer:
+			var op gdraw.OpacityOp
+			op.Decode(encOp.Data, encOp.Refs)
+			if op.Alpha == 1 && state.blend == gdraw.BlendSrcOver {
+				// An opaque layer is the same as no layer.
+				continue
+			}
+			// The layer covers the rest of the stack.
+			w.layer(state, op.Alpha)
+			return
+		case ops.TypeDraw:
+			var op gdraw.DrawOp
+			op.Decode(encOp.Data, encOp.Refs)
+			w.draw(&state, op.Rect)
+		case ops.TypePush:
+			w.contentf("q")
+			w.collectOps(state)
+			w.contentf("Q")
+		case ops.TypePop:
+			return
+		}
+	}
+}
+
+// layer writes the rest of the current stack into a
+// transparency group and draws it with alpha and the
+// current blend mode.
+func (w *Writer) layer(state drawState, alpha float32) {
+	content := w.content
+	w.content = new(bytes.Buffer)
+	lstate := state
+	lstate.blend = gdraw.BlendSrcOver
+	w.collectOps(lstate)
+	layer := w.content
+	w.content = content
+	if state.blend == gdraw.BlendClear {
+		return
+	}
+	// The layer is drawn in the current space, where the
+	// bounds of its content are unknown.
+	const inf = "1e9"
+	id := w.stream(fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [-%s -%s %s %s] /Group << /S /Transparency >> /Resources %d 0 R",
+		inf, inf, inf, inf, resourcesID), layer.Bytes())
+	name := "Fm" + strconv.Itoa(len(w.xobjects)+1)
+	w.xobjects = append(w.xobjects, resource{name: name, value: fmt.Sprintf("%d 0 R", id)})
+	w.contentf("q /%s gs /%s Do Q", w.gstate(gstate{alpha: alpha, blend: state.blend}), name)
+}
+
+// draw writes the content for filling rect with the
+// current material.
+func (w *Writer) draw(state *drawState, rect f32.Rectangle) {
+	if rect.Empty() || state.blend == gdraw.BlendClear {
+		return
+	}
+	w.contentf("q")
+	defer w.contentf("Q")
+	switch {
+	case state.gradient != nil:
+		w.setGState(1, state.blend)
+		w.rectContent(rect)
+		w.contentf("W n /%s sh", w.shading(state.gradient))
+	case state.img == nil:
+		w.fill(state.color, state.blend, rect)
+	default:
+		if uniform, ok := state.img.(*image.Uniform); ok {
+			w.fill(color.RGBAModel.Convert(uniform.C).(color.RGBA), state.blend, rect)
+			return
+		}
+		w.setGState(1, state.blend)
+		name := w.image(state.img, state.imgRect)
+		// Images fill the unit square, upside down
+		// in the flipped space of the page.
+		w.contentf("%s 0 0 %s %s %s cm /%s Do", num(rect.Dx()), num(-rect.Dy()), num(rect.Min.X), num(rect.Max.Y), name)
+	}
+}
+
+// fill writes the content for filling rect with the
+// premultiplied color c.
+func (w *Writer) fill(c color.RGBA, blend gdraw.BlendMode, rect f32.Rectangle) {
+	nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+	w.setGState(float32(nc.A)/0xff, blend)
+	w.contentf("%s rg", colorString(nc))
+	w.rectContent(rect)
+	w.contentf("f")
+}
+
+// setGState sets the alpha and blend mode of the
+// graphics state, if they're not the defaults.
+func (w *Writer) setGState(alpha float32, blend gdraw.BlendMode) {
+	if alpha == 1 && blend == gdraw.BlendSrcOver {
+		return
+	}
+	w.contentf("/%s gs", w.gstate(gstate{alpha: alpha, blend: blend}))
+}
+
+// gstate returns the name of the graphics state
+// resource for s.
+func (w *Writer) gstate(s gstate) string {
+	if name, ok := w.gstateNames[s]; ok {
+		return name
+	}
+	name := "GS" + strconv.Itoa(len(w.gstates)+1)
+	a := num(s.alpha)
+	w.gstates = append(w.gstates, resource{
+		name:  name,
+		value: fmt.Sprintf("<< /ca %s /CA %s /BM /%s >>", a, a, blendModes[s.blend]),
+	})
+	w.gstateNames[s] = name
+	return name
+}
+
+// shading returns the name of the shading resource
+// for g, writing it if necessary.
+func (w *Writer) shading(g *gradient) string {
+	if g.name != "" {
+		return g.name
+	}
+	var stops []gdraw.ColorStop
+	var dict string
+	if r := g.radial; r != nil {
+		cx, cy := num(r.Center.X), num(r.Center.Y)
+		dict = fmt.Sprintf("/ShadingType 3 /Coords [%s %s 0 %s %s %s]", cx, cy, cx, cy, num(r.Radius))
+		stops = r.Stops
+	} else {
+		l := g.linear
+		dict = fmt.Sprintf("/ShadingType 2 /Coords [%s %s %s %s]", num(l.Start.X), num(l.Start.Y), num(l.End.X), num(l.End.Y))
+		stops = l.Stops
+	}
+	id := w.object(fmt.Sprintf("<< %s /ColorSpace /DeviceRGB /Function %s /Extend [true true] >>", dict, stopsFunction(stops)))
+	g.name = "Sh" + strconv.Itoa(len(w.shadings)+1)
+	w.shadings = append(w.shadings, resource{name: g.name, value: fmt.Sprintf("%d 0 R", id)})
+	return g.name
+}
+
+// stopsFunction returns a function that maps gradient
+// offsets to the colors of stops.
+func stopsFunction(stops []gdraw.ColorStop) string {
+	if len(stops) == 0 {
+		stops = []gdraw.ColorStop{{Color: color.RGBA{A: 0xff}}}
+	}
+	// Extend the stops to cover offsets 0 to 1.
+	if s := stops[0]; s.Offset > 0 {
+		stops = append([]gdraw.ColorStop{{Offset: 0, Color: s.Color}}, stops...)
+	}
+	if s := stops[len(stops)-1]; s.Offset < 1 {
+		stops = append(stops[:len(stops):len(stops)], gdraw.ColorStop{Offset: 1, Color: s.Color})
+	}
+	interp := func(c0, c1 color.RGBA) string {
+		n0 := color.NRGBAModel.Convert(c0).(color.NRGBA)
+		n1 := color.NRGBAModel.Convert(c1).(color.NRGBA)
+		return fmt.Sprintf("<< /FunctionType 2 /Domain [0 1] /C0 [%s] /C1 [%s] /N 1 >>", colorString(n0), colorString(n1))
+	}
+	if len(stops) == 1 {
+		return interp(stops[0].Color, stops[0].Color)
+	}
+	var funcs, bounds, encode []string
+	for i := 1; i < len(stops); i++ {
+		funcs = append(funcs, interp(stops[i-1].Color, stops[i].Color))
+		encode = append(encode, "0 1")
+		if i < len(stops)-1 {
+			bounds = append(bounds, num(stops[i].Offset))
+		}
+	}
+	return fmt.Sprintf("<< /FunctionType 3 /Domain [0 1] /Functions [%s] /Bounds [%s] /Encode [%s] >>",
+		strings.Join(funcs, " "), strings.Join(bounds, " "), strings.Join(encode, " "))
+}
+
+// image returns the name of the image resource for the rect
+// part of img, writing it if necessary.
+func (w *Writer) image(img image.Image, rect image.Rectangle) string {
+	k := imageKey{img: img, rect: rect}
+	if name, ok := w.images[k]; ok {
+		return name
+	}
+	sz := rect.Size()
+	sub := image.NewNRGBA(image.Rectangle{Max: sz})
+	draw.Draw(sub, sub.Bounds(), img, rect.Min, draw.Src)
+	rgb := make([]byte, 0, sz.X*sz.Y*3)
+	alpha := make([]byte, 0, sz.X*sz.Y)
+	opaque := true
+	for i := 0; i < len(sub.Pix); i += 4 {
+		p := sub.Pix[i : i+4]
+		rgb = append(rgb, p[0], p[1], p[2])
+		alpha = append(alpha, p[3])
+		opaque = opaque && p[3] == 0xff
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", sz.X, sz.Y)
+	smask := ""
+	if !opaque {
+		id := w.stream(dict+" /ColorSpace /DeviceGray", alpha)
+		smask = fmt.Sprintf(" /SMask %d 0 R", id)
+	}
+	id := w.stream(dict+" /ColorSpace /DeviceRGB"+smask, rgb)
+	name := "Im" + strconv.Itoa(len(w.xobjects)+1)
+	w.xobjects = append(w.xobjects, resource{name: name, value: fmt.Sprintf("%d 0 R", id)})
+	w.images[k] = name
+	return name
+}
+
+// pathContent writes the path of the vertices of a clip path.
+// The vertices don't record where contours start, and leave out
+// vertical lines. Like the renderers, a curve that starts at a
+// different x coordinate than the pen starts a new contour, and a
+// curve that starts at a different y coordinate is connected to
+// the pen by a line.
+func (w *Writer) pathContent(verts []byte) {
+	var pen f32.Point
+	first := true
+	for len(verts) >= 4*path.VertStride {
+		from, ctrl, to := path.DecodeCurve(verts)
+		verts = verts[4*path.VertStride:]
+		switch {
+		case first:
+			w.contentf("%s m", pointString(from))
+			first = false
+		case from.X != pen.X:
+			w.contentf("h %s m", pointString(from))
+		case from.Y != pen.Y:
+			w.contentf("%s l", pointString(from))
+		}
+		if mid := from.Add(to).Mul(.5); ctrl == mid {
+			w.contentf("%s l", pointString(to))
+		} else {
+			// Elevate the quadratic curve to a cubic curve.
+			c0 := from.Add(ctrl.Sub(from).Mul(2.0 / 3))
+			c1 := to.Add(ctrl.Sub(to).Mul(2.0 / 3))
+			w.contentf("%s %s %s c", pointString(c0), pointString(c1), pointString(to))
+		}
+		pen = to
+	}
+	if !first {
+		w.contentf("h")
+	}
+}
+
+func (w *Writer) rectContent(r f32.Rectangle) {
+	w.contentf("%s %s %s %s re", num(r.Min.X), num(r.Min.Y), num(r.Dx()), num(r.Dy()))
+}
+
+// contentf writes an operation to the current content stream.
+func (w *Writer) contentf(format string, args ...interface{}) {
+	fmt.Fprintf(w.content, format, args...)
+	w.content.WriteByte('\n')
+}
+
+// object writes an object and returns its number.
+func (w *Writer) object(body string) int {
+	w.offsets = append(w.offsets, 0)
+	id := len(w.offsets)
+	w.writeObject(id, body)
+	return id
+}
+
+func (w *Writer) writeObject(id int, body string) {
+	w.offsets[id-1] = w.w.n
+	w.printf("%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream writes a compressed stream object with the
+// entries of dict and returns its number.
+func (w *Writer) stream(dict string, data []byte) int {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	w.offsets = append(w.offsets, 0)
+	id := len(w.offsets)
+	w.offsets[id-1] = w.w.n
+	if dict != "" {
+		dict += " "
+	}
+	w.printf("%d 0 obj\n<< %s/Filter /FlateDecode /Length %d >>\nstream\n", id, dict, buf.Len())
+	w.write(buf.Bytes())
+	w.printf("\nendstream\nendobj\n")
+	return id
+}
+
+func (w *Writer) printf(format string, args ...interface{}) {
+	w.write([]byte(fmt.Sprintf(format, args...)))
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.w.Write(b)
+	w.w.n += int64(n)
+	w.err = err
+}
+
+func colorString(c color.NRGBA) string {
+	return fmt.Sprintf("%s %s %s", num(float32(c.R)/0xff), num(float32(c.G)/0xff), num(float32(c.B)/0xff))
+}
+
+func pointString(p f32.Point) string {
+	return num(p.X) + " " + num(p.Y)
+}
+
+// num formats v without an exponent, which
+// PDF doesn't support.
+func num(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 32)
+}
+
+func (op *opClip) decode(data []byte) {
+	if ops.OpType(data[0]) != ops.TypeClip {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	r := f32.Rectangle{
+		Min: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[1:])),
+			Y: math.Float32frombits(bo.Uint32(data[5:])),
+		},
+		Max: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[9:])),
+			Y: math.Float32frombits(bo.Uint32(data[13:])),
+		},
+	}
+	*op = opClip{
+		bounds:   r,
+		fillRule: gdraw.FillRule(data[17]),
+	}
+}
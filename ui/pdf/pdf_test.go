@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+)
+
+func TestWriter(t *testing.T) {
+	cfg := &Config{PageSize: f32.Point{X: 100, Y: 200}}
+	if got, want := cfg.Px(ui.Dp(4)), 3; got != want {
+		t.Errorf("Px(Dp(4)) = %d, want %d", got, want)
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf, cfg)
+	o := new(ui.Ops)
+	draw.ColorOp{Color: color.RGBA{R: 0x80, A: 0x80}}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(o)
+	var stack ui.StackOp
+	stack.Push(o)
+	ui.TransformOp{Transform: ui.Offset(f32.Point{X: 5, Y: 5})}.Add(o)
+	var p draw.PathBuilder
+	p.Init(o)
+	p.Line(f32.Point{X: 10})
+	p.Line(f32.Point{Y: 10})
+	p.Quad(f32.Point{X: -5, Y: 5}, f32.Point{X: -10, Y: -10})
+	p.End()
+	draw.ColorOp{Color: color.RGBA{B: 0xff, A: 0xff}}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(o)
+	stack.Pop()
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	draw.ImageOp{Src: src, Rect: src.Bounds()}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Min: f32.Point{X: 10}, Max: f32.Point{X: 20, Y: 10}}}.Add(o)
+	if err := w.Page(o); err != nil {
+		t.Fatal(err)
+	}
+	o.Reset()
+	if err := w.Page(o); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.Bytes()
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatal("invalid PDF header or trailer")
+	}
+	if !bytes.Contains(doc, []byte("/Type /Pages /Kids [7 0 R 9 0 R] /Count 2")) {
+		t.Error("missing page tree")
+	}
+	// Check that every object is where the
+	// cross-reference table says it is.
+	m := regexp.MustCompile(`xref\n0 (\d+)\n0000000000 65535 f \n`).FindSubmatchIndex(doc)
+	if m == nil {
+		t.Fatal("missing cross-reference table")
+	}
+	n, _ := strconv.Atoi(string(doc[m[2]:m[3]]))
+	entries := doc[m[1]:]
+	for id := 1; id < n; id++ {
+		off, err := strconv.Atoi(string(entries[:10]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = entries[20:]
+		if obj := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(doc[off:], []byte(obj)) {
+			t.Errorf("object %d not at offset %d", id, off)
+		}
+	}
+	c := regexp.MustCompile(`/Contents (\d+) 0 R`).FindSubmatch(doc)
+	if c == nil {
+		t.Fatal("missing page contents")
+	}
+	obj := doc[bytes.Index(doc, []byte(string(c[1])+" 0 obj\n")):]
+	start := bytes.Index(obj, []byte("stream\n")) + len("stream\n")
+	end := bytes.Index(obj, []byte("\nendstream"))
+	zr, err := zlib.NewReader(bytes.NewReader(obj[start:end]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `1 0 0 -1 0 200 cm
+q
+/GS1 gs
+1 0 0 rg
+0 0 10 10 re
+f
+Q
+q
+1 0 0 1 5 5 cm
+0 0 m
+10 0 l
+10 10 l
+6.6666665 13.333334 3.3333335 10 0 0 c
+h
+W n
+q
+0 0 1 rg
+0 0 10 10 re
+f
+Q
+Q
+q
+10 0 0 -10 10 10 cm /Im1 Do
+Q
+`
+	if got := string(content); got != want {
+		t.Errorf("got content\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(buf.String(), "/GS1 << /ca 0.5019608 /CA 0.5019608 /BM /Normal >>") {
+		t.Error("missing graphics state")
+	}
+}
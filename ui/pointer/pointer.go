@@ -4,13 +4,16 @@ package pointer
 
 import (
 	"encoding/binary"
+	"fmt"
 	"image"
+	"strings"
 	"time"
 
 	"gioui.org/ui"
 	"gioui.org/ui/f32"
 	"gioui.org/ui/input"
 	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/key"
 )
 
 type Event struct {
@@ -22,6 +25,15 @@ type Event struct {
 	Hit       bool
 	Position  f32.Point
 	Scroll    f32.Point
+	// Buttons are the set of mouse buttons pressed
+	// after the event. A Press event includes the
+	// pressed button, a Release event excludes the
+	// released button. Buttons is empty for touch
+	// events.
+	Buttons Buttons
+	// Modifiers are the keyboard modifiers pressed
+	// during the event.
+	Modifiers key.Modifiers
 }
 
 type RectAreaOp struct {
@@ -32,6 +44,26 @@ type EllipseAreaOp struct {
 	Size image.Point
 }
 
+// PathAreaOp is a hit area in the shape of the path of
+// the current clip, as built by a draw.PathBuilder. The
+// area contains the points inside the path according to
+// its fill rule, and is empty if there is no clip.
+//
+// For example, to make a rounded button respond only to
+// presses inside its outline:
+//
+//	var stack ui.StackOp
+//	stack.Push(ops)
+//	var b draw.PathBuilder
+//	b.Init(ops)
+//	... // Build the outline.
+//	b.End()
+//	pointer.PathAreaOp{}.Add(ops)
+//	pointer.HandlerOp{Key: h}.Add(ops)
+//	... // Draw the button.
+//	stack.Pop()
+type PathAreaOp struct{}
+
 // Must match the structure in input.areaOp
 type areaOp struct {
 	kind areaKind
@@ -43,6 +75,12 @@ type HandlerOp struct {
 	Grab bool
 }
 
+// CursorOp sets the mouse cursor shape of the
+// current hit area.
+type CursorOp struct {
+	Cursor Cursor
+}
+
 // PassOp change the current event pass-through
 // setting.
 type PassOp struct {
@@ -54,6 +92,12 @@ type Type uint8
 type Priority uint8
 type Source uint8
 
+// Cursor is a mouse cursor shape.
+type Cursor uint8
+
+// Buttons is a set of mouse buttons.
+type Buttons uint8
+
 // Must match input.areaKind
 type areaKind uint8
 
@@ -61,7 +105,19 @@ const (
 	Cancel Type = iota
 	Press
 	Release
+	// Move is delivered to the handlers hit by a
+	// pointer without pressed buttons, and to the
+	// handlers of a press until its release.
 	Move
+	// Enter is delivered when a pointer moves into
+	// the area of a handler.
+	Enter
+	// Leave is delivered when a pointer moves out
+	// of the area of a handler, when a touch pointer
+	// is released and when pointers are cancelled.
+	// Window drivers send Leave when the mouse
+	// leaves the window.
+	Leave
 )
 
 const (
@@ -69,6 +125,36 @@ const (
 	Touch
 )
 
+const (
+	ButtonPrimary Buttons = 1 << iota
+	ButtonSecondary
+	ButtonTertiary
+	ButtonBack
+	ButtonForward
+)
+
+const (
+	// CursorDefault is the platform default cursor,
+	// typically an arrow.
+	CursorDefault Cursor = iota
+	// CursorText is the I-beam cursor for text.
+	CursorText
+	// CursorPointer is the hand cursor for links
+	// and buttons.
+	CursorPointer
+	CursorCrossHair
+	// CursorColResize is the cursor for resizing
+	// horizontally.
+	CursorColResize
+	// CursorRowResize is the cursor for resizing
+	// vertically.
+	CursorRowResize
+	CursorGrab
+	CursorNotAllowed
+	// CursorNone hides the cursor.
+	CursorNone
+)
+
 const (
 	Shared Priority = iota
 	Foremost
@@ -78,6 +164,7 @@ const (
 const (
 	areaRect areaKind = iota
 	areaEllipse
+	areaPath
 )
 
 func (op RectAreaOp) Add(ops *ui.Ops) {
@@ -94,23 +181,25 @@ func (op EllipseAreaOp) Add(ops *ui.Ops) {
 	}.add(ops)
 }
 
+func (op PathAreaOp) Add(ops *ui.Ops) {
+	areaOp{
+		kind: areaPath,
+	}.add(ops)
+}
+
 func (op areaOp) add(o *ui.Ops) {
-	data := make([]byte, ops.TypeAreaLen)
-	data[0] = byte(ops.TypeArea)
+	data := o.Write(ops.TypeArea)
 	data[1] = byte(op.kind)
 	bo := binary.LittleEndian
 	bo.PutUint32(data[2:], uint32(op.size.X))
 	bo.PutUint32(data[6:], uint32(op.size.Y))
-	o.Write(data)
 }
 
 func (h HandlerOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypePointerHandlerLen)
-	data[0] = byte(ops.TypePointerHandler)
+	data := o.Write(ops.TypePointerHandler, h.Key)
 	if h.Grab {
 		data[1] = 1
 	}
-	o.Write(data, h.Key)
 }
 
 func (h *HandlerOp) Decode(d []byte, refs []interface{}) {
@@ -123,13 +212,25 @@ func (h *HandlerOp) Decode(d []byte, refs []interface{}) {
 	}
 }
 
+func (op CursorOp) Add(o *ui.Ops) {
+	data := o.Write(ops.TypeCursor)
+	data[1] = byte(op.Cursor)
+}
+
+func (op *CursorOp) Decode(d []byte) {
+	if ops.OpType(d[0]) != ops.TypeCursor {
+		panic("invalid op")
+	}
+	*op = CursorOp{
+		Cursor: Cursor(d[1]),
+	}
+}
+
 func (op PassOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypePassLen)
-	data[0] = byte(ops.TypePass)
+	data := o.Write(ops.TypePass)
 	if op.Pass {
 		data[1] = 1
 	}
-	o.Write(data)
 }
 
 func (op *PassOp) Decode(d []byte) {
@@ -141,6 +242,12 @@ func (op *PassOp) Decode(d []byte) {
 	}
 }
 
+// Contain reports whether the set b contains
+// all of the buttons.
+func (b Buttons) Contain(buttons Buttons) bool {
+	return b&buttons == buttons
+}
+
 func (t Type) String() string {
 	switch t {
 	case Press:
@@ -151,11 +258,40 @@ func (t Type) String() string {
 		return "Cancel"
 	case Move:
 		return "Move"
+	case Enter:
+		return "Enter"
+	case Leave:
+		return "Leave"
 	default:
 		panic("unknown Type")
 	}
 }
 
+func (c Cursor) String() string {
+	switch c {
+	case CursorDefault:
+		return "CursorDefault"
+	case CursorText:
+		return "CursorText"
+	case CursorPointer:
+		return "CursorPointer"
+	case CursorCrossHair:
+		return "CursorCrossHair"
+	case CursorColResize:
+		return "CursorColResize"
+	case CursorRowResize:
+		return "CursorRowResize"
+	case CursorGrab:
+		return "CursorGrab"
+	case CursorNotAllowed:
+		return "CursorNotAllowed"
+	case CursorNone:
+		return "CursorNone"
+	default:
+		return fmt.Sprintf("Cursor(%d)", c)
+	}
+}
+
 func (p Priority) String() string {
 	switch p {
 	case Shared:
@@ -180,5 +316,24 @@ func (s Source) String() string {
 	}
 }
 
+func (b Buttons) String() string {
+	var names []string
+	for _, btn := range []struct {
+		b    Buttons
+		name string
+	}{
+		{ButtonPrimary, "ButtonPrimary"},
+		{ButtonSecondary, "ButtonSecondary"},
+		{ButtonTertiary, "ButtonTertiary"},
+		{ButtonBack, "ButtonBack"},
+		{ButtonForward, "ButtonForward"},
+	} {
+		if b.Contain(btn.b) {
+			names = append(names, btn.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
 func (Event) ImplementsEvent()      {}
 func (Event) ImplementsInputEvent() {}
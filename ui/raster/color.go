@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package raster
+
+import "math"
+
+var (
+	// srgbToLinear maps 8-bit sRGB components to linear values.
+	srgbToLinear [256]float32
+	// linearToSRGBTable maps quantized linear values to
+	// 8-bit sRGB components.
+	linearToSRGBTable [linearSteps + 1]uint8
+)
+
+// linearSteps is the number of quantization steps for
+// linear values.
+const linearSteps = 1 << 14
+
+func init() {
+	for i := range srgbToLinear {
+		srgbToLinear[i] = toLinear(float32(i) / 0xff)
+	}
+	for i := range linearToSRGBTable {
+		c := float64(i) / linearSteps
+		// Use the formula from EXT_sRGB.
+		if c <= 0.0031308 {
+			c = c * 12.92
+		} else {
+			c = 1.055*math.Pow(c, 1/2.4) - 0.055
+		}
+		linearToSRGBTable[i] = uint8(c*0xff + .5)
+	}
+}
+
+func toLinear(c float32) float32 {
+	// Use the formula from EXT_sRGB.
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return float32(math.Pow(float64((c+0.055)/1.055), 2.4))
+}
+
+func linearToSRGB(c float32) uint8 {
+	c = clampf(c, 0, 1)
+	return linearToSRGBTable[int(c*linearSteps+.5)]
+}
+
+// gamma converts color components the same way
+// as the GPU renderer.
+func gamma(r, g, b, a uint32) [4]float32 {
+	color := [4]float32{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
+	// Assume that image.Uniform colors are in sRGB space. Linearize.
+	for i, c := range color {
+		color[i] = toLinear(c)
+	}
+	return color
+}
@@ -0,0 +1,285 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package raster
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/ui"
+	gdraw "gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/path"
+)
+
+// rasterizer computes anti-aliased coverage by accumulating
+// the signed area of line segments, in the style of font-rs.
+//
+// Like the GPU stenciler, coverage is accumulated along the
+// y axis so vertical segments don't contribute. That matters
+// because draw.PathBuilder discards vertical curves.
+type rasterizer struct {
+	bounds image.Rectangle
+	// acc holds the accumulation buffer, transposed:
+	// each column is stored contiguously.
+	acc []float32
+}
+
+// mask is a rasterized clip path.
+type mask struct {
+	bounds image.Rectangle
+	// Coverage values, transposed like rasterizer.acc.
+	cov    []float32
+	parent *mask
+}
+
+// flatness is the maximum distance in pixels between a
+// curve and its line approximation.
+const flatness = 0.1
+
+func (r *rasterizer) reset(bounds image.Rectangle) {
+	r.bounds = bounds
+	n := bounds.Dx() * (bounds.Dy() + 1)
+	if cap(r.acc) < n {
+		r.acc = make([]float32, n)
+	}
+	r.acc = r.acc[:n]
+	for i := range r.acc {
+		r.acc[i] = 0
+	}
+}
+
+// fill rasterizes the path vertices from a draw.PathBuilder,
+// transformed by t.
+func (r *rasterizer) fill(verts []byte, t ui.Transform) {
+	var start, pen f32.Point
+	first := true
+	for len(verts) >= 4*path.VertStride {
+		from, ctrl, to := path.DecodeCurve(verts)
+		verts = verts[4*path.VertStride:]
+		switch {
+		case first:
+			start = from
+			first = false
+		case from.X != pen.X:
+			// A new contour.
+			r.line(t.Transform(pen), t.Transform(start))
+			start = from
+		case from.Y != pen.Y:
+			// A discarded vertical curve. It doesn't
+			// contribute unless t rotates or shears.
+			r.line(t.Transform(pen), t.Transform(from))
+		}
+		r.quad(t.Transform(from), t.Transform(ctrl), t.Transform(to))
+		pen = to
+	}
+	if !first {
+		r.line(t.Transform(pen), t.Transform(start))
+	}
+}
+
+// rect rasterizes the rectangle rect transformed by t.
+func (r *rasterizer) rect(rect f32.Rectangle, t ui.Transform) {
+	p0 := t.Transform(rect.Min)
+	p1 := t.Transform(f32.Point{X: rect.Max.X, Y: rect.Min.Y})
+	p2 := t.Transform(rect.Max)
+	p3 := t.Transform(f32.Point{X: rect.Min.X, Y: rect.Max.Y})
+	r.line(p0, p1)
+	r.line(p1, p2)
+	r.line(p2, p3)
+	r.line(p3, p0)
+}
+
+// quad flattens a quadratic bezier into lines.
+func (r *rasterizer) quad(from, ctrl, to f32.Point) {
+	// The maximum deviation of a quadratic bezier from n
+	// chords is |from - 2ctrl + to|/(4n²).
+	dd := from.Sub(ctrl.Mul(2)).Add(to)
+	dev := math.Sqrt(float64(dd.X*dd.X + dd.Y*dd.Y))
+	n := int(math.Ceil(math.Sqrt(dev / (4 * flatness))))
+	if n <= 1 {
+		r.line(from, to)
+		return
+	}
+	prev := from
+	for i := 1; i < n; i++ {
+		t := float32(i) / float32(n)
+		c0 := from.Mul(1 - t).Add(ctrl.Mul(t))
+		c1 := ctrl.Mul(1 - t).Add(to.Mul(t))
+		p := c0.Mul(1 - t).Add(c1.Mul(t))
+		r.line(prev, p)
+		prev = p
+	}
+	r.line(prev, to)
+}
+
+// line accumulates the signed area of the line from p0 to p1.
+func (r *rasterizer) line(p0, p1 f32.Point) {
+	// Transpose and translate to the accumulation buffer.
+	u0, v0 := p0.Y-float32(r.bounds.Min.Y), p0.X-float32(r.bounds.Min.X)
+	u1, v1 := p1.Y-float32(r.bounds.Min.Y), p1.X-float32(r.bounds.Min.X)
+	if v0 == v1 {
+		return
+	}
+	dir := float32(1)
+	if v0 > v1 {
+		dir = -1
+		u0, v0, u1, v1 = u1, v1, u0, v0
+	}
+	r.clipLine(u0, v0, u1, v1, dir)
+}
+
+// clipLine splits the line where it leaves the buffer along
+// the accumulation axis. Areas before the buffer accumulate at
+// its start; areas after it are never read.
+func (r *rasterizer) clipLine(u0, v0, u1, v1, dir float32) {
+	for _, edge := range [...]float32{0, float32(r.bounds.Dy())} {
+		if u0 < edge && u1 > edge || u0 > edge && u1 < edge {
+			vs := v0 + (edge-u0)*(v1-v0)/(u1-u0)
+			r.clipLine(u0, v0, edge, vs, dir)
+			r.clipLine(edge, vs, u1, v1, dir)
+			return
+		}
+	}
+	r.span(u0, v0, u1, v1, dir)
+}
+
+// span accumulates a line segment that is increasing in v and
+// doesn't cross the u boundaries of the buffer.
+func (r *rasterizer) span(u0, v0, u1, v1, dir float32) {
+	width := r.bounds.Dy()
+	height := r.bounds.Dx()
+	stride := width + 1
+	fw := float32(width)
+	clampu := func(u float32) float32 {
+		if u < 0 {
+			return 0
+		}
+		if u > fw {
+			return fw
+		}
+		return u
+	}
+	u0, u1 = clampu(u0), clampu(u1)
+	if v1 <= 0 || v0 >= float32(height) {
+		return
+	}
+	dudv := (u1 - u0) / (v1 - v0)
+	if v0 < 0 {
+		u0 += dudv * -v0
+		v0 = 0
+	}
+	if v1 > float32(height) {
+		v1 = float32(height)
+	}
+	u := u0
+	for vi := int(v0); vi < height && float32(vi) < v1; vi++ {
+		lineStart := vi * stride
+		dv := minf(float32(vi+1), v1) - maxf(float32(vi), v0)
+		unext := u + dudv*dv
+		d := dv * dir
+		ua, ub := u, unext
+		if ua > ub {
+			ua, ub = ub, ua
+		}
+		ua, ub = clampu(ua), clampu(ub)
+		uafloor := float32(math.Floor(float64(ua)))
+		uai := int(uafloor)
+		ubceil := float32(math.Ceil(float64(ub)))
+		ubi := int(ubceil)
+		if ubi <= uai+1 {
+			umf := .5*(ua+ub) - uafloor
+			r.add(lineStart, uai, stride, d-d*umf)
+			r.add(lineStart, uai+1, stride, d*umf)
+		} else {
+			s := 1 / (ub - ua)
+			uaf := ua - uafloor
+			a0 := .5 * s * (1 - uaf) * (1 - uaf)
+			ubf := ub - ubceil + 1
+			am := .5 * s * ubf * ubf
+			r.add(lineStart, uai, stride, d*a0)
+			if ubi == uai+2 {
+				r.add(lineStart, uai+1, stride, d*(1-a0-am))
+			} else {
+				a1 := s * (1.5 - uaf)
+				r.add(lineStart, uai+1, stride, d*(a1-a0))
+				for ui := uai + 2; ui < ubi-1; ui++ {
+					r.add(lineStart, ui, stride, d*s)
+				}
+				a2 := a1 + float32(ubi-uai-3)*s
+				r.add(lineStart, ubi-1, stride, d*(1-a2-am))
+			}
+			r.add(lineStart, ubi, stride, d*am)
+		}
+		u = unext
+	}
+}
+
+func (r *rasterizer) add(lineStart, u, stride int, v float32) {
+	if u < stride {
+		r.acc[lineStart+u] += v
+	}
+}
+
+// mask converts the accumulated area to a coverage mask
+// according to the fill rule.
+func (r *rasterizer) mask(rule gdraw.FillRule) *mask {
+	w, h := r.bounds.Dy(), r.bounds.Dx()
+	m := &mask{
+		bounds: r.bounds,
+		cov:    make([]float32, w*h),
+	}
+	stride := w + 1
+	for v := 0; v < h; v++ {
+		var acc float32
+		line := r.acc[v*stride : v*stride+w]
+		cov := m.cov[v*w : v*w+w]
+		for u, a := range line {
+			acc += a
+			c := acc
+			if c < 0 {
+				c = -c
+			}
+			switch {
+			case rule == gdraw.EvenOdd:
+				c = 1 - float32(math.Abs(float64(1-float32(math.Mod(float64(c), 2)))))
+			case c > 1:
+				c = 1
+			}
+			cov[u] = c
+		}
+	}
+	return m
+}
+
+// at returns the coverage at the pixel (x, y), including
+// the coverage of parent masks.
+func (m *mask) at(x, y int) float32 {
+	c := float32(1)
+	for m != nil {
+		if !(image.Point{X: x, Y: y}).In(m.bounds) {
+			return 0
+		}
+		u, v := y-m.bounds.Min.Y, x-m.bounds.Min.X
+		c *= m.cov[v*m.bounds.Dy()+u]
+		if c == 0 {
+			return 0
+		}
+		m = m.parent
+	}
+	return c
+}
+
+func minf(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxf(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,632 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package raster implements a software renderer for drawing
+operation lists into images.
+
+The renderer doesn't need a GPU and is intended for
+rendering in tests, on servers and in other headless
+environments. Its output closely matches the GPU renderer
+used by package app, including gamma correct blending.
+*/
+package raster
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/ui"
+	gdraw "gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/gradient"
+	"gioui.org/ui/internal/ops"
+	"golang.org/x/image/draw"
+)
+
+// Renderer draws operation lists into images. The zero
+// value is ready to use.
+type Renderer struct {
+	reader ui.OpsReader
+	rast   rasterizer
+	size   image.Point
+	// fb holds the linear, premultiplied color
+	// of each pixel, 4 components per pixel.
+	fb []float32
+	// layers holds unused buffers for OpacityOp
+	// layers.
+	layers [][]float32
+	// textures maps images to their linear representation.
+	textures map[interface{}]*texture
+}
+
+type drawState struct {
+	clip f32.Rectangle
+	t    ui.Transform
+	mask *mask
+
+	// Current ImageOp image and rect, if any.
+	img     image.Image
+	imgRect image.Rectangle
+	// Current ColorOp, if any.
+	color color.RGBA
+	// Current gradient op, if any.
+	gradient *gradientState
+	// blend is the current blend mode.
+	blend gdraw.BlendMode
+}
+
+// gradientState is a decoded LinearGradientOp
+// or RadialGradientOp.
+type gradientState struct {
+	radial bool
+	// space maps the gradient to its offsets.
+	space  ui.Transform
+	stops  []gdraw.ColorStop
+	spread gdraw.Spread
+}
+
+type material struct {
+	material materialType
+	// For materialColor.
+	color [4]float32
+	// For materialTexture.
+	texture *texture
+	// For the gradient materials.
+	stops  []linearStop
+	spread gdraw.Spread
+	// uvTrans maps pixel centers to texture
+	// or gradient coordinates.
+	uvTrans ui.Transform
+}
+
+// linearStop is a gradient stop with a linear color.
+type linearStop struct {
+	offset float32
+	color  [4]float32
+}
+
+type materialType uint8
+
+// texture is an image converted to linear colors.
+type texture struct {
+	size image.Point
+	pix  []float32
+}
+
+// opClip structure must match opClip in package ui/draw.
+type opClip struct {
+	bounds   f32.Rectangle
+	fillRule gdraw.FillRule
+}
+
+const (
+	materialColor materialType = iota
+	materialTexture
+	materialLinearGradient
+	materialRadialGradient
+)
+
+// Render the operation list into dst. The top left corner of
+// the list is placed at the minimum point of dst's bounds.
+func (r *Renderer) Render(dst *image.RGBA, root *ui.Ops) {
+	b := dst.Bounds()
+	r.reset(b.Size())
+	r.reader.Reset(root)
+	state := drawState{
+		clip: f32.Rectangle{
+			Max: f32.Point{X: float32(r.size.X), Y: float32(r.size.Y)},
+		},
+	}
+	r.collectOps(&r.reader, state)
+	r.encode(dst)
+	// Release images to the GC.
+	for k := range r.textures {
+		delete(r.textures, k)
+	}
+}
+
+func (r *Renderer) reset(size image.Point) {
+	r.size = size
+	n := size.X * size.Y * 4
+	if cap(r.fb) < n {
+		r.fb = make([]float32, n)
+	}
+	r.fb = r.fb[:n]
+	// Clear to white, like the GPU renderer.
+	for i := range r.fb {
+		r.fb[i] = 1
+	}
+	if r.textures == nil {
+		r.textures = make(map[interface{}]*texture)
+	}
+}
+
+func (r *Renderer) collectOps(or *ui.OpsReader, state drawState) {
+	var aux []byte
+loop:
+	for encOp, ok := or.Decode(); ok; encOp, ok = or.Decode() {
+		switch ops.OpType(encOp.Data[0]) {
+		case ops.TypeTransform:
+			var op ui.TransformOp
+			op.Decode(encOp.Data)
+			state.t = state.t.Mul(op.Transform)
+		case ops.TypeAux:
+			aux = encOp.Data[ops.TypeAuxLen:]
+		case ops.TypeClip:
+			var op opClip
+			op.decode(encOp.Data)
+			bounds, isRect := state.t.TransformRect(op.bounds)
+			state.clip = state.clip.Intersect(bounds)
+			if (len(aux) > 0 || !isRect) && !state.clip.Empty() {
+				r.rast.reset(r.clipBounds(state.clip))
+				rule := gdraw.NonZero
+				if len(aux) > 0 {
+					r.rast.fill(aux, state.t)
+					rule = op.fillRule
+				} else {
+					r.rast.rect(op.bounds, state.t)
+				}
+				m := r.rast.mask(rule)
+				m.parent = state.mask
+				state.mask = m
+			}
+			aux = nil
+		case ops.TypeColor:
+			var op gdraw.ColorOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.img = nil
+			state.gradient = nil
+			state.color = op.Color
+		case ops.TypeImage:
+			var op gdraw.ImageOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.img = op.Src
+			state.imgRect = op.Rect
+			state.gradient = nil
+		case ops.TypeLinearGradient:
+			var op gdraw.LinearGradientOp
+			op.Decode(encOp.Data, aux)
+			aux = nil
+			state.img = nil
+			state.gradient = &gradientState{
+				space:  gradient.LinearSpace(op.Start, op.End),
+				stops:  op.Stops,
+				spread: op.Spread,
+			}
+		case ops.TypeRadialGradient:
+			var op gdraw.RadialGradientOp
+			op.Decode(encOp.Data, aux)
+			aux = nil
+			state.img = nil
+			state.gradient = &gradientState{
+				radial: true,
+				space:  gradient.RadialSpace(op.Center, op.Radius),
+				stops:  op.Stops,
+				spread: op.Spread,
+			}
+		case ops.TypeBlend:
+			var op gdraw.BlendOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.blend = op.Mode
+		case ops.TypeLayer:
+			var op gdraw.OpacityOp
+			op.Decode(encOp.Data, encOp.Refs)
+			if op.Alpha == 1 && state.blend == gdraw.BlendSrcOver {
+				// An opaque layer is the same as no layer.
+				continue
+			}
+			// The layer covers the rest of the stack.
+			r.layer(or, state, op.Alpha)
+			break loop
+		case ops.TypeDraw:
+			var op gdraw.DrawOp
+			op.Decode(encOp.Data, encOp.Refs)
+			r.draw(&state, op.Rect)
+		case ops.TypePush:
+			r.collectOps(or, state)
+		case ops.TypePop:
+			break loop
+		}
+	}
+}
+
+// layer draws the rest of the current stack into a
+// transparent layer and composites it with alpha and
+// the current blend mode.
+func (r *Renderer) layer(or *ui.OpsReader, state drawState, alpha float32) {
+	fb := r.fb
+	r.fb = r.newLayer(len(fb))
+	lstate := state
+	lstate.blend = gdraw.BlendSrcOver
+	r.collectOps(or, lstate)
+	layer := r.fb
+	r.fb = fb
+	// Nothing is drawn outside the clip.
+	bounds := r.clipBounds(state.clip)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			idx := (y*r.size.X + x) * 4
+			var col [4]float32
+			for i, c := range layer[idx : idx+4] {
+				col[i] = c * alpha
+			}
+			blend(fb[idx:idx+4], &col, state.blend)
+		}
+	}
+	r.layers = append(r.layers, layer)
+}
+
+// newLayer returns a transparent layer of n components.
+func (r *Renderer) newLayer(n int) []float32 {
+	var l []float32
+	if k := len(r.layers); k > 0 {
+		l = r.layers[k-1]
+		r.layers = r.layers[:k-1]
+	}
+	if cap(l) < n {
+		return make([]float32, n)
+	}
+	l = l[:n]
+	for i := range l {
+		l[i] = 0
+	}
+	return l
+}
+
+// clipBounds returns the pixels touched by a clip rectangle,
+// limited to the viewport.
+func (r *Renderer) clipBounds(clip f32.Rectangle) image.Rectangle {
+	return boundRectF(clip).Intersect(image.Rectangle{Max: r.size})
+}
+
+func (r *Renderer) draw(state *drawState, rect f32.Rectangle) {
+	trect, isRect := state.t.TransformRect(rect)
+	clip := state.clip.Intersect(trect)
+	if clip.Empty() {
+		return
+	}
+	// Like the GPU renderer, draw the pixel aligned
+	// bounds of the clipped rectangle.
+	bounds := r.clipBounds(clip)
+	if bounds.Empty() {
+		return
+	}
+	mask := state.mask
+	if !isRect {
+		// Clip to the transformed rectangle.
+		r.rast.reset(bounds)
+		r.rast.rect(rect, state.t)
+		mask = r.rast.mask(gdraw.NonZero)
+		mask.parent = state.mask
+	}
+	mat := r.materialFor(state, rect, trect, isRect)
+	var col [4]float32
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cov := mask.at(x, y)
+			if cov == 0 {
+				continue
+			}
+			mat.sample(&col, x, y)
+			for i := range col {
+				col[i] *= cov
+			}
+			idx := (y*r.size.X + x) * 4
+			blend(r.fb[idx:idx+4], &col, state.blend)
+		}
+	}
+}
+
+// blend the premultiplied color src into dst.
+func blend(dst []float32, src *[4]float32, mode gdraw.BlendMode) {
+	sa, da := src[3], dst[3]
+	switch mode {
+	case gdraw.BlendSrcOver:
+		for i, s := range src {
+			dst[i] = s + dst[i]*(1-sa)
+		}
+	case gdraw.BlendScreen:
+		for i, s := range src {
+			dst[i] = s + dst[i] - s*dst[i]
+		}
+	case gdraw.BlendAdd:
+		for i, s := range src {
+			// Like a GPU framebuffer, clamp the sum.
+			dst[i] = minf(s+dst[i], 1)
+		}
+	case gdraw.BlendClear:
+		for i := range dst {
+			dst[i] *= 1 - sa
+		}
+	default:
+		// The separable blend modes from the W3C
+		// compositing specification, for premultiplied
+		// colors.
+		for i, s := range src[:3] {
+			d := dst[i]
+			var b float32
+			switch mode {
+			case gdraw.BlendMultiply:
+				b = s * d
+			case gdraw.BlendOverlay:
+				if 2*d <= da {
+					b = 2 * s * d
+				} else {
+					b = sa*da - 2*(da-d)*(sa-s)
+				}
+			case gdraw.BlendDarken:
+				b = minf(s*da, d*sa)
+			case gdraw.BlendLighten:
+				b = maxf(s*da, d*sa)
+			}
+			dst[i] = (1-da)*s + (1-sa)*d + b
+		}
+		dst[3] = sa + da - sa*da
+	}
+}
+
+// materialFor returns the material for drawing rect, where trect
+// is the bounds of the transformed rect.
+func (r *Renderer) materialFor(state *drawState, rect, trect f32.Rectangle, isRect bool) material {
+	var m material
+	if g := state.gradient; g != nil {
+		m.material = materialLinearGradient
+		if g.radial {
+			m.material = materialRadialGradient
+		}
+		m.stops = make([]linearStop, len(g.stops))
+		for i, s := range g.stops {
+			m.stops[i] = linearStop{offset: s.Offset, color: gamma(s.Color.RGBA())}
+		}
+		m.spread = g.spread
+		m.uvTrans = g.space.Mul(state.t.Invert())
+	} else if state.img == nil {
+		m.material = materialColor
+		m.color = gamma(state.color.RGBA())
+	} else if uniform, ok := state.img.(*image.Uniform); ok {
+		m.material = materialColor
+		m.color = gamma(uniform.RGBA())
+	} else {
+		m.material = materialTexture
+		m.texture = r.textureFor(state.img)
+		sr := state.imgRect
+		b := state.img.Bounds()
+		srOff := f32.Point{X: float32(sr.Min.X - b.Min.X), Y: float32(sr.Min.Y - b.Min.Y)}
+		if isRect {
+			dr := boundRectF(trect)
+			scale := f32.Point{
+				X: float32(sr.Dx()) / float32(dr.Dx()),
+				Y: float32(sr.Dy()) / float32(dr.Dy()),
+			}
+			off := srOff.Sub(f32.Point{
+				X: float32(dr.Min.X) * scale.X,
+				Y: float32(dr.Min.Y) * scale.Y,
+			})
+			m.uvTrans = ui.Offset(off).Mul(ui.Scale(scale))
+		} else {
+			// Map back to the untransformed rect and
+			// from there to the source rectangle.
+			scale := f32.Point{
+				X: float32(sr.Dx()) / rect.Dx(),
+				Y: float32(sr.Dy()) / rect.Dy(),
+			}
+			m.uvTrans = ui.Offset(srOff).
+				Mul(ui.Scale(scale)).
+				Mul(ui.Offset(rect.Min.Mul(-1))).
+				Mul(state.t.Invert())
+		}
+	}
+	return m
+}
+
+func (r *Renderer) textureFor(img image.Image) *texture {
+	if t, exists := r.textures[img]; exists {
+		return t
+	}
+	b := img.Bounds()
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(b)
+		draw.Draw(rgba, b, img, b.Min, draw.Src)
+	}
+	t := &texture{
+		size: b.Size(),
+		pix:  make([]float32, b.Dx()*b.Dy()*4),
+	}
+	for y := 0; y < b.Dy(); y++ {
+		row := rgba.Pix[rgba.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x := 0; x < b.Dx(); x++ {
+			src := row[x*4 : x*4+4]
+			dst := t.pix[(y*b.Dx()+x)*4:]
+			// Like sRGB textures, convert color components
+			// but not alpha.
+			dst[0] = srgbToLinear[src[0]]
+			dst[1] = srgbToLinear[src[1]]
+			dst[2] = srgbToLinear[src[2]]
+			dst[3] = float32(src[3]) / 0xff
+		}
+	}
+	r.textures[img] = t
+	return t
+}
+
+// sample the material at the center of the pixel (x, y).
+func (m *material) sample(col *[4]float32, x, y int) {
+	switch m.material {
+	case materialColor:
+		*col = m.color
+	case materialTexture:
+		uv := m.uvTrans.Transform(f32.Point{X: float32(x) + .5, Y: float32(y) + .5})
+		m.texture.sample(col, uv.X, uv.Y)
+	case materialLinearGradient, materialRadialGradient:
+		p := m.uvTrans.Transform(f32.Point{X: float32(x) + .5, Y: float32(y) + .5})
+		t := p.X
+		if m.material == materialRadialGradient {
+			t = float32(math.Hypot(float64(p.X), float64(p.Y)))
+		}
+		interpolate(col, m.stops, spread(m.spread, t))
+	}
+}
+
+// spread applies the spread s to the gradient offset t.
+func spread(s gdraw.Spread, t float32) float32 {
+	switch s {
+	case gdraw.SpreadRepeat:
+		t -= float32(math.Floor(float64(t)))
+	case gdraw.SpreadReflect:
+		t = 1 - float32(math.Abs(math.Mod(math.Abs(float64(t)), 2)-1))
+	}
+	if t < 0 {
+		t = 0
+	}
+	if t > 1 {
+		t = 1
+	}
+	return t
+}
+
+// interpolate the color of the stops at the offset t.
+func interpolate(col *[4]float32, stops []linearStop, t float32) {
+	if len(stops) == 0 {
+		*col = [4]float32{}
+		return
+	}
+	if t <= stops[0].offset {
+		*col = stops[0].color
+		return
+	}
+	for i := 1; i < len(stops); i++ {
+		s0, s1 := stops[i-1], stops[i]
+		if t > s1.offset {
+			continue
+		}
+		f := (t - s0.offset) / (s1.offset - s0.offset)
+		for j := range col {
+			col[j] = s0.color[j]*(1-f) + s1.color[j]*f
+		}
+		return
+	}
+	*col = stops[len(stops)-1].color
+}
+
+// sample the texture at the texture coordinate (u, v) with
+// bilinear filtering.
+func (t *texture) sample(col *[4]float32, u, v float32) {
+	u, v = u-.5, v-.5
+	x0, y0 := int(math.Floor(float64(u))), int(math.Floor(float64(v)))
+	fx, fy := u-float32(x0), v-float32(y0)
+	x1, y1 := t.clampX(x0+1), t.clampY(y0+1)
+	x0, y0 = t.clampX(x0), t.clampY(y0)
+	c00 := t.pix[(y0*t.size.X+x0)*4:]
+	c10 := t.pix[(y0*t.size.X+x1)*4:]
+	c01 := t.pix[(y1*t.size.X+x0)*4:]
+	c11 := t.pix[(y1*t.size.X+x1)*4:]
+	for i := range col {
+		top := c00[i]*(1-fx) + c10[i]*fx
+		bot := c01[i]*(1-fx) + c11[i]*fx
+		col[i] = top*(1-fy) + bot*fy
+	}
+}
+
+func (t *texture) clampX(x int) int {
+	return clamp(x, 0, t.size.X-1)
+}
+
+func (t *texture) clampY(y int) int {
+	return clamp(y, 0, t.size.Y-1)
+}
+
+// encode the linear frame buffer into sRGB pixels in dst.
+func (r *Renderer) encode(dst *image.RGBA) {
+	b := dst.Bounds()
+	for y := 0; y < r.size.Y; y++ {
+		row := dst.Pix[dst.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x := 0; x < r.size.X; x++ {
+			src := r.fb[(y*r.size.X+x)*4:]
+			p := row[x*4 : x*4+4]
+			p[0] = linearToSRGB(src[0])
+			p[1] = linearToSRGB(src[1])
+			p[2] = linearToSRGB(src[2])
+			p[3] = uint8(clampf(src[3], 0, 1)*0xff + .5)
+		}
+	}
+}
+
+func (op *opClip) decode(data []byte) {
+	if ops.OpType(data[0]) != ops.TypeClip {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	r := f32.Rectangle{
+		Min: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[1:])),
+			Y: math.Float32frombits(bo.Uint32(data[5:])),
+		},
+		Max: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[9:])),
+			Y: math.Float32frombits(bo.Uint32(data[13:])),
+		},
+	}
+	*op = opClip{
+		bounds:   r,
+		fillRule: gdraw.FillRule(data[17]),
+	}
+}
+
+// boundRectF returns a bounding image.Rectangle for a f32.Rectangle.
+func boundRectF(r f32.Rectangle) image.Rectangle {
+	return image.Rectangle{
+		Min: image.Point{
+			X: floor(r.Min.X),
+			Y: floor(r.Min.Y),
+		},
+		Max: image.Point{
+			X: ceil(r.Max.X),
+			Y: ceil(r.Max.Y),
+		},
+	}
+}
+
+func ceil(v float32) int {
+	switch {
+	case math.IsInf(float64(v), +1):
+		return ui.Inf
+	case math.IsInf(float64(v), -1):
+		return -ui.Inf
+	default:
+		return int(math.Ceil(float64(v)))
+	}
+}
+
+func floor(v float32) int {
+	switch {
+	case math.IsInf(float64(v), +1):
+		return ui.Inf
+	case math.IsInf(float64(v), -1):
+		return -ui.Inf
+	default:
+		return int(math.Floor(float64(v)))
+	}
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func clampf(v, min, max float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
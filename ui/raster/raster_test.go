@@ -0,0 +1,342 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package raster
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+)
+
+func TestRenderColor(t *testing.T) {
+	ops := new(ui.Ops)
+	draw.ColorOp{Color: color.RGBA{R: 0xff, A: 0xff}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 5, Y: 10}}}.Add(ops)
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	var r Renderer
+	r.Render(dst, ops)
+	if got, exp := dst.RGBAAt(2, 2), (color.RGBA{R: 0xff, A: 0xff}); got != exp {
+		t.Errorf("inside: got %v, expected %v", got, exp)
+	}
+	if got, exp := dst.RGBAAt(7, 2), (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}); got != exp {
+		t.Errorf("outside: got %v, expected %v", got, exp)
+	}
+}
+
+func TestRenderPath(t *testing.T) {
+	ops := new(ui.Ops)
+	var p draw.PathBuilder
+	p.Init(ops)
+	// A diamond centered at (10, 10).
+	p.Move(f32.Point{X: 10, Y: 0})
+	p.Line(f32.Point{X: 10, Y: 10})
+	p.Line(f32.Point{X: -10, Y: 10})
+	p.Line(f32.Point{X: -10, Y: -10})
+	p.Line(f32.Point{X: 10, Y: -10})
+	p.End()
+	draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	dst := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	var r Renderer
+	r.Render(dst, ops)
+	if got := dst.RGBAAt(10, 10); got.R != 0 {
+		t.Errorf("center: got %v, expected black", got)
+	}
+	if got := dst.RGBAAt(1, 1); got.R != 0xff {
+		t.Errorf("corner: got %v, expected white", got)
+	}
+	// Pixels on the edge are partially covered.
+	if got := dst.RGBAAt(4, 5); got.R == 0 || got.R == 0xff {
+		t.Errorf("edge: got %v, expected gray", got)
+	}
+}
+
+func TestRenderClip(t *testing.T) {
+	ops := new(ui.Ops)
+	var stack ui.StackOp
+	stack.Push(ops)
+	draw.RectClip(image.Rect(0, 0, 5, 5)).Add(ops)
+	draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(ops)
+	stack.Pop()
+	ui.TransformOp{Transform: ui.Offset(f32.Point{X: 5, Y: 5})}.Add(ops)
+	draw.ColorOp{Color: color.RGBA{B: 0xff, A: 0xff}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 2, Y: 2}}}.Add(ops)
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	var r Renderer
+	r.Render(dst, ops)
+	tests := []struct {
+		x, y int
+		c    color.RGBA
+	}{
+		{4, 4, color.RGBA{A: 0xff}},
+		{5, 4, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+		{6, 6, color.RGBA{B: 0xff, A: 0xff}},
+		{7, 7, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}},
+	}
+	for _, tc := range tests {
+		if got := dst.RGBAAt(tc.x, tc.y); got != tc.c {
+			t.Errorf("(%d, %d): got %v, expected %v", tc.x, tc.y, got, tc.c)
+		}
+	}
+}
+
+func TestRenderTransform(t *testing.T) {
+	// A square centered at (20, 20), rotated by 45 degrees.
+	trans := ui.Offset(f32.Point{X: 20, Y: 20}).
+		Mul(ui.Rotate(math.Pi / 4)).
+		Mul(ui.Offset(f32.Point{X: -5, Y: -5}))
+	square := func(ops *ui.Ops) {
+		draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(ops)
+	}
+	// A square path has vertical sides that are only
+	// implied by the path data.
+	squarePath := func(ops *ui.Ops) {
+		var p draw.PathBuilder
+		p.Init(ops)
+		p.Line(f32.Point{X: 10})
+		p.Line(f32.Point{Y: 10})
+		p.Line(f32.Point{X: -10})
+		p.Line(f32.Point{Y: -10})
+		p.End()
+		square(ops)
+	}
+	for _, layout := range []func(ops *ui.Ops){square, squarePath} {
+		ops := new(ui.Ops)
+		ui.TransformOp{Transform: trans}.Add(ops)
+		layout(ops)
+		dst := image.NewRGBA(image.Rect(0, 0, 40, 40))
+		var r Renderer
+		r.Render(dst, ops)
+		for _, p := range []image.Point{{20, 20}, {20, 14}, {25, 20}} {
+			if got := dst.RGBAAt(p.X, p.Y); got.R != 0 {
+				t.Errorf("%v: got %v, expected black", p, got)
+			}
+		}
+		for _, p := range []image.Point{{15, 15}, {25, 25}, {20, 11}} {
+			if got := dst.RGBAAt(p.X, p.Y); got.R != 0xff {
+				t.Errorf("%v: got %v, expected white", p, got)
+			}
+		}
+	}
+}
+
+func TestRenderStroke(t *testing.T) {
+	black := color.RGBA{A: 0xff}
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	tests := []struct {
+		stroke draw.Stroke
+		pixels map[image.Point]color.RGBA
+	}{
+		{
+			draw.Stroke{Width: 4},
+			map[image.Point]color.RGBA{{15, 10}: black, {15, 12}: white, {4, 10}: white, {30, 11}: black},
+		},
+		{
+			draw.Stroke{Width: 4, Cap: draw.SquareCap, Join: draw.BevelJoin},
+			map[image.Point]color.RGBA{{4, 10}: black, {30, 11}: white},
+		},
+		{
+			draw.Stroke{Width: 4, Dashes: []float32{4}},
+			map[image.Point]color.RGBA{{6, 10}: black, {10, 10}: white, {14, 10}: black},
+		},
+	}
+	for i, test := range tests {
+		ops := new(ui.Ops)
+		var p draw.PathBuilder
+		p.Init(ops)
+		p.Stroke(test.stroke)
+		p.Move(f32.Point{X: 5, Y: 10})
+		p.Line(f32.Point{X: 24})
+		// A right angle corner.
+		p.Line(f32.Point{Y: -20})
+		p.End()
+		draw.ColorOp{Color: black}.Add(ops)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 40, Y: 20}}}.Add(ops)
+		dst := image.NewRGBA(image.Rect(0, 0, 40, 20))
+		var r Renderer
+		r.Render(dst, ops)
+		for pt, exp := range test.pixels {
+			if got := dst.RGBAAt(pt.X, pt.Y); got != exp {
+				t.Errorf("%d: %v: got %v, expected %v", i, pt, got, exp)
+			}
+		}
+	}
+}
+
+func TestRenderStrokeOverlap(t *testing.T) {
+	ops := new(ui.Ops)
+	var p draw.PathBuilder
+	p.Init(ops)
+	p.Stroke(draw.Stroke{Width: 4})
+	// A stroke crossing itself at (10, 10).
+	p.Move(f32.Point{X: 2, Y: 10})
+	p.Line(f32.Point{X: 14})
+	p.Line(f32.Point{Y: 6})
+	p.Line(f32.Point{X: -6})
+	p.Line(f32.Point{Y: -14})
+	p.End()
+	draw.ColorOp{Color: color.RGBA{A: 0x80}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	dst := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	var r Renderer
+	r.Render(dst, ops)
+	// The crossing is covered once.
+	if got, exp := dst.RGBAAt(10, 10), dst.RGBAAt(5, 10); got != exp {
+		t.Errorf("crossing: got %v, expected %v", got, exp)
+	}
+}
+
+func TestRenderFillRule(t *testing.T) {
+	for _, rule := range []draw.FillRule{draw.NonZero, draw.EvenOdd} {
+		ops := new(ui.Ops)
+		var p draw.PathBuilder
+		p.Init(ops)
+		p.SetFillRule(rule)
+		// Two nested squares with the same orientation.
+		for _, sq := range []struct{ off, size float32 }{{0, 20}, {5, 10}} {
+			p.Move(f32.Point{X: sq.off, Y: sq.off})
+			p.Line(f32.Point{X: sq.size})
+			p.Line(f32.Point{Y: sq.size})
+			p.Line(f32.Point{X: -sq.size})
+			p.Line(f32.Point{Y: -sq.size})
+		}
+		p.End()
+		draw.ColorOp{Color: color.RGBA{A: 0xff}}.Add(ops)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+		dst := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		var r Renderer
+		r.Render(dst, ops)
+		if got := dst.RGBAAt(2, 10); got.R != 0 {
+			t.Errorf("rule %d: outer: got %v, expected black", rule, got)
+		}
+		center := dst.RGBAAt(10, 10)
+		if rule == draw.NonZero && center.R != 0 || rule == draw.EvenOdd && center.R != 0xff {
+			t.Errorf("rule %d: center: got %v", rule, center)
+		}
+	}
+}
+
+func TestRenderGradient(t *testing.T) {
+	stops := []draw.ColorStop{
+		{Offset: 0, Color: color.RGBA{R: 0xff, A: 0xff}},
+		{Offset: 1, Color: color.RGBA{B: 0xff, A: 0xff}},
+	}
+	ops := new(ui.Ops)
+	draw.LinearGradientOp{
+		Start:  f32.Point{X: 0},
+		End:    f32.Point{X: 10},
+		Stops:  stops,
+		Spread: draw.SpreadReflect,
+	}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 10}}}.Add(ops)
+	ui.TransformOp{Transform: ui.Offset(f32.Point{Y: 10})}.Add(ops)
+	draw.RadialGradientOp{
+		Center: f32.Point{X: 10, Y: 5},
+		Radius: 5,
+		Stops:  stops,
+	}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 20, Y: 10}}}.Add(ops)
+	dst := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	var r Renderer
+	r.Render(dst, ops)
+	if got := dst.RGBAAt(0, 5); got.R < 0xf0 || got.B > 0x40 {
+		t.Errorf("linear start: got %v, expected red", got)
+	}
+	if got := dst.RGBAAt(9, 5); got.B < 0xf0 || got.R > 0x40 {
+		t.Errorf("linear end: got %v, expected blue", got)
+	}
+	// The reflected gradient is symmetric around its end.
+	if got, exp := dst.RGBAAt(12, 5), dst.RGBAAt(7, 5); got != exp {
+		t.Errorf("reflected: got %v, expected %v", got, exp)
+	}
+	if got := dst.RGBAAt(10, 15); got.R < 0xe0 || got.B > got.R {
+		t.Errorf("radial center: got %v, expected mostly red", got)
+	}
+	// Padded outside the radius.
+	if got, exp := dst.RGBAAt(0, 10), (color.RGBA{B: 0xff, A: 0xff}); got != exp {
+		t.Errorf("radial corner: got %v, expected %v", got, exp)
+	}
+}
+
+func TestRenderOpacity(t *testing.T) {
+	ops := new(ui.Ops)
+	var stack ui.StackOp
+	stack.Push(ops)
+	draw.OpacityOp{Alpha: 0.5}.Add(ops)
+	draw.ColorOp{Color: color.RGBA{R: 0xff, A: 0xff}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(ops)
+	draw.ColorOp{Color: color.RGBA{B: 0xff, A: 0xff}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Min: f32.Point{X: 5}, Max: f32.Point{X: 15, Y: 10}}}.Add(ops)
+	stack.Pop()
+	draw.ColorOp{Color: color.RGBA{G: 0xff, A: 0xff}}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Min: f32.Point{Y: 10}, Max: f32.Point{X: 20, Y: 20}}}.Add(ops)
+	dst := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	var r Renderer
+	r.Render(dst, ops)
+	// The overlapping parts of the layer are not blended
+	// with each other.
+	if got, exp := dst.RGBAAt(7, 5), dst.RGBAAt(12, 5); got != exp {
+		t.Errorf("overlap: got %v, expected %v", got, exp)
+	}
+	if got := dst.RGBAAt(2, 5); got.R != 0xff || got.G == 0 || got.G == 0xff {
+		t.Errorf("translucent: got %v, expected light red", got)
+	}
+	// Ops after the layer are not affected.
+	if got, exp := dst.RGBAAt(2, 15), (color.RGBA{G: 0xff, A: 0xff}); got != exp {
+		t.Errorf("after layer: got %v, expected %v", got, exp)
+	}
+}
+
+func TestRenderBlend(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	blue := color.RGBA{B: 0xff, A: 0xff}
+	black := color.RGBA{A: 0xff}
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	magenta := color.RGBA{R: 0xff, B: 0xff, A: 0xff}
+	tests := []struct {
+		mode     draw.BlendMode
+		dst, src color.RGBA
+		exp      color.RGBA
+	}{
+		{draw.BlendSrcOver, red, blue, blue},
+		{draw.BlendMultiply, white, red, red},
+		{draw.BlendMultiply, blue, red, black},
+		{draw.BlendScreen, red, blue, magenta},
+		{draw.BlendOverlay, white, red, white},
+		{draw.BlendOverlay, black, red, black},
+		{draw.BlendDarken, red, white, red},
+		{draw.BlendLighten, red, black, red},
+		{draw.BlendAdd, red, blue, magenta},
+		{draw.BlendClear, red, blue, color.RGBA{}},
+	}
+	for _, test := range tests {
+		ops := new(ui.Ops)
+		draw.ColorOp{Color: test.dst}.Add(ops)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 2, Y: 2}}}.Add(ops)
+		var stack ui.StackOp
+		stack.Push(ops)
+		draw.BlendOp{Mode: test.mode}.Add(ops)
+		draw.ColorOp{Color: test.src}.Add(ops)
+		draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 1, Y: 2}}}.Add(ops)
+		stack.Pop()
+		draw.ColorOp{Color: test.src}.Add(ops)
+		draw.DrawOp{Rect: f32.Rectangle{Min: f32.Point{X: 1}, Max: f32.Point{X: 2, Y: 2}}}.Add(ops)
+		dst := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		var r Renderer
+		r.Render(dst, ops)
+		if got := dst.RGBAAt(0, 0); got != test.exp {
+			t.Errorf("mode %d: got %v, expected %v", test.mode, got, test.exp)
+		}
+		// The blend mode is scoped to the stack.
+		if got := dst.RGBAAt(1, 0); got != test.src {
+			t.Errorf("mode %d after pop: got %v, expected %v", test.mode, got, test.src)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package semantic describes the meaning of user interface
+elements, for accessibility services and for tests.
+
+A NodeOp describes an element such as a button or a label, and
+applies to the rest of the current StackOp. The nodes added
+after a NodeOp in the same StackOp, or in StackOps nested within
+it, are the children of its node.
+
+For example, to describe a checked checkbox:
+
+	var stack ui.StackOp
+	stack.Push(ops)
+	semantic.NodeOp{
+		Size:    sz,
+		Role:    semantic.CheckBox,
+		Label:   "Enable notifications",
+		Checked: true,
+		Actions: semantic.ActionClick,
+	}.Add(ops)
+	... // Draw the checkbox.
+	stack.Pop()
+
+The tree of Nodes of a frame is built from its NodeOps.
+*/
+package semantic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"strings"
+
+	"gioui.org/ui"
+	"gioui.org/ui/internal/ops"
+)
+
+// NodeOp describes the element in the rectangle from the
+// origin to Size.
+type NodeOp struct {
+	Size image.Point
+	Role Role
+	// Label is the name of the element, such as the
+	// text of a button.
+	Label string
+	// Description is an optional extra explanation of
+	// the element.
+	Description string
+	// Value is the current value of the element, such
+	// as the text of an editor.
+	Value    string
+	Disabled bool
+	Selected bool
+	Checked  bool
+	// Actions is the set of actions the element
+	// supports.
+	Actions Actions
+}
+
+// Node is a node in the semantic tree of a frame.
+type Node struct {
+	NodeOp
+	// Bounds is the bounding rectangle of the
+	// node in window coordinates.
+	Bounds   image.Rectangle
+	Children []*Node
+}
+
+// Role is the kind of a user interface element.
+type Role uint8
+
+// Actions is a set of actions.
+type Actions uint8
+
+const (
+	Unknown Role = iota
+	Button
+	CheckBox
+	Label
+	Editor
+	Image
+	List
+)
+
+const (
+	ActionClick Actions = 1 << iota
+	ActionFocus
+	ActionScrollForward
+	ActionScrollBackward
+)
+
+const (
+	flagDisabled = 1 << iota
+	flagSelected
+	flagChecked
+)
+
+func (op NodeOp) Add(o *ui.Ops) {
+	data := o.Write(ops.TypeSemantic, op.Label, op.Description, op.Value)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], uint32(op.Size.X))
+	bo.PutUint32(data[5:], uint32(op.Size.Y))
+	data[9] = byte(op.Role)
+	var flags byte
+	if op.Disabled {
+		flags |= flagDisabled
+	}
+	if op.Selected {
+		flags |= flagSelected
+	}
+	if op.Checked {
+		flags |= flagChecked
+	}
+	data[10] = flags
+	data[11] = byte(op.Actions)
+}
+
+func (op *NodeOp) Decode(d []byte, refs []interface{}) {
+	if ops.OpType(d[0]) != ops.TypeSemantic {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	flags := d[10]
+	*op = NodeOp{
+		Size: image.Point{
+			X: int(int32(bo.Uint32(d[1:]))),
+			Y: int(int32(bo.Uint32(d[5:]))),
+		},
+		Role:        Role(d[9]),
+		Label:       refs[0].(string),
+		Description: refs[1].(string),
+		Value:       refs[2].(string),
+		Disabled:    flags&flagDisabled != 0,
+		Selected:    flags&flagSelected != 0,
+		Checked:     flags&flagChecked != 0,
+		Actions:     Actions(d[11]),
+	}
+}
+
+// Find returns the first node in depth-first order, starting
+// with n, whose role and label match, or nil if there is none.
+func (n *Node) Find(r Role, label string) *Node {
+	if n.Role == r && n.Label == label {
+		return n
+	}
+	for _, c := range n.Children {
+		if f := c.Find(r, label); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// String formats the tree rooted at n with one node per
+// line, indented by depth.
+func (n *Node) String() string {
+	var b strings.Builder
+	n.format(&b, 0)
+	return b.String()
+}
+
+func (n *Node) format(b *strings.Builder, depth int) {
+	fmt.Fprintf(b, "%s%s %q %v", strings.Repeat("\t", depth), n.Role, n.Label, n.Bounds)
+	if n.Description != "" {
+		fmt.Fprintf(b, " description=%q", n.Description)
+	}
+	if n.Value != "" {
+		fmt.Fprintf(b, " value=%q", n.Value)
+	}
+	if n.Disabled {
+		b.WriteString(" disabled")
+	}
+	if n.Selected {
+		b.WriteString(" selected")
+	}
+	if n.Checked {
+		b.WriteString(" checked")
+	}
+	if n.Actions != 0 {
+		fmt.Fprintf(b, " actions=%s", n.Actions)
+	}
+	b.WriteString("\n")
+	for _, c := range n.Children {
+		c.format(b, depth+1)
+	}
+}
+
+func (r Role) String() string {
+	switch r {
+	case Unknown:
+		return "Unknown"
+	case Button:
+		return "Button"
+	case CheckBox:
+		return "CheckBox"
+	case Label:
+		return "Label"
+	case Editor:
+		return "Editor"
+	case Image:
+		return "Image"
+	case List:
+		return "List"
+	default:
+		return fmt.Sprintf("Role(%d)", r)
+	}
+}
+
+func (a Actions) String() string {
+	var names []string
+	for _, act := range []struct {
+		a    Actions
+		name string
+	}{
+		{ActionClick, "Click"},
+		{ActionFocus, "Focus"},
+		{ActionScrollForward, "ScrollForward"},
+		{ActionScrollBackward, "ScrollBackward"},
+	} {
+		if a&act.a != 0 {
+			names = append(names, act.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,429 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package svg encodes operation lists into SVG documents, for
+producing vector assets and figures from real frames and for
+comparing rendering output as text.
+
+The document mirrors the structure of the ops: TransformOps
+become groups, clip paths become clipPaths applied to groups,
+and DrawOps become rectangles filled with the current color,
+gradient or image. Text drawn by package measure becomes glyph
+outlines. Images are embedded as PNG data.
+
+The document is a close but not exact match of the renderers:
+SVG viewers blend in sRGB instead of linear light and don't
+snap draws to pixels. BlendClear has no SVG equivalent, and
+draws and layers with that blend mode are left out.
+*/
+package svg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"gioui.org/ui"
+	gdraw "gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/internal/ops"
+	"gioui.org/ui/internal/path"
+	"golang.org/x/image/draw"
+)
+
+type encoder struct {
+	reader ui.OpsReader
+	// body holds the elements of the document,
+	// defs the gradients and clip paths they use.
+	body, defs bytes.Buffer
+	indent     int
+	ids        int
+	// images maps images and their source rectangles
+	// to their data URIs.
+	images map[imageKey]string
+	err    error
+}
+
+type drawState struct {
+	// Current ImageOp image and rect, if any.
+	img     image.Image
+	imgRect image.Rectangle
+	// Current ColorOp, if any.
+	color color.RGBA
+	// Current gradient op, if any.
+	gradient *gradient
+	blend    gdraw.BlendMode
+}
+
+// gradient is a decoded LinearGradientOp or
+// RadialGradientOp.
+type gradient struct {
+	linear gdraw.LinearGradientOp
+	radial *gdraw.RadialGradientOp
+	// id of the gradient element, or empty if
+	// the gradient is not yet defined.
+	id string
+}
+
+type imageKey struct {
+	img  image.Image
+	rect image.Rectangle
+}
+
+// opClip structure must match opClip in package ui/draw.
+type opClip struct {
+	bounds   f32.Rectangle
+	fillRule gdraw.FillRule
+}
+
+var blendModes = [...]string{
+	gdraw.BlendMultiply: "multiply",
+	gdraw.BlendScreen:   "screen",
+	gdraw.BlendOverlay:  "overlay",
+	gdraw.BlendDarken:   "darken",
+	gdraw.BlendLighten:  "lighten",
+	gdraw.BlendAdd:      "plus-lighter",
+}
+
+var spreads = [...]string{
+	gdraw.SpreadPad:     "pad",
+	gdraw.SpreadRepeat:  "repeat",
+	gdraw.SpreadReflect: "reflect",
+}
+
+// Encode writes the ops of root as an SVG document of the
+// given size in pixels. Like the renderers, the document
+// has a white background.
+func Encode(w io.Writer, root *ui.Ops, size image.Point) error {
+	e := &encoder{
+		images: make(map[imageKey]string),
+		indent: 1,
+	}
+	e.printf(`<rect width="%d" height="%d" fill="#ffffff"/>`, size.X, size.Y)
+	e.reader.Reset(root)
+	e.collectOps(drawState{})
+	if e.err != nil {
+		return e.err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", size.X, size.Y, size.X, size.Y)
+	if e.defs.Len() > 0 {
+		bw.WriteString("\t<defs>\n")
+		bw.Write(e.defs.Bytes())
+		bw.WriteString("\t</defs>\n")
+	}
+	bw.Write(e.body.Bytes())
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+// collectOps writes the elements of the ops up to the end
+// of the current stack.
+func (e *encoder) collectOps(state drawState) {
+	var aux []byte
+	// groups is the number of groups opened for
+	// the current stack.
+	groups := 0
+	defer func() {
+		for ; groups > 0; groups-- {
+			e.indent--
+			e.printf("</g>")
+		}
+	}()
+	group := func(format string, args ...interface{}) {
+		e.printf("<g "+format+">", args...)
+		e.indent++
+		groups++
+	}
+	for encOp, ok := e.reader.Decode(); ok; encOp, ok = e.reader.Decode() {
+		switch ops.OpType(encOp.Data[0]) {
+		case ops.TypeTransform:
+			var op ui.TransformOp
+			op.Decode(encOp.Data)
+			group(`transform="%s"`, transformString(op.Transform))
+		case ops.TypeAux:
+			aux = encOp.Data[ops.TypeAuxLen:]
+		case ops.TypeClip:
+			var op opClip
+			op.decode(encOp.Data)
+			id := e.newID("clip")
+			e.def(`<clipPath id="%s">`, id)
+			if len(aux) > 0 {
+				rule := "nonzero"
+				if op.fillRule == gdraw.EvenOdd {
+					rule = "evenodd"
+				}
+				e.def(`	<path d="%s" clip-rule="%s"/>`, pathData(aux), rule)
+			} else {
+				e.def(`	%s`, rectElement(op.bounds, ""))
+			}
+			e.def(`</clipPath>`)
+			group(`clip-path="url(#%s)"`, id)
+			aux = nil
+		case ops.TypeColor:
+			var op gdraw.ColorOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.img = nil
+			state.gradient = nil
+			state.color = op.Color
+		case ops.TypeImage:
+			var op gdraw.ImageOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.img = op.Src
+			state.imgRect = op.Rect
+			state.gradient = nil
+		case ops.TypeLinearGradient:
+			var op gdraw.LinearGradientOp
+			op.Decode(encOp.Data, aux)
+			aux = nil
+			state.img = nil
+			state.gradient = &gradient{linear: op}
+		case ops.TypeRadialGradient:
+			var op gdraw.RadialGradientOp
+			op.Decode(encOp.Data, aux)
+			aux = nil
+			state.img = nil
+			state.gradient = &gradient{radial: &op}
+		case ops.TypeBlend:
+			var op gdraw.BlendOp
+			op.Decode(encOp.Data, encOp.Refs)
+			state.blend = op.Mode
+		case ops.TypeLayer:
+			var op gdraw.OpacityOp
+			op.Decode(encOp.Data, encOp.Refs)
+			if op.Alpha == 1 && state.blend == gdraw.BlendSrcOver {
+				// An opaque layer is the same as no layer.
+				continue
+			}
+			// The layer covers the rest of the stack.
+			attrs := fmt.Sprintf(`opacity="%s"%s`, num(op.Alpha), blendStyle(state.blend))
+			if state.blend == gdraw.BlendClear {
+				attrs = `display="none"`
+			}
+			group("%s", attrs)
+			state.blend = gdraw.BlendSrcOver
+		case ops.TypeDraw:
+			var op gdraw.DrawOp
+			op.Decode(encOp.Data, encOp.Refs)
+			e.draw(&state, op.Rect)
+		case ops.TypePush:
+			e.collectOps(state)
+		case ops.TypePop:
+			return
+		}
+	}
+}
+
+// draw writes the element for filling rect with the
+// current material.
+func (e *encoder) draw(state *drawState, rect f32.Rectangle) {
+	if rect.Empty() || state.blend == gdraw.BlendClear {
+		return
+	}
+	style := blendStyle(state.blend)
+	switch {
+	case state.gradient != nil:
+		id := e.gradientID(state.gradient)
+		e.printf("%s", rectElement(rect, fmt.Sprintf(` fill="url(#%s)"%s`, id, style)))
+	case state.img == nil:
+		e.printf("%s", rectElement(rect, fillAttrs(state.color)+style))
+	default:
+		if uniform, ok := state.img.(*image.Uniform); ok {
+			c := color.RGBAModel.Convert(uniform.C).(color.RGBA)
+			e.printf("%s", rectElement(rect, fillAttrs(c)+style))
+			return
+		}
+		uri := e.imageURI(state.img, state.imgRect)
+		e.printf(`<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="none"%s xlink:href="%s"/>`,
+			num(rect.Min.X), num(rect.Min.Y), num(rect.Dx()), num(rect.Dy()), style, uri)
+	}
+}
+
+// gradientID returns the id of the gradient element
+// for g, defining it if necessary.
+func (e *encoder) gradientID(g *gradient) string {
+	if g.id != "" {
+		return g.id
+	}
+	g.id = e.newID("gradient")
+	var stops []gdraw.ColorStop
+	if r := g.radial; r != nil {
+		e.def(`<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%s" cy="%s" r="%s" spreadMethod="%s" color-interpolation="linearRGB">`,
+			g.id, num(r.Center.X), num(r.Center.Y), num(r.Radius), name(spreads[:], byte(r.Spread)))
+		stops = r.Stops
+	} else {
+		l := g.linear
+		e.def(`<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%s" y1="%s" x2="%s" y2="%s" spreadMethod="%s" color-interpolation="linearRGB">`,
+			g.id, num(l.Start.X), num(l.Start.Y), num(l.End.X), num(l.End.Y), name(spreads[:], byte(l.Spread)))
+		stops = l.Stops
+	}
+	for _, s := range stops {
+		c := color.NRGBAModel.Convert(s.Color).(color.NRGBA)
+		attrs := ""
+		if c.A != 0xff {
+			attrs = fmt.Sprintf(` stop-opacity="%s"`, num(float32(c.A)/0xff))
+		}
+		e.def(`	<stop offset="%s" stop-color="%s"%s/>`, num(s.Offset), colorString(c), attrs)
+	}
+	if g.radial != nil {
+		e.def(`</radialGradient>`)
+	} else {
+		e.def(`</linearGradient>`)
+	}
+	return g.id
+}
+
+// imageURI returns a PNG data URI of the rect part of img.
+func (e *encoder) imageURI(img image.Image, rect image.Rectangle) string {
+	k := imageKey{img: img, rect: rect}
+	if uri, ok := e.images[k]; ok {
+		return uri
+	}
+	sub := image.NewNRGBA(image.Rectangle{Max: rect.Size()})
+	draw.Draw(sub, sub.Bounds(), img, rect.Min, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sub); err != nil && e.err == nil {
+		e.err = err
+	}
+	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	e.images[k] = uri
+	return uri
+}
+
+func (e *encoder) newID(prefix string) string {
+	e.ids++
+	return prefix + strconv.Itoa(e.ids)
+}
+
+// printf writes an element to the body.
+func (e *encoder) printf(format string, args ...interface{}) {
+	e.body.WriteString(strings.Repeat("\t", e.indent))
+	fmt.Fprintf(&e.body, format, args...)
+	e.body.WriteByte('\n')
+}
+
+// def writes an element to the definitions.
+func (e *encoder) def(format string, args ...interface{}) {
+	e.defs.WriteString("\t\t")
+	fmt.Fprintf(&e.defs, format, args...)
+	e.defs.WriteByte('\n')
+}
+
+// pathData converts the vertices of a clip path to SVG path data.
+// The vertices don't record where contours start, and leave out
+// vertical lines. Like the renderers, a curve that starts at a
+// different x coordinate than the pen starts a new contour, and a
+// curve that starts at a different y coordinate is connected to
+// the pen by a line.
+func pathData(verts []byte) string {
+	var b strings.Builder
+	var pen f32.Point
+	first := true
+	for len(verts) >= 4*path.VertStride {
+		from, ctrl, to := path.DecodeCurve(verts)
+		verts = verts[4*path.VertStride:]
+		switch {
+		case first:
+			fmt.Fprintf(&b, "M%s", pointString(from))
+			first = false
+		case from.X != pen.X:
+			fmt.Fprintf(&b, "ZM%s", pointString(from))
+		case from.Y != pen.Y:
+			fmt.Fprintf(&b, "L%s", pointString(from))
+		}
+		if mid := from.Add(to).Mul(.5); ctrl == mid {
+			fmt.Fprintf(&b, "L%s", pointString(to))
+		} else {
+			fmt.Fprintf(&b, "Q%s %s", pointString(ctrl), pointString(to))
+		}
+		pen = to
+	}
+	if !first {
+		b.WriteString("Z")
+	}
+	return b.String()
+}
+
+func rectElement(r f32.Rectangle, attrs string) string {
+	return fmt.Sprintf(`<rect x="%s" y="%s" width="%s" height="%s"%s/>`,
+		num(r.Min.X), num(r.Min.Y), num(r.Dx()), num(r.Dy()), attrs)
+}
+
+// fillAttrs returns the fill attributes for the
+// premultiplied color c.
+func fillAttrs(c color.RGBA) string {
+	nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+	attrs := fmt.Sprintf(` fill="%s"`, colorString(nc))
+	if nc.A != 0xff {
+		attrs += fmt.Sprintf(` fill-opacity="%s"`, num(float32(nc.A)/0xff))
+	}
+	return attrs
+}
+
+func blendStyle(m gdraw.BlendMode) string {
+	if m == gdraw.BlendSrcOver {
+		return ""
+	}
+	return fmt.Sprintf(` style="mix-blend-mode:%s"`, name(blendModes[:], byte(m)))
+}
+
+func transformString(t ui.Transform) string {
+	sx, hx, ox, hy, sy, oy := t.Elems()
+	if sx == 1 && hx == 0 && hy == 0 && sy == 1 {
+		return fmt.Sprintf("translate(%s %s)", num(ox), num(oy))
+	}
+	return fmt.Sprintf("matrix(%s %s %s %s %s %s)", num(sx), num(hy), num(hx), num(sy), num(ox), num(oy))
+}
+
+func colorString(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func pointString(p f32.Point) string {
+	return num(p.X) + " " + num(p.Y)
+}
+
+// num formats v with the fewest digits that
+// represent it exactly.
+func num(v float32) string {
+	return strconv.FormatFloat(float64(v), 'g', -1, 32)
+}
+
+// name returns the name of v from names, or the
+// number v if it has no name.
+func name(names []string, v byte) string {
+	if int(v) < len(names) && names[v] != "" {
+		return names[v]
+	}
+	return strconv.Itoa(int(v))
+}
+
+func (op *opClip) decode(data []byte) {
+	if ops.OpType(data[0]) != ops.TypeClip {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	r := f32.Rectangle{
+		Min: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[1:])),
+			Y: math.Float32frombits(bo.Uint32(data[5:])),
+		},
+		Max: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[9:])),
+			Y: math.Float32frombits(bo.Uint32(data[13:])),
+		},
+	}
+	*op = opClip{
+		bounds:   r,
+		fillRule: gdraw.FillRule(data[17]),
+	}
+}
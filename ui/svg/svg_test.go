@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package svg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+)
+
+func TestEncode(t *testing.T) {
+	o := new(ui.Ops)
+	draw.ColorOp{Color: color.RGBA{R: 0x80, A: 0x80}}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(o)
+	var stack ui.StackOp
+	stack.Push(o)
+	ui.TransformOp{Transform: ui.Offset(f32.Point{X: 5, Y: 5})}.Add(o)
+	var p draw.PathBuilder
+	p.Init(o)
+	p.Line(f32.Point{X: 10})
+	p.Line(f32.Point{Y: 10})
+	p.Quad(f32.Point{X: -5, Y: 5}, f32.Point{X: -10, Y: -10})
+	p.End()
+	draw.LinearGradientOp{
+		End: f32.Point{X: 10},
+		Stops: []draw.ColorStop{
+			{Offset: 0, Color: color.RGBA{R: 0xff, A: 0xff}},
+			{Offset: 1, Color: color.RGBA{B: 0xff, A: 0xff}},
+		},
+	}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: 10, Y: 10}}}.Add(o)
+	stack.Pop()
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	draw.ImageOp{Src: src, Rect: src.Bounds()}.Add(o)
+	draw.DrawOp{Rect: f32.Rectangle{Min: f32.Point{X: 10}, Max: f32.Point{X: 20, Y: 10}}}.Add(o)
+	var buf bytes.Buffer
+	if err := Encode(&buf, o, image.Pt(20, 20)); err != nil {
+		t.Fatal(err)
+	}
+	const want = `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="20" height="20" viewBox="0 0 20 20">
+	<defs>
+		<clipPath id="clip1">
+			<path d="M0 0L10 0L10 10Q5 15 0 0Z" clip-rule="nonzero"/>
+		</clipPath>
+		<linearGradient id="gradient2" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="10" y2="0" spreadMethod="pad" color-interpolation="linearRGB">
+			<stop offset="0" stop-color="#ff0000"/>
+			<stop offset="1" stop-color="#0000ff"/>
+		</linearGradient>
+	</defs>
+	<rect width="20" height="20" fill="#ffffff"/>
+	<rect x="0" y="0" width="10" height="10" fill="#ff0000" fill-opacity="0.5019608"/>
+	<g transform="translate(5 5)">
+		<g clip-path="url(#clip1)">
+			<rect x="0" y="0" width="10" height="10" fill="url(#gradient2)"/>
+		</g>
+	</g>
+	<image x="10" y="0" width="10" height="10" preserveAspectRatio="none" xlink:href="data:image/png;base64,`
+	got := buf.String()
+	if !strings.HasPrefix(got, want) || !strings.HasSuffix(got, "\"/>\n</svg>\n") {
+		t.Errorf("got document\n%s\nexpected it to start with\n%s", got, want)
+	}
+}
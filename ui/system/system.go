@@ -24,9 +24,7 @@ type ProfileEvent struct {
 }
 
 func (p ProfileOp) Add(o *ui.Ops) {
-	data := make([]byte, ops.TypeProfileLen)
-	data[0] = byte(ops.TypeProfile)
-	o.Write(data, p.Key)
+	o.Write(ops.TypeProfile, p.Key)
 }
 
 func (p *ProfileOp) Decode(d []byte, refs []interface{}) {
@@ -35,6 +35,11 @@ func (e *editBuffer) Changed() bool {
 	return c
 }
 
+// reset clears the buffer.
+func (e *editBuffer) reset() {
+	*e = editBuffer{changed: e.changed || e.len() > 0}
+}
+
 func (e *editBuffer) deleteRuneForward() {
 	e.moveGap(0)
 	_, s := utf8.DecodeRune(e.text[e.gapend:])
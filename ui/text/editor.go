@@ -6,10 +6,12 @@ import (
 	"image"
 	"image/color"
 	"math"
+	"strings"
 	"time"
 	"unicode/utf8"
 
 	"gioui.org/ui"
+	"gioui.org/ui/clipboard"
 	"gioui.org/ui/draw"
 	"gioui.org/ui/gesture"
 	"gioui.org/ui/input"
@@ -53,6 +55,13 @@ type Editor struct {
 	scrollOff image.Point
 
 	clicker gesture.Click
+
+	// clipboardWrite is the text to copy to the
+	// clipboard in the next Layout, if any.
+	clipboardWrite *string
+	// paste is set when the clipboard content is
+	// to be requested in the next Layout.
+	paste bool
 }
 
 type EditorEvent interface {
@@ -135,6 +144,14 @@ func (e *Editor) Next(cfg ui.Config, queue input.Queue) (EditorEvent, bool) {
 			e.scrollToCaret(cfg)
 			e.scroller.Stop()
 			e.append(ke.Text)
+		case clipboard.Event:
+			text := ke.Text
+			if e.SingleLine {
+				text = strings.Replace(text, "\n", " ", -1)
+			}
+			e.scrollToCaret(cfg)
+			e.scroller.Stop()
+			e.append(text)
 		}
 		if e.rr.Changed() {
 			return ChangeEvent{}, true
@@ -185,6 +202,14 @@ func (e *Editor) Layout(cfg ui.Config, queue input.Queue, ops *ui.Ops, cs layout
 	}
 	key.HandlerOp{Key: e, Focus: e.requestFocus}.Add(ops)
 	e.requestFocus = false
+	if e.clipboardWrite != nil {
+		clipboard.WriteOp{Text: *e.clipboardWrite}.Add(ops)
+		e.clipboardWrite = nil
+	}
+	if e.paste {
+		clipboard.ReadOp{Key: e}.Add(ops)
+		e.paste = false
+	}
 	e.it = lineIterator{
 		Lines:     lines,
 		Clip:      clip,
@@ -251,6 +276,7 @@ func (e *Editor) Layout(cfg ui.Config, queue input.Queue, ops *ui.Ops, cs layout
 	pointer.RectAreaOp{Size: e.viewSize}.Add(ops)
 	e.scroller.Add(ops)
 	e.clicker.Add(ops)
+	pointer.CursorOp{Cursor: pointer.CursorText}.Add(ops)
 	return layout.Dimens{Size: e.viewSize, Baseline: baseline}
 }
 
@@ -554,6 +580,12 @@ func (e *Editor) scrollToCaret(cfg ui.Config) {
 }
 
 func (e *Editor) command(k key.ChordEvent) bool {
+	if k.Modifiers.Contain(key.ModCommand) {
+		switch k.Name {
+		case 'C', 'X', 'V':
+			return e.clipboardCommand(k.Name)
+		}
+	}
 	switch k.Name {
 	case key.NameReturn, key.NameEnter:
 		e.append("\n")
@@ -584,3 +616,25 @@ func (e *Editor) command(k key.ChordEvent) bool {
 	}
 	return true
 }
+
+// clipboardCommand handles the copy, cut and paste shortcuts.
+// The editor has no selection, so copy and cut apply to all
+// of the text.
+func (e *Editor) clipboardCommand(name rune) bool {
+	switch name {
+	case 'C', 'X':
+		text := e.Text()
+		if text == "" {
+			return false
+		}
+		e.clipboardWrite = &text
+		if name == 'X' {
+			e.rr.reset()
+			e.carXOff = 0
+			return true
+		}
+	case 'V':
+		e.paste = true
+	}
+	return false
+}
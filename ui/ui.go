@@ -31,17 +31,24 @@ type TransformOp struct {
 	Transform Transform
 }
 
+// Transform is a 2D affine transformation. The zero value
+// is the identity transformation.
 type Transform struct {
-	// TODO: general transforms.
-	offset f32.Point
+	// The linear part of the transformation, stored with the
+	// identity subtracted so that the zero Transform is the
+	// identity:
+	//
+	//	[1+a  c ]
+	//	[ b  1+d]
+	a, b, c, d float32
+	offset     f32.Point
 }
 
 // Inf is the int value that represents an unbounded maximum constraint.
 const Inf = int(^uint(0) >> 1)
 
 func (r InvalidateOp) Add(o *Ops) {
-	data := make([]byte, ops.TypeRedrawLen)
-	data[0] = byte(ops.TypeInvalidate)
+	data := o.Write(ops.TypeInvalidate)
 	bo := binary.LittleEndian
 	// UnixNano cannot represent the zero time.
 	if t := r.At; !t.IsZero() {
@@ -50,7 +57,6 @@ func (r InvalidateOp) Add(o *Ops) {
 			bo.PutUint64(data[1:], uint64(nanos))
 		}
 	}
-	o.Write(data)
 }
 
 func (r *InvalidateOp) Decode(d []byte) {
@@ -63,27 +69,97 @@ func (r *InvalidateOp) Decode(d []byte) {
 	}
 }
 
+// InvTransform applies the inverse of t to p.
 func (t Transform) InvTransform(p f32.Point) f32.Point {
-	return p.Sub(t.offset)
+	return t.Invert().Transform(p)
 }
 
+// Transform applies t to p.
 func (t Transform) Transform(p f32.Point) f32.Point {
-	return p.Add(t.offset)
+	return f32.Point{
+		X: (1+t.a)*p.X + t.c*p.Y + t.offset.X,
+		Y: t.b*p.X + (1+t.d)*p.Y + t.offset.Y,
+	}
+}
+
+// TransformRect returns the bounds of r transformed by t, and
+// whether the transformed r is itself a rectangle with the
+// orientation of r.
+func (t Transform) TransformRect(r f32.Rectangle) (f32.Rectangle, bool) {
+	sx, hx, ox, hy, sy, oy := t.Elems()
+	if hx == 0 && hy == 0 && sx > 0 && sy > 0 {
+		// Avoid multiplying infinite coordinates by zero.
+		return f32.Rectangle{
+			Min: f32.Point{X: sx*r.Min.X + ox, Y: sy*r.Min.Y + oy},
+			Max: f32.Point{X: sx*r.Max.X + ox, Y: sy*r.Max.Y + oy},
+		}, true
+	}
+	p0 := t.Transform(r.Min)
+	b := f32.Rectangle{Min: p0, Max: p0}
+	for _, p := range [...]f32.Point{
+		t.Transform(f32.Point{X: r.Max.X, Y: r.Min.Y}),
+		t.Transform(r.Max),
+		t.Transform(f32.Point{X: r.Min.X, Y: r.Max.Y}),
+	} {
+		b = b.Union(f32.Rectangle{Min: p, Max: p})
+	}
+	return b, false
 }
 
+// Mul returns the transformation that applies t2
+// followed by t.
 func (t Transform) Mul(t2 Transform) Transform {
+	sx, hx, ox, hy, sy, oy := t.Elems()
+	sx2, hx2, ox2, hy2, sy2, oy2 := t2.Elems()
+	return elems(
+		sx*sx2+hx*hy2, sx*hx2+hx*sy2, sx*ox2+hx*oy2+ox,
+		hy*sx2+sy*hy2, hy*hx2+sy*sy2, hy*ox2+sy*oy2+oy,
+	)
+}
+
+// Invert returns the inverse transformation of t. The
+// inverse of a transformation that collapses the plane
+// is not defined.
+func (t Transform) Invert() Transform {
+	if t.a == 0 && t.b == 0 && t.c == 0 && t.d == 0 {
+		return Offset(t.offset.Mul(-1))
+	}
+	sx, hx, ox, hy, sy, oy := t.Elems()
+	det := sx*sy - hx*hy
+	isx, ihx, ihy, isy := sy/det, -hx/det, -hy/det, sx/det
+	return elems(
+		isx, ihx, -(isx*ox + ihx*oy),
+		ihy, isy, -(ihy*ox + isy*oy),
+	)
+}
+
+// Elems returns the elements of the matrix
+//
+//	[sx hx ox]
+//	[hy sy oy]
+//
+// that maps (x, y) to (sx*x + hx*y + ox, hy*x + sy*y + oy).
+func (t Transform) Elems() (sx, hx, ox, hy, sy, oy float32) {
+	return 1 + t.a, t.c, t.offset.X, t.b, 1 + t.d, t.offset.Y
+}
+
+func elems(sx, hx, ox, hy, sy, oy float32) Transform {
 	return Transform{
-		offset: t.offset.Add(t2.offset),
+		a: sx - 1, b: hy, c: hx, d: sy - 1,
+		offset: f32.Point{X: ox, Y: oy},
 	}
 }
 
 func (t TransformOp) Add(o *Ops) {
-	data := make([]byte, ops.TypeTransformLen)
-	data[0] = byte(ops.TypeTransform)
+	data := o.Write(ops.TypeTransform)
 	bo := binary.LittleEndian
-	bo.PutUint32(data[1:], math.Float32bits(t.Transform.offset.X))
-	bo.PutUint32(data[5:], math.Float32bits(t.Transform.offset.Y))
-	o.Write(data)
+	tr := t.Transform
+	bo.PutUint32(data[1:], math.Float32bits(tr.offset.X))
+	bo.PutUint32(data[5:], math.Float32bits(tr.offset.Y))
+	bo.PutUint32(data[9:], math.Float32bits(tr.a))
+	bo.PutUint32(data[13:], math.Float32bits(tr.b))
+	bo.PutUint32(data[17:], math.Float32bits(tr.c))
+	bo.PutUint32(data[21:], math.Float32bits(tr.d))
 }
 
 func (t *TransformOp) Decode(d []byte) {
@@ -92,13 +168,42 @@ func (t *TransformOp) Decode(d []byte) {
 		panic("invalid op")
 	}
 	*t = TransformOp{
-		Transform: Offset(f32.Point{
-			X: math.Float32frombits(bo.Uint32(d[1:])),
-			Y: math.Float32frombits(bo.Uint32(d[5:])),
-		}),
+		Transform: Transform{
+			offset: f32.Point{
+				X: math.Float32frombits(bo.Uint32(d[1:])),
+				Y: math.Float32frombits(bo.Uint32(d[5:])),
+			},
+			a: math.Float32frombits(bo.Uint32(d[9:])),
+			b: math.Float32frombits(bo.Uint32(d[13:])),
+			c: math.Float32frombits(bo.Uint32(d[17:])),
+			d: math.Float32frombits(bo.Uint32(d[21:])),
+		},
 	}
 }
 
+// Offset returns the transformation that translates by o.
 func Offset(o f32.Point) Transform {
-	return Transform{o}
+	return Transform{offset: o}
+}
+
+// Scale returns the transformation that scales by s.X
+// horizontally and s.Y vertically.
+func Scale(s f32.Point) Transform {
+	return Transform{a: s.X - 1, d: s.Y - 1}
+}
+
+// Rotate returns the transformation that rotates by angle
+// radians around the origin. Because the y axis points down,
+// positive angles rotate clockwise.
+func Rotate(angle float32) Transform {
+	sin, cos := math.Sincos(float64(angle))
+	s, c := float32(sin), float32(cos)
+	return Transform{a: c - 1, b: s, c: -s, d: c - 1}
+}
+
+// Shear returns the transformation that adds x times the
+// y coordinate to the x coordinate and y times the x
+// coordinate to the y coordinate.
+func Shear(x, y float32) Transform {
+	return Transform{b: y, c: x}
 }
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package uitest
+
+import (
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/internal/ops"
+)
+
+// Clock is a Config with a virtual clock. Time advances
+// only when Advance or Step is called, so animations can be
+// stepped frame by frame and recorded sessions replayed
+// exactly.
+type Clock struct {
+	Config
+
+	reader ui.OpsReader
+	// wakeup is the earliest redraw time requested
+	// by InvalidateOps, if any.
+	wakeup    time.Time
+	hasWakeup bool
+}
+
+// Advance the clock by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.Time = c.Time.Add(d)
+}
+
+// Frame collects the redraw times requested by the InvalidateOps
+// of a frame. The zero time requests an immediate redraw.
+func (c *Clock) Frame(root *ui.Ops) {
+	c.reader.Reset(root)
+	for encOp, ok := c.reader.Decode(); ok; encOp, ok = c.reader.Decode() {
+		if ops.OpType(encOp.Data[0]) != ops.TypeInvalidate {
+			continue
+		}
+		var op ui.InvalidateOp
+		op.Decode(encOp.Data)
+		at := op.At
+		if at.Before(c.Time) {
+			at = c.Time
+		}
+		if !c.hasWakeup || at.Before(c.wakeup) {
+			c.hasWakeup = true
+			c.wakeup = at
+		}
+	}
+}
+
+// Wakeup returns the earliest redraw time requested by the
+// frames passed to Frame since the last call to Step.
+func (c *Clock) Wakeup() (time.Time, bool) {
+	return c.wakeup, c.hasWakeup
+}
+
+// Step advances the clock to the next requested redraw, but
+// at least by interval, the time between display frames.
+// Step reports false and leaves the clock unchanged if no
+// redraw was requested.
+func (c *Clock) Step(interval time.Duration) bool {
+	if !c.hasWakeup {
+		return false
+	}
+	next := c.Time.Add(interval)
+	if c.wakeup.After(next) {
+		next = c.wakeup
+	}
+	c.Time = next
+	c.hasWakeup = false
+	return true
+}
@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package uitest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/draw"
+)
+
+// Golden describes the rendering of a layout and
+// how to compare it with a golden image.
+type Golden struct {
+	// Size of the rendered image in pixels.
+	Size image.Point
+	// Config is the configuration passed to the layout.
+	// If nil, a Config with 1 pixel per dp and sp is used.
+	Config *Config
+	// Tolerance is the maximum difference of any color
+	// component of a pixel before the pixel is considered
+	// different.
+	Tolerance uint8
+	// Dir is the directory of the golden images. The
+	// empty string means "testdata".
+	Dir string
+	// OutDir is the directory for writing the actual and
+	// difference images of failing tests. The empty string
+	// means Dir.
+	OutDir string
+}
+
+var update = flag.Bool("uitest.update", false, "update golden images")
+
+// Check renders the layout and compares it with the golden
+// image named name. If the images differ, the actual image
+// and an image highlighting the differences are written to
+// the output directory and the test fails.
+func (g Golden) Check(t testing.TB, name string, layout LayoutFunc) {
+	t.Helper()
+	cfg := g.Config
+	if cfg == nil {
+		cfg = new(Config)
+	}
+	actual := Render(cfg, g.Size, layout)
+	dir := g.Dir
+	if dir == "" {
+		dir = "testdata"
+	}
+	path := filepath.Join(dir, name+".png")
+	if *update {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := savePNG(path, actual); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := loadPNG(path)
+	if err != nil {
+		t.Fatalf("%v (run with -uitest.update to create the golden image)", err)
+	}
+	diff, n := Compare(expected, actual, g.Tolerance)
+	if n == 0 {
+		return
+	}
+	out := g.OutDir
+	if out == "" {
+		out = dir
+	}
+	actPath := filepath.Join(out, name+".actual.png")
+	diffPath := filepath.Join(out, name+".diff.png")
+	if err := savePNG(actPath, actual); err != nil {
+		t.Error(err)
+	}
+	if err := savePNG(diffPath, diff); err != nil {
+		t.Error(err)
+	}
+	t.Errorf("%s: %d pixels differ from golden image %s (actual: %s, diff: %s)", name, n, path, actPath, diffPath)
+}
+
+// Compare two images and return the number of pixels where a color
+// component differ by more than tolerance. The returned image contains
+// the differing pixels in red on top of a faded copy of expected.
+// Images of different sizes differ in every pixel outside their
+// intersection.
+func Compare(expected, actual image.Image, tolerance uint8) (*image.RGBA, int) {
+	eb, ab := expected.Bounds(), actual.Bounds()
+	size := eb.Size()
+	if s := ab.Size(); s.X > size.X {
+		size.X = s.X
+	}
+	if s := ab.Size(); s.Y > size.Y {
+		size.Y = s.Y
+	}
+	diff := image.NewRGBA(image.Rectangle{Max: size})
+	draw.Draw(diff, diff.Bounds(), expected, eb.Min, draw.Src)
+	// Fade the expected image.
+	fade := image.NewUniform(color.RGBA{R: 0xbf, G: 0xbf, B: 0xbf, A: 0xbf})
+	draw.Draw(diff, diff.Bounds(), fade, image.Point{}, draw.Over)
+	red := color.RGBA{R: 0xff, A: 0xff}
+	n := 0
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			ep, ap := image.Point{X: x, Y: y}.Add(eb.Min), image.Point{X: x, Y: y}.Add(ab.Min)
+			if !ep.In(eb) || !ap.In(ab) {
+				diff.SetRGBA(x, y, red)
+				n++
+				continue
+			}
+			ec := color.RGBAModel.Convert(expected.At(ep.X, ep.Y)).(color.RGBA)
+			ac := color.RGBAModel.Convert(actual.At(ap.X, ap.Y)).(color.RGBA)
+			if !similar(ec, ac, tolerance) {
+				diff.SetRGBA(x, y, red)
+				n++
+			}
+		}
+	}
+	return diff, n
+}
+
+func similar(c1, c2 color.RGBA, tolerance uint8) bool {
+	return absDiff(c1.R, c2.R) <= tolerance &&
+		absDiff(c1.G, c2.G) <= tolerance &&
+		absDiff(c1.B, c2.B) <= tolerance &&
+		absDiff(c1.A, c2.A) <= tolerance
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+func loadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("uitest: %s: %v", path, err)
+	}
+	return img, nil
+}
+
+func savePNG(path string, img image.Image) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return png.Encode(f, img)
+}
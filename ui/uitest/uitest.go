@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package uitest implements golden image testing of layouts.
+
+A layout function is rendered with the software renderer from
+package raster and compared with a PNG image stored in a testdata
+directory. Run tests with the -uitest.update flag to create or
+replace the stored images.
+
+For example:
+
+	func TestLabel(t *testing.T) {
+		g := uitest.Golden{Size: image.Point{X: 200, Y: 50}}
+		g.Check(t, "label", func(c ui.Config, ops *ui.Ops, cs layout.Constraints) {
+			text.Label{Face: face, Text: "Hello"}.Layout(ops, cs)
+		})
+	}
+*/
+package uitest
+
+import (
+	"image"
+	"math"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/raster"
+)
+
+// LayoutFunc lays out a user interface.
+type LayoutFunc func(c ui.Config, ops *ui.Ops, cs layout.Constraints)
+
+// Config implements the ui.Config interface with
+// fixed densities and time.
+type Config struct {
+	// PxPerDp is the number of pixels per dp.
+	// The zero value means 1.
+	PxPerDp float32
+	// PxPerSp is the number of pixels per sp.
+	// The zero value means PxPerDp.
+	PxPerSp float32
+	// Time is the value returned by Now.
+	Time time.Time
+}
+
+// Render the layout with rigid constraints of the given size.
+func Render(c ui.Config, size image.Point, lay LayoutFunc) *image.RGBA {
+	ops := new(ui.Ops)
+	lay(c, ops, layout.RigidConstraints(size))
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	var r raster.Renderer
+	r.Render(img, ops)
+	return img
+}
+
+func (c *Config) Now() time.Time {
+	return c.Time
+}
+
+func (c *Config) Px(v ui.Value) int {
+	pxPerDp := c.PxPerDp
+	if pxPerDp == 0 {
+		pxPerDp = 1
+	}
+	pxPerSp := c.PxPerSp
+	if pxPerSp == 0 {
+		pxPerSp = pxPerDp
+	}
+	var r float32
+	switch v.U {
+	case ui.UnitPx:
+		r = v.V
+	case ui.UnitDp:
+		r = pxPerDp * v.V
+	case ui.UnitSp:
+		r = pxPerSp * v.V
+	default:
+		panic("unknown unit")
+	}
+	if math.IsInf(float64(r), +1) {
+		return ui.Inf
+	}
+	return int(math.Round(float64(r)))
+}
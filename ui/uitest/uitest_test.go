@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package uitest
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"gioui.org/ui"
+	"gioui.org/ui/draw"
+	"gioui.org/ui/f32"
+	"gioui.org/ui/input"
+	"gioui.org/ui/layout"
+	"gioui.org/ui/measure"
+	"gioui.org/ui/pointer"
+	"gioui.org/ui/text"
+	"gioui.org/ui/widget"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+type emptyQueue struct{}
+
+func (emptyQueue) Events(k input.Key) []input.Event { return nil }
+
+// scrollQueue delivers a single scroll event to every key.
+type scrollQueue struct {
+	scroll f32.Point
+}
+
+func (q scrollQueue) Events(k input.Key) []input.Event {
+	return []input.Event{pointer.Event{Type: pointer.Move, Scroll: q.scroll}}
+}
+
+func TestCompare(t *testing.T) {
+	img1 := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img2 := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	img2.SetRGBA(1, 1, color.RGBA{R: 10})
+	img2.SetRGBA(2, 2, color.RGBA{G: 100})
+	if _, n := Compare(img1, img2, 10); n != 1 {
+		t.Errorf("got %d differing pixels, expected 1", n)
+	}
+	if _, n := Compare(img1, img1.SubImage(image.Rect(0, 0, 4, 3)), 0); n != 4 {
+		t.Errorf("got %d differing pixels, expected 4", n)
+	}
+}
+
+func TestClock(t *testing.T) {
+	t0 := time.Unix(1000, 0)
+	c := &Clock{Config: Config{Time: t0}}
+	ops := new(ui.Ops)
+	ui.InvalidateOp{At: t0.Add(time.Second)}.Add(ops)
+	ui.InvalidateOp{At: t0.Add(500 * time.Millisecond)}.Add(ops)
+	c.Frame(ops)
+	if w, ok := c.Wakeup(); !ok || !w.Equal(t0.Add(500*time.Millisecond)) {
+		t.Errorf("got wakeup %v, %v", w, ok)
+	}
+	if !c.Step(16 * time.Millisecond) {
+		t.Fatal("no step")
+	}
+	if got, exp := c.Now(), t0.Add(500*time.Millisecond); !got.Equal(exp) {
+		t.Errorf("got time %v, expected %v", got, exp)
+	}
+	ops.Reset()
+	ui.InvalidateOp{}.Add(ops)
+	c.Frame(ops)
+	c.Step(16 * time.Millisecond)
+	if got, exp := c.Now(), t0.Add(516*time.Millisecond); !got.Equal(exp) {
+		t.Errorf("got time %v, expected %v", got, exp)
+	}
+	if c.Step(16 * time.Millisecond) {
+		t.Error("stepped without wakeup")
+	}
+}
+
+func TestLabel(t *testing.T) {
+	g := Golden{Size: image.Point{X: 120, Y: 60}, Config: &Config{PxPerDp: 2}, Tolerance: 2}
+	var faces measure.Faces
+	g.Check(t, "label", func(c ui.Config, ops *ui.Ops, cs layout.Constraints) {
+		faces.Reset(c)
+		text.Label{Face: faces.For(regular(t), ui.Sp(10)), Text: "Hello, Gio"}.Layout(ops, cs)
+	})
+}
+
+func TestEditor(t *testing.T) {
+	g := Golden{Size: image.Point{X: 120, Y: 60}, Tolerance: 2}
+	var faces measure.Faces
+	e := &text.Editor{}
+	e.SetText("Editor text\nspanning lines")
+	g.Check(t, "editor", func(c ui.Config, ops *ui.Ops, cs layout.Constraints) {
+		faces.Reset(c)
+		e.Face = faces.For(regular(t), ui.Sp(14))
+		e.Layout(c, emptyQueue{}, ops, cs)
+	})
+}
+
+func TestFlexStack(t *testing.T) {
+	g := Golden{Size: image.Point{X: 60, Y: 40}}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.SetRGBA(0, 0, color.RGBA{R: 0xff, A: 0xff})
+	src.SetRGBA(1, 1, color.RGBA{B: 0xff, A: 0xff})
+	g.Check(t, "flexstack", func(c ui.Config, ops *ui.Ops, cs layout.Constraints) {
+		f := (&layout.Flex{}).Init(ops, cs)
+		f.Rigid()
+		c1 := f.End(fill(ops, color.RGBA{G: 0x80, A: 0xff}, image.Point{X: 20, Y: 20}))
+		s := (&layout.Stack{Alignment: layout.Center}).Init(ops, f.Flexible(1))
+		c2 := s.End(widget.Image{Src: src, Rect: src.Bounds(), Scale: 8}.Layout(c, ops, s.Rigid()))
+		s.Rigid()
+		c3 := s.End(fill(ops, color.RGBA{A: 0x80}, image.Point{X: 30, Y: 8}))
+		c4 := f.End(s.Layout(c2, c3))
+		f.Layout(c1, c4)
+	})
+}
+
+func TestList(t *testing.T) {
+	g := Golden{Size: image.Point{X: 60, Y: 50}}
+	colors := []color.RGBA{
+		{R: 0xff, A: 0xff},
+		{G: 0x80, A: 0xff},
+		{B: 0xff, A: 0xff},
+	}
+	l := &layout.List{Axis: layout.Vertical}
+	list := func(c ui.Config, q input.Queue, ops *ui.Ops, cs layout.Constraints) {
+		for l.Init(c, q, ops, cs, 10); l.More(); l.Next() {
+			cs := l.Constraints()
+			sz := image.Point{X: cs.Width.Max, Y: 20}
+			l.Elem(fill(ops, colors[l.Index()%len(colors)], sz))
+		}
+		l.Layout()
+	}
+	g.Check(t, "list", func(c ui.Config, ops *ui.Ops, cs layout.Constraints) {
+		// The first layout sets up the scroll axis.
+		list(c, emptyQueue{}, new(ui.Ops), cs)
+		// Scroll past the first element and into the second.
+		list(c, scrollQueue{scroll: f32.Point{Y: 25}}, ops, cs)
+		if l.Distance != 25 {
+			t.Errorf("scrolled %d pixels, expected 25", l.Distance)
+		}
+	})
+}
+
+func fill(ops *ui.Ops, col color.RGBA, sz image.Point) layout.Dimens {
+	draw.ColorOp{Color: col}.Add(ops)
+	draw.DrawOp{Rect: f32.Rectangle{Max: f32.Point{X: float32(sz.X), Y: float32(sz.Y)}}}.Add(ops)
+	return layout.Dimens{Size: sz, Baseline: sz.Y}
+}
+
+func regular(t *testing.T) *sfnt.Font {
+	fnt, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fnt
+}
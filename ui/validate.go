@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package ui
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"gioui.org/ui/internal/ops"
+)
+
+// OpError describes an invalid op in an Ops.
+type OpError struct {
+	// Offset is the position of the op in the
+	// data of the Ops that contains it.
+	Offset int
+	// Op is the name of the op type.
+	Op string
+	// Desc describes the error.
+	Desc string
+}
+
+type validator struct {
+	// pushes tracks the offsets of unmatched
+	// StackOp pushes.
+	pushes []int
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("ui: %s (%s op at offset %d)", e.Desc, e.Op, e.Offset)
+}
+
+// SetChecked enables or disables checked mode. In checked
+// mode, misuse such as unbalanced StackOps or MacroOps
+// don't panic. Instead, the offending op is dropped and
+// the error is returned by Validate. Checked mode also
+// detects some concurrent writes to the Ops.
+func (o *Ops) SetChecked(checked bool) {
+	o.checked = checked
+}
+
+// Validate checks the ops for errors that would otherwise
+// make them panic when drawn, such as unbalanced stacks,
+// unterminated macros and references to macros in
+// Ops that have since been reset. In checked mode, Validate
+// also reports the first misuse of the Ops and detected
+// concurrent writes since the last Reset.
+func (o *Ops) Validate() error {
+	if atomic.LoadInt32(&o.concurrent) != 0 {
+		return &OpError{Offset: len(o.data), Op: "write", Desc: "concurrent write"}
+	}
+	if o.err != nil {
+		return o.err
+	}
+	if o.inAux {
+		return &OpError{Offset: o.auxOff, Op: ops.TypeAux.String(), Desc: "unterminated aux block"}
+	}
+	var v validator
+	end := o.pc()
+	p, err := v.validate(o, pc{}, end)
+	if err != nil {
+		return err
+	}
+	if n := len(v.pushes); n > 0 {
+		return &OpError{Offset: v.pushes[n-1], Op: ops.TypePush.String(), Desc: "unbalanced push"}
+	}
+	if p.refs != end.refs {
+		return &OpError{Offset: end.data, Op: "end", Desc: "invalid ref count"}
+	}
+	return nil
+}
+
+// misuse panics with desc, or records the error
+// in checked mode.
+func (o *Ops) misuse(t ops.OpType, desc string) {
+	if !o.checked {
+		panic(desc)
+	}
+	if o.err == nil {
+		o.err = &OpError{Offset: len(o.data), Op: t.String(), Desc: desc}
+	}
+}
+
+// validate checks the ops in the range from start to end and
+// returns the position after the last op.
+func (v *validator) validate(o *Ops, start, end pc) (pc, error) {
+	p := start
+	for p.data < end.data {
+		t := ops.OpType(o.data[p.data])
+		errorf := func(format string, args ...interface{}) (pc, error) {
+			return p, &OpError{Offset: p.data, Op: t.String(), Desc: fmt.Sprintf(format, args...)}
+		}
+		if !t.Valid() {
+			if t == 0 {
+				// MacroOp.Record reserves zeros for the macro definition.
+				return errorf("unterminated macro")
+			}
+			return errorf("invalid op type %d", t)
+		}
+		n, nrefs := t.Size(), t.NumRefs()
+		if p.data+n > end.data {
+			return errorf("truncated op")
+		}
+		if p.refs+nrefs > end.refs {
+			return errorf("invalid ref count")
+		}
+		data := o.data[p.data : p.data+n]
+		switch t {
+		case ops.TypeAux:
+			var op opAux
+			op.decode(data)
+			if op.len > end.data-p.data-n {
+				return errorf("aux block of %d bytes exceeds the ops", op.len)
+			}
+			n += op.len
+		case ops.TypeMacroDef:
+			var op opMacroDef
+			op.decode(data)
+			if op.endpc.data < p.data+n || op.endpc.data > end.data ||
+				op.endpc.refs < p.refs+nrefs || op.endpc.refs > end.refs {
+				return errorf("macro end out of range")
+			}
+			// Macros are validated where they are added.
+			p = op.endpc
+			continue
+		case ops.TypeMacro:
+			macroOps, ok := o.refs[p.refs].(*Ops)
+			if !ok {
+				return errorf("invalid macro reference %T", o.refs[p.refs])
+			}
+			var op MacroOp
+			op.decode(data, o.refs[p.refs:p.refs+nrefs])
+			if op.version != macroOps.version {
+				return errorf("stale reference to reset Ops")
+			}
+			mp := op.pc
+			defLen := ops.TypeMacroDef.Size()
+			if mp.data < 0 || mp.data+defLen > len(macroOps.data) ||
+				ops.OpType(macroOps.data[mp.data]) != ops.TypeMacroDef {
+				return errorf("invalid macro reference")
+			}
+			var def opMacroDef
+			def.decode(macroOps.data[mp.data : mp.data+defLen])
+			if def.endpc.data > len(macroOps.data) || def.endpc.refs > len(macroOps.refs) {
+				return errorf("macro end out of range")
+			}
+			mp.data += defLen
+			mp.refs += ops.TypeMacroDef.NumRefs()
+			if _, err := v.validate(macroOps, mp, def.endpc); err != nil {
+				return p, err
+			}
+		case ops.TypeSplice:
+			if _, ok := o.refs[p.refs].(*Ops); !ok {
+				return errorf("invalid splice reference %T", o.refs[p.refs])
+			}
+			var op opSplice
+			op.decode(data, o.refs[p.refs:p.refs+nrefs])
+			if op.version != op.ops.version {
+				return errorf("stale reference to reset Ops")
+			}
+			if op.endpc.data > len(op.ops.data) || op.endpc.refs > len(op.ops.refs) {
+				return errorf("splice end out of range")
+			}
+			if _, err := v.validate(op.ops, pc{}, op.endpc); err != nil {
+				return p, err
+			}
+		case ops.TypePush:
+			v.pushes = append(v.pushes, p.data)
+		case ops.TypePop:
+			if len(v.pushes) == 0 {
+				return errorf("unbalanced pop")
+			}
+			v.pushes = v.pushes[:len(v.pushes)-1]
+		}
+		p.data += n
+		p.refs += nrefs
+	}
+	return p, nil
+}
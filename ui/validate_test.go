@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package ui
+
+import (
+	"testing"
+
+	"gioui.org/ui/internal/ops"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  func(o *Ops)
+		desc string
+	}{
+		{"valid", func(o *Ops) {
+			var m MacroOp
+			m.Record(o)
+			var s StackOp
+			s.Push(o)
+			s.Pop()
+			m.Stop()
+			m.Add(o)
+			m.Add(o)
+		}, ""},
+		{"unbalanced push", func(o *Ops) {
+			var s StackOp
+			s.Push(o)
+		}, "unbalanced push"},
+		{"unbalanced pop", func(o *Ops) {
+			var s1, s2 StackOp
+			s1.Push(o)
+			s2.Push(o)
+			s1.Pop()
+		}, "unbalanced pop"},
+		{"unterminated macro", func(o *Ops) {
+			var m MacroOp
+			m.Record(o)
+		}, "unterminated macro"},
+		{"stale macro", func(o *Ops) {
+			other := new(Ops)
+			var m MacroOp
+			m.Record(other)
+			m.Stop()
+			other.Reset()
+			m.Add(o)
+		}, "stale reference to reset Ops"},
+		{"stale splice", func(o *Ops) {
+			child := new(Ops)
+			var s StackOp
+			s.Push(child)
+			s.Pop()
+			o.Splice(child)
+			child.Reset()
+		}, "stale reference to reset Ops"},
+		{"unbalanced splice", func(o *Ops) {
+			child := new(Ops)
+			var s StackOp
+			s.Push(child)
+			o.Splice(child)
+		}, "splice of unbalanced push"},
+		{"ref count", func(o *Ops) {
+			o.Write(ops.TypeMacro)
+		}, "invalid ref count"},
+		{"concurrent write", func(o *Ops) {
+			// Simulate a write in progress in another goroutine.
+			o.writing = 1
+			var s StackOp
+			s.Push(o)
+			s.Pop()
+			o.writing = 0
+		}, "concurrent write"},
+	}
+	for _, test := range tests {
+		o := new(Ops)
+		o.SetChecked(true)
+		test.ops(o)
+		err := o.Validate()
+		if test.desc == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		if err, ok := err.(*OpError); !ok || err.Desc != test.desc {
+			t.Errorf("%s: got error %v, expected %q", test.name, err, test.desc)
+		}
+	}
+}